@@ -8,9 +8,12 @@ go build -o server
 ./server --command=status
 ./server --command=stop
 ./server --command=status
+./server --command=restart
 
 command包解析启动命令，支持start、daemon、status、stop、restart五个命令，需要定义command和pidfile两个配置参数。
 通过向进程发送对应的系统信号实现对应的命令。
+restart命令会热重启进程，新进程通过环境变量EUDORE_LISTENERS继承全部监听，
+在App启动钩子中写入pid文件并通知旧进程，旧进程收到通知后处理完已有连接退出；新进程启动失败时旧进程继续提供服务。
 该组件不支持win系统。
 */
 
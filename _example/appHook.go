@@ -0,0 +1,59 @@
+package main
+
+/*
+App生命周期钩子，AddHook方法或Options方法添加AppHook。
+
+Listen(Serve)或Run方法会在处理请求前按Depends依赖顺序执行钩子的Start，App结束时先关闭Server全部监听并等待结束，
+再按相反顺序执行钩子的Stop，每次执行钩子超时时间为Timeout，全部错误合并后返回。
+
+type AppHook struct {
+	Name    string
+	Depends []string
+	Timeout time.Duration
+	Start   func(context.Context) error
+	Stop    func(context.Context) error
+}
+*/
+
+import (
+	"context"
+	"time"
+
+	"github.com/eudore/eudore"
+)
+
+func main() {
+	app := eudore.NewApp()
+	app.Options(eudore.AppHook{
+		Name:    "worker",
+		Depends: []string{"db"},
+		Start: func(context.Context) error {
+			app.Info("start worker")
+			return nil
+		},
+		Stop: func(context.Context) error {
+			app.Info("stop worker")
+			return nil
+		},
+	})
+	app.AddHook(eudore.AppHook{
+		Name:    "db",
+		Timeout: 5 * time.Second,
+		Start: func(context.Context) error {
+			app.Info("open db pool")
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			app.Info("close db pool")
+			return nil
+		},
+	})
+
+	app.AnyFunc("/*path", func(ctx eudore.Context) {
+		ctx.WriteString("hello eudore")
+	})
+	app.Listen(":8088")
+	app.Listen(":8089")
+	app.CancelFunc()
+	app.Run()
+}
@@ -152,3 +152,93 @@ func createKey() {
 	pem.Encode(keyOut, &pem.Block{Type: "RAS PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pk)})
 	keyOut.Close()
 }
+
+func TestAppHook2(t *testing.T) {
+	app := eudore.NewApp()
+	newhook := func(name string, depends ...string) eudore.AppHook {
+		return eudore.AppHook{
+			Name:    name,
+			Depends: depends,
+			Timeout: 100 * time.Millisecond,
+			Start: func(context.Context) error {
+				t.Log("start", name)
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				t.Log("stop", name)
+				if name == "worker" {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return nil
+			},
+		}
+	}
+	app.Options(newhook("worker", "db"), newhook("db", "log"), newhook("log"))
+	app.AddHook(newhook("log"))
+	app.AddHook(eudore.AppHook{})
+	app.Listen(":8088")
+	app.Listen(":8089")
+	app.CancelFunc()
+	t.Log(app.Run())
+
+	app = eudore.NewApp(newhook("a", "b"), newhook("b", "a"))
+	app.Listen(":8088")
+	t.Log(app.Run())
+	app = eudore.NewApp(newhook("a", "c"))
+	t.Log(app.Run())
+}
+
+func TestAppHookServe2(t *testing.T) {
+	app := eudore.NewApp()
+	started := make(chan struct{})
+	app.AddHook(eudore.AppHook{
+		Name: "db",
+		Start: func(context.Context) error {
+			close(started)
+			return nil
+		},
+	})
+	app.AnyFunc("/*", func(ctx eudore.Context) {
+		select {
+		case <-started:
+			ctx.WriteString("started")
+		default:
+			ctx.WriteHeader(500)
+		}
+	})
+	app.Listen(":8088")
+	resp, err := http.Get("http://127.0.0.1:8088")
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatal("hook not started before serve", err)
+	}
+	resp.Body.Close()
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestAppListenWithFD2(t *testing.T) {
+	ln, err := eudore.ListenWithFD("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasListener := func() bool {
+		names, files := eudore.GetListenerFiles()
+		for _, file := range files {
+			file.Close()
+		}
+		for _, name := range names {
+			if name == "tcp://127.0.0.1:0" {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasListener() {
+		t.Fatal("ListenWithFD listener not found")
+	}
+	ln.Close()
+	if hasListener() {
+		t.Fatal("closed listener is still recorded")
+	}
+}
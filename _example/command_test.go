@@ -0,0 +1,143 @@
+// +build !windows
+
+package eudore_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/command"
+)
+
+const (
+	envCommandTestChild = "EUDORE_TEST_COMMAND_CHILD"
+	envCommandTestMode  = "EUDORE_TEST_COMMAND_MODE"
+)
+
+func TestCommandHotRestart2(t *testing.T) {
+	// 子进程使用继承的监听，将监听地址写入文件，然后按照模式通知启动完成。
+	if out := os.Getenv(envCommandTestChild); out != "" {
+		ln, err := eudore.ListenWithFD("tcp", "127.0.0.1:0")
+		if err != nil {
+			ioutil.WriteFile(out, []byte(err.Error()), 0644)
+			return
+		}
+		ioutil.WriteFile(out, []byte(ln.Addr().String()), 0644)
+		defer ln.Close()
+		switch os.Getenv(envCommandTestMode) {
+		case "exit":
+			return
+		case "hang":
+			time.Sleep(10 * time.Second)
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		command.NewCommand(ctx, "start", os.Args[len(os.Args)-1]).Run()
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "eudore-command")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pidfile := filepath.Join(dir, "eudore.pid")
+	out := filepath.Join(dir, "child")
+
+	ln, err := eudore.ListenWithFD("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := command.NewCommand(ctx, "start", pidfile)
+	cmd.Cancel = cancel
+	cmd.ReadyTimeout = 500 * time.Millisecond
+	cmd.Args = []string{"-test.run=^TestCommandHotRestart2$", pidfile}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 新进程启动失败、启动完成前退出或者超时，重新锁定pid文件继续服务。
+	arg0 := os.Args[0]
+	os.Args[0] = filepath.Join(dir, "not-found")
+	err = cmd.HotRestart()
+	os.Args[0] = arg0
+	if err == nil {
+		t.Fatal("hot restart not found command not error")
+	}
+	checkCommandPidfile(t, pidfile, true)
+	for _, mode := range []string{"exit", "hang"} {
+		cmd.Envs = []string{envCommandTestChild + "=" + out, envCommandTestMode + "=" + mode}
+		err = cmd.HotRestart()
+		t.Log(mode, err)
+		if err == nil || ctx.Err() != nil {
+			t.Fatalf("hot restart child %s not error", mode)
+		}
+		checkCommandPidfile(t, pidfile, true)
+	}
+
+	os.Remove(out)
+	cmd.ReadyTimeout = 10 * time.Second
+	cmd.Envs = []string{envCommandTestChild + "=" + out}
+	if err := cmd.HotRestart(); err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadFile(out)
+	if string(body) != ln.Addr().String() {
+		t.Fatalf("child listen addr %q, want inherit %q", body, ln.Addr().String())
+	}
+
+	// 新进程启动完成后结束，pid文件由新进程写入。
+	<-ctx.Done()
+	time.Sleep(50 * time.Millisecond)
+	body, err = ioutil.ReadFile(pidfile)
+	if err != nil || len(body) == 0 || strings.TrimSpace(string(body)) == strconv.Itoa(os.Getpid()) {
+		t.Fatalf("pidfile content %q not write by new process: %v", body, err)
+	}
+}
+
+func TestCommandRelease2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eudore-command")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pidfile := filepath.Join(dir, "eudore.pid")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := command.NewCommand(ctx, "start", pidfile)
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkCommandPidfile(t, pidfile, true)
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	checkCommandPidfile(t, pidfile, false)
+}
+
+func checkCommandPidfile(t *testing.T, pidfile string, exist bool) {
+	body, err := ioutil.ReadFile(pidfile)
+	if !exist {
+		if !os.IsNotExist(err) {
+			t.Fatalf("pidfile %s should be removed, err: %v", pidfile, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(body)) != strconv.Itoa(os.Getpid()) {
+		t.Fatalf("pidfile content %q, want pid %d", body, os.Getpid())
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 /*
-在Config默认解析函数ConfigAllParseFunc中包含eudore.ConfigParseJSON函数，用于解析json文件。
+在Config默认解析函数ConfigAllParseFunc中包含eudore.ConfigParseFile函数，用于解析配置文件。
+
+ConfigParseFile根据文件后缀选择格式，'.yaml'和'.yml'使用yaml，'.toml'使用toml，其他使用json，
+按顺序读取config配置的路径，使用第一个存在的文件。
+也可以使用ConfigParseJSON、ConfigParseYAML、ConfigParseTOML指定格式解析。
 */
 
 import (
@@ -0,0 +1,46 @@
+package main
+
+/*
+ConfigParseFile函数根据文件后缀解析yaml或toml配置文件，使用alias tag转换到配置结构体。
+*/
+
+import (
+	"os"
+
+	"github.com/eudore/eudore"
+)
+
+type yamlConfig struct {
+	Config  []string         `alias:"config"`
+	Workdir string           `alias:"workdir"`
+	Help    bool             `alias:"help"`
+	Server  yamlServerConfig `alias:"server"`
+}
+
+type yamlServerConfig struct {
+	Addr  string   `alias:"addr"`
+	Hosts []string `alias:"hosts"`
+}
+
+func main() {
+	// 创建一个测试配置文件
+	content := []byte(`# eudore yaml config
+help: true
+server:
+  addr: ":8088"
+  hosts:
+    - localhost
+    - 127.0.0.1
+`)
+	tmpfile, _ := os.Create("example.yaml")
+	defer os.Remove(tmpfile.Name())
+	tmpfile.Write(content)
+
+	app := eudore.NewApp(eudore.NewConfigEudore(&yamlConfig{
+		Config: []string{"example.toml", "example.yaml"},
+	}))
+	app.Options(app.Parse())
+	app.Listen(app.GetString("server.addr"))
+	app.CancelFunc()
+	app.Run()
+}
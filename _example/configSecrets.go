@@ -0,0 +1,56 @@
+package main
+
+/*
+ConfigParseSecrets函数解析配置中的secret引用，需要在文件、参数、环境变量解析之后执行。
+
+"file:///run/secrets/db"读取文件内容作为配置值，删除末尾换行。
+"enc:xxx"使用AES-GCM解密，密钥从环境变量EUDORE_CONFIG_KEY读取，使用"base64:"前缀表示base64编码的密钥。
+ConfigEncryptSecret函数用于生成加密值。
+
+解析的secret键使用GetConfigValues函数和配置查看中间件输出时值为"******"，按键记录不影响其他配置。
+*/
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/eudore/eudore"
+)
+
+type secretsConfig struct {
+	DB secretsDBConfig `alias:"db"`
+}
+
+type secretsDBConfig struct {
+	User     string `alias:"user"`
+	Password string `alias:"password"`
+	Token    string `alias:"token"`
+}
+
+func main() {
+	key := "0123456789abcdef0123456789abcdef"
+	os.Setenv(eudore.EnvEudoreConfigKey, key)
+	token, _ := eudore.ConfigEncryptSecret([]byte(key), "db-token")
+	ioutil.WriteFile("db-password", []byte("db-password\n"), 0600)
+	defer os.Remove("db-password")
+
+	conf := &secretsConfig{
+		DB: secretsDBConfig{
+			User:     "root",
+			Password: "file://db-password",
+			Token:    token,
+		},
+	}
+	app := eudore.NewApp(eudore.NewConfigEudore(conf))
+	app.ParseOption([]eudore.ConfigParseFunc{
+		eudore.ConfigParseFile,
+		eudore.ConfigParseArgs,
+		eudore.ConfigParseEnvs,
+		eudore.ConfigParseSecrets,
+		eudore.ConfigParseValidate,
+	})
+	app.Options(app.Parse())
+	app.Infof("db user %s password length %d token length %d", conf.DB.User, len(conf.DB.Password), len(conf.DB.Token))
+	app.CancelFunc()
+	app.Run()
+}
@@ -0,0 +1,59 @@
+package main
+
+/*
+Config记录每个配置键的来源，来源为文件路径、'--arg'参数、'ENV_'环境变量、'mods.xxx'差异化配置、set或default。
+
+eudore.GetConfigValues函数获取展开后的全部键值和来源，middleware.NewConfigFunc显示生效的配置树，secret值显示为"******"。
+*/
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+	"github.com/eudore/eudore/middleware"
+)
+
+type sourceConfig struct {
+	Config   []string          `alias:"config"`
+	Workdir  string            `alias:"workdir"`
+	Listen   string            `alias:"listen"`
+	Password string            `alias:"password"`
+	Enable   []string          `alias:"enable"`
+	Mods     map[string]string `alias:"mods"`
+	DB       sourceDBConfig    `alias:"db"`
+}
+
+type sourceDBConfig struct {
+	Host string `alias:"host"`
+	Port int    `alias:"port"`
+}
+
+func main() {
+	ioutil.WriteFile("example-source.json", []byte(`{"listen":":8088","db":{"host":"localhost","port":3306}}`), 0644)
+	ioutil.WriteFile("example-password", []byte("password-secret\n"), 0600)
+	defer os.Remove("example-source.json")
+	defer os.Remove("example-password")
+	os.Setenv("ENV_DB_PORT", "3307")
+	defer os.Unsetenv("ENV_DB_PORT")
+
+	app := eudore.NewApp(eudore.NewConfigEudore(&sourceConfig{
+		Config:   []string{"example-source.json"},
+		Password: "file://example-password",
+	}))
+	app.ParseOption(append(eudore.ConfigAllParseFunc, eudore.ConfigParseSecrets))
+	app.Parse()
+	app.Set("workdir", ".")
+	for _, value := range eudore.GetConfigValues(app.Config) {
+		app.Infof("%s = %v # %s", value.Key, value.Value, value.Source)
+	}
+
+	app.AnyFunc("/eudore/debug/config/*", middleware.NewConfigFunc(app.Config))
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/eudore/debug/config/").Do().OutBody()
+	client.NewRequest("GET", "/eudore/debug/config/db?format=text").Do().OutBody()
+
+	app.CancelFunc()
+	app.Run()
+}
@@ -0,0 +1,42 @@
+package main
+
+/*
+ConfigParseValidate函数在args、envs和mods配置解析后使用validate tag验证配置，返回全部无效配置的路径。
+ConfigAllParseFunc默认包含ConfigParseValidate，使用DefaultValidater验证。
+
+使用NewConfigParseValidate(app.Validater)可以指定验证器。
+
+go run configValidate.go --server.port=0 --name=Eudore
+*/
+
+import (
+	"github.com/eudore/eudore"
+)
+
+type validateConfig struct {
+	Name   string               `alias:"name" validate:"regexp:^[a-z]+$"`
+	Server validateServerConfig `alias:"server"`
+}
+
+type validateServerConfig struct {
+	Addr string `alias:"addr" validate:"nozero"`
+	Port int    `alias:"port" validate:"min:1,max:65535"`
+}
+
+func main() {
+	app := eudore.NewApp(eudore.NewConfigEudore(&validateConfig{
+		Name: "eudore",
+		Server: validateServerConfig{
+			Addr: "localhost",
+			Port: 8088,
+		},
+	}))
+	app.ParseOption([]eudore.ConfigParseFunc{
+		eudore.ConfigParseArgs,
+		eudore.ConfigParseEnvs,
+		eudore.NewConfigParseValidate(app.Validater),
+	})
+	app.Options(app.Parse())
+	app.CancelFunc()
+	app.Run()
+}
@@ -0,0 +1,51 @@
+package main
+
+/*
+ConfigWatcher在配置文件修改或收到SIGHUP信号时重新执行配置解析函数，
+解析成功后按键前缀通知订阅者，解析失败保留之前的配置。
+
+kill -HUP `pidof configWatch`
+*/
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/eudore/eudore"
+)
+
+type watchConfig struct {
+	Config []string          `alias:"config"`
+	Logger watchLoggerConfig `alias:"logger"`
+}
+
+type watchLoggerConfig struct {
+	Level eudore.LoggerLevel `alias:"level"`
+}
+
+func main() {
+	ioutil.WriteFile("example.yaml", []byte("logger:\n  level: info\n"), 0644)
+	defer os.Remove("example.yaml")
+
+	conf := &watchConfig{Config: []string{"example.yaml"}}
+	app := eudore.NewApp(eudore.NewConfigEudore(conf))
+	app.Options(app.Parse())
+
+	watcher := eudore.NewConfigWatcher(app.Config)
+	watcher.Subscribe("logger.level", func(key string, oldval, newval interface{}) {
+		app.Infof("config %s changed from %v to %v", key, oldval, newval)
+		app.SetLevel(conf.Logger.Level)
+	})
+	go watcher.Watch(app)
+
+	app.GetFunc("/*", func(ctx eudore.Context) {
+		ctx.Debug("debug message")
+		ctx.WriteString("logger level is " + conf.Logger.Level.String())
+	})
+	app.GetFunc("/reload", func(ctx eudore.Context) error {
+		return watcher.Reload()
+	})
+	app.Listen(":8088")
+	// app.CancelFunc()
+	app.Run()
+}
@@ -1,11 +1,19 @@
 package eudore_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+	"github.com/eudore/eudore/middleware"
 )
 
 func TestConfigMapPrint2(t *testing.T) {
@@ -55,3 +63,280 @@ func TestConfigReadError2(t *testing.T) {
 	app.CancelFunc()
 	app.Run()
 }
+
+func TestConfigReadYAMLTOML2(t *testing.T) {
+	type serverConfig struct {
+		Addr  string   `alias:"addr"`
+		Hosts []string `alias:"hosts"`
+	}
+	type config struct {
+		Name   string       `alias:"name"`
+		Server serverConfig `alias:"server"`
+		Config []string     `alias:"config"`
+	}
+	files := map[string]string{
+		"testconfig.yaml": "name: yaml\nserver:\n  addr: ':8088'\n  hosts: [a, b]\n",
+		"testconfig.toml": "name = 'toml'\n[server]\naddr = ':8089'\nhosts = ['c']\n",
+		"testerror.yml":   "name: [yaml\n",
+		"testerror.toml":  "name = \n",
+	}
+	for name, body := range files {
+		ioutil.WriteFile(name, []byte(body), 0644)
+		defer os.Remove(name)
+	}
+
+	for _, name := range []string{"testconfig.yaml", "testconfig.toml", "testerror.yml", "testerror.toml"} {
+		data := &config{Config: []string{"notfound-file.yaml", name}}
+		conf := eudore.NewConfigEudore(data)
+		conf.Set("print", t.Log)
+		t.Log(conf.Parse(), data)
+	}
+
+	conf := eudore.NewConfigMap(nil)
+	conf.Set("config", "testconfig.yaml")
+	conf.ParseOption([]eudore.ConfigParseFunc{eudore.ConfigParseYAML})
+	t.Log(conf.Parse(), conf.Get("server"))
+	conf.Set("config", "testconfig.toml")
+	conf.ParseOption([]eudore.ConfigParseFunc{eudore.ConfigParseTOML})
+	t.Log(conf.Parse(), conf.Get("server"))
+}
+
+func TestConfigParseYAMLTOML2(t *testing.T) {
+	type M = map[string]interface{}
+	type L = []interface{}
+	cases := []struct {
+		name string
+		body string
+		data M
+		err  string
+	}{
+		{"yaml", "a: 1\nb: str\nc: true\nd: ~\n", M{"a": 1, "b": "str", "c": true, "d": nil}, ""},
+		{"yaml", "a:\n  b: [1, x]\n  c: {d: 1.5}\n", M{"a": M{"b": L{1, "x"}, "c": M{"d": 1.5}}}, ""},
+		{"yaml", "list:\n- k: v\n- - 1\n  - 2\n", M{"list": L{M{"k": "v"}, L{1, 2}}}, ""},
+		{"yaml", "s: 'it''s'\nq: \"a\\tb\" # comment\nu: http://host:80\n", M{"s": "it's", "q": "a\tb", "u": "http://host:80"}, ""},
+		{"yaml", "l: |\n  a\n  b\nf: >-\n  a\n  b\n", M{"l": "a\nb\n", "f": "a b"}, ""},
+		{"yaml", "---\na: 1\n...\nb: 2\n", M{"a": 1}, ""},
+		{"yaml", "a: 1\n---\nb: 2\n", M{"a": 1}, ""},
+		{"yaml", "a: b: c\n", nil, "yaml parse error at line 1: mapping values are not allowed in this context"},
+		{"yaml", "a:\n- b: c: d\n", nil, "yaml parse error at line 2: mapping values are not allowed in this context"},
+		{"yaml", "a: [1\n", nil, "yaml parse error at line 1: unclosed value [1"},
+		{"yaml", "a:\n\tb: 1\n", nil, "yaml parse error at line 2: tab indentation"},
+		{"yaml", "a: 1\n  b: 2\n", nil, "yaml parse error at line 2: bad indentation of a mapping entry"},
+		{"yaml", "a: &x 1\n", nil, "yaml parse error at line 1: anchors, aliases and tags are not supported &x 1"},
+		{"yaml", "a: 1\nb: *x\n", nil, "yaml parse error at line 2: anchors, aliases and tags are not supported *x"},
+		{"yaml", "a:\n- !!str 1\n", nil, "yaml parse error at line 2: anchors, aliases and tags are not supported !!str 1"},
+		{"yaml", "a: [1, *x]\n", nil, "yaml parse error at line 1: anchors, aliases and tags are not supported"},
+		{"yaml", "a: '*x'\n", M{"a": "*x"}, ""},
+		{"toml", "a = 1\nb = 'str'\nc = [1, 2.5]\n", M{"a": 1, "b": "str", "c": L{1, 2.5}}, ""},
+		{"toml", "[a]\nb.c = 1\n[a.b.d]\ne = \"\\u0041\"\n", M{"a": M{"b": M{"c": 1, "d": M{"e": "A"}}}}, ""},
+		{"toml", "[a.b]\nc = 1\n[a]\nd = 2\n", M{"a": M{"b": M{"c": 1}, "d": 2}}, ""},
+		{"toml", "[[p]]\nn = 1\n[p.q]\nx = 1\n[[p]]\nn = 2\n[p.q]\nx = 2\n", M{"p": L{M{"n": 1, "q": M{"x": 1}}, M{"n": 2, "q": M{"x": 2}}}}, ""},
+		{"toml", "t = {x = 1, y.z = 2}\n", M{"t": M{"x": 1, "y": M{"z": 2}}}, ""},
+		{"toml", "a.b = 1\n[a]\n", nil, "toml parse error at line 2: duplicate table a"},
+		{"toml", "[a]\nb.c = 1\n[a.b]\n", nil, "toml parse error at line 3: duplicate table a.b"},
+		{"toml", "[a.b]\n[a]\nb.c = 1\n", nil, "toml parse error at line 3: table a.b already defined"},
+		{"toml", "a = {b = 1}\n[a.c]\n", nil, "toml parse error at line 2: inline table a can not be extended"},
+		{"toml", "[a]\n[a]\n", nil, "toml parse error at line 2: duplicate table a"},
+		{"toml", "a = 1\na = 2\n", nil, "toml parse error at line 2: duplicate key a"},
+		{"toml", "a = \n", nil, "toml parse error at line 1: missed value"},
+		{"toml", "a = 'str\n", nil, "toml parse error at line 1: unclosed string"},
+	}
+	for i, c := range cases {
+		name := fmt.Sprintf("testparse%d.%s", i, c.name)
+		ioutil.WriteFile(name, []byte(c.body), 0644)
+		conf := eudore.NewConfigMap(nil)
+		conf.Set("config", name)
+		conf.ParseOption([]eudore.ConfigParseFunc{eudore.ConfigParseFile})
+		err := conf.Parse()
+		os.Remove(name)
+		if c.err != "" {
+			want := fmt.Sprintf("config load %s error: %s", name, c.err)
+			if err == nil || err.Error() != want {
+				t.Errorf("case %d parse error %v, want %s", i, err, want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d parse error: %v", i, err)
+			continue
+		}
+		data := conf.Get("").(map[string]interface{})
+		delete(data, "config")
+		if !reflect.DeepEqual(data, c.data) {
+			t.Errorf("case %d parse data %#v, want %#v", i, data, c.data)
+		}
+	}
+}
+
+func TestConfigWatcher2(t *testing.T) {
+	type loggerConfig struct {
+		Level eudore.LoggerLevel `alias:"level"`
+	}
+	type config struct {
+		Config []string     `alias:"config"`
+		Logger loggerConfig `alias:"logger"`
+	}
+	filename := "testwatch.yaml"
+	ioutil.WriteFile(filename, []byte("logger:\n  level: info\n"), 0644)
+	defer os.Remove(filename)
+
+	data := &config{Config: []string{filename}}
+	conf := eudore.NewConfigEudore(data)
+	conf.Set("print", t.Log)
+	conf.Parse()
+
+	watcher := eudore.NewConfigWatcher(conf)
+	watcher.Interval = 10 * time.Millisecond
+	changes := make(chan interface{}, 4)
+	watcher.Subscribe("logger", func(key string, oldval, newval interface{}) {
+		t.Log("change", key, oldval, newval)
+		changes <- newval
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Watch(ctx)
+	time.Sleep(30 * time.Millisecond)
+
+	// 修改配置文件
+	ioutil.WriteFile(filename, []byte("logger:\n  level: debug\n"), 0644)
+	os.Chtimes(filename, time.Now(), time.Now().Add(time.Second))
+	select {
+	case val := <-changes:
+		if val != eudore.LogDebug {
+			t.Fatalf("reload logger.level %v, want %v", val, eudore.LogDebug)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("config watcher not reload")
+	}
+	cancel()
+
+	// 解析失败保留之前配置
+	ioutil.WriteFile(filename, []byte("logger: [\n"), 0644)
+	if watcher.Reload() == nil {
+		t.Fatal("reload invalid config not error")
+	}
+	if level := conf.Get("logger.level"); level != eudore.LogDebug {
+		t.Fatalf("reload error logger.level %v, want keep %v", level, eudore.LogDebug)
+	}
+
+	mapconf := eudore.NewConfigMap(map[string]interface{}{"name": "eudore"})
+	mapconf.ParseOption([]eudore.ConfigParseFunc{func(c eudore.Config) error {
+		return c.Set("time", time.Now().String())
+	}})
+	watcher = eudore.NewConfigWatcher(mapconf)
+	watcher.Subscribe("", func(key string, oldval, newval interface{}) {
+		t.Log("change", key, oldval, newval)
+	})
+	t.Log(watcher.Reload())
+}
+
+func TestConfigValidate2(t *testing.T) {
+	type dbConfig struct {
+		Addr string `alias:"addr" validate:"nozero"`
+		Pool int    `alias:"pool" validate:"min:1,max:100"`
+	}
+	type config struct {
+		Name  string             `alias:"name" validate:"regexp:^[a-z]+$"`
+		Level eudore.LoggerLevel `alias:"level" validate:"max:3"`
+		DB    dbConfig           `alias:"db"`
+		Slave []dbConfig         `alias:"slave"`
+	}
+
+	conf := eudore.NewConfigEudore(&config{Name: "Eudore", Level: 4, Slave: []dbConfig{{}}})
+	conf.Set("print", t.Log)
+	conf.ParseOption([]eudore.ConfigParseFunc{eudore.ConfigParseValidate})
+	t.Log(conf.Parse())
+
+	conf = eudore.NewConfigEudore(&config{Name: "eudore", DB: dbConfig{"localhost", 10}})
+	conf.ParseOption([]eudore.ConfigParseFunc{eudore.NewConfigParseValidate(eudore.DefaultValidater)})
+	t.Log(conf.Parse())
+}
+
+func TestConfigSecrets2(t *testing.T) {
+	key := "0123456789abcdef"
+	os.Setenv(eudore.EnvEudoreConfigKey, "base64:MDEyMzQ1Njc4OWFiY2RlZg==")
+	defer os.Unsetenv(eudore.EnvEudoreConfigKey)
+	token, err := eudore.ConfigEncryptSecret([]byte(key), "token-secret")
+	t.Log(token, err)
+	ioutil.WriteFile("testsecret", []byte("password-secret\n"), 0600)
+	defer os.Remove("testsecret")
+
+	conf := eudore.NewConfigMap(map[string]interface{}{
+		"password": "file://testsecret",
+		"hint":     "password-secret",
+		"db": map[string]interface{}{
+			"token": token,
+			"file":  "file://notfound-file",
+			"enc":   "enc:-",
+		},
+	})
+	conf.Set("print", t.Log)
+	conf.ParseOption([]eudore.ConfigParseFunc{eudore.ConfigParseSecrets})
+	t.Log(conf.Parse())
+	t.Log(conf.Get("password"), conf.Get("db"))
+	if conf.Get("password") != "password-secret" {
+		t.Fatalf("secret password %v", conf.Get("password"))
+	}
+
+	// 按键脱敏，相同内容的其他键和其他配置不受影响
+	other := eudore.NewConfigMap(map[string]interface{}{"password": "password-secret"})
+	want := map[string]interface{}{"password": "******", "hint": "password-secret", "db.token": "******"}
+	for _, value := range eudore.GetConfigValues(conf) {
+		if val, ok := want[value.Key]; ok && value.Value != val {
+			t.Errorf("config value %s is %v, want %v", value.Key, value.Value, val)
+		}
+	}
+	for _, value := range eudore.GetConfigValues(other) {
+		if value.Key == "password" && value.Value != "password-secret" {
+			t.Errorf("other config value %s is %v", value.Key, value.Value)
+		}
+	}
+}
+
+func TestConfigValidateSecret2(t *testing.T) {
+	type config struct {
+		Password string `alias:"password" validate:"regexp:^[0-9]+$"`
+		Hint     string `alias:"hint" validate:"regexp:^[0-9]+$"`
+	}
+	ioutil.WriteFile("testsecret", []byte("password-secret\n"), 0600)
+	defer os.Remove("testsecret")
+
+	conf := eudore.NewConfigEudore(&config{Password: "file://testsecret", Hint: "hint-value"})
+	conf.ParseOption([]eudore.ConfigParseFunc{eudore.ConfigParseSecrets, eudore.ConfigParseValidate})
+	err := conf.Parse()
+	t.Log(err)
+	if err == nil {
+		t.Fatal("validate secret not return error")
+	}
+	if strings.Contains(err.Error(), "password-secret") || !strings.Contains(err.Error(), "config key 'password' invalid") {
+		t.Errorf("validate secret error: %v", err)
+	}
+	if !strings.Contains(err.Error(), "hint-value") {
+		t.Errorf("validate error not contains value: %v", err)
+	}
+}
+
+func TestConfigSource2(t *testing.T) {
+	ioutil.WriteFile("testsource.json", []byte(`{"db":{"host":"localhost","port":3306},"listen":":8088","enable":["debug"],"mods":{"debug":{"listen":":8089"}}}`), 0644)
+	defer os.Remove("testsource.json")
+	os.Setenv("ENV_DB_PORT", "3307")
+	defer os.Unsetenv("ENV_DB_PORT")
+
+	conf := eudore.NewConfigEudore(map[string]interface{}{
+		"config": "testsource.json",
+	})
+	conf.Set("print", t.Log)
+	t.Log(conf.Parse())
+	conf.Set("name", "eudore")
+	for _, value := range eudore.GetConfigValues(conf) {
+		t.Log(value.Key, value.Value, value.Source)
+	}
+
+	app := eudore.NewApp()
+	app.AnyFunc("/config/*", middleware.NewConfigFunc(conf))
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/config/").Do().CheckStatus(200).Out()
+	client.NewRequest("GET", "/config/db?format=text").Do().CheckStatus(200).Out()
+	app.CancelFunc()
+	app.Run()
+}
@@ -0,0 +1,46 @@
+package main
+
+/*
+eudore.BindStream逐个解析json数组或ndjson格式的请求body，每个元素调用一次处理函数，不会读取全部body到内存。
+
+每个元素使用app.Validater校验，超出body长度或元素数量限制返回Status为413的*eudore.BindStreamError，
+解析和校验失败返回Status为422的*eudore.BindStreamError，Index为出错元素的索引，同时会写入对应的响应状态码。
+*/
+
+import (
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+)
+
+type importUser struct {
+	Name string `json:"name" validate:"nozero"`
+	Age  int    `json:"age" validate:"min:0,max:150"`
+}
+
+func main() {
+	app := eudore.NewApp()
+	app.PostFunc("/users/import", func(ctx eudore.Context) interface{} {
+		var count int
+		err := eudore.BindStreamWithLimit(ctx, func(user *importUser) error {
+			count++
+			ctx.Debug("import user", user.Name, user.Age)
+			return nil
+		}, 32<<20, 10000)
+		if err != nil {
+			return err
+		}
+		return map[string]int{"count": count}
+	})
+
+	client := httptest.NewClient(app)
+	client.NewRequest("POST", "/users/import").WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationJSON).
+		WithBodyString(`[{"name":"eudore","age":1},{"name":"golang","age":10}]`).Do().CheckStatus(200).OutBody()
+	client.NewRequest("POST", "/users/import").WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationJSON).
+		WithHeaderValue(eudore.HeaderContentType, "application/x-ndjson").
+		WithBodyString("{\"name\":\"eudore\",\"age\":1}\n{\"name\":\"golang\",\"age\":10}\n").Do().CheckStatus(200).OutBody()
+	client.NewRequest("POST", "/users/import").WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationJSON).
+		WithBodyString(`[{"name":"eudore","age":1},{"name":"","age":10}]`).Do().CheckStatus(422).OutBody()
+
+	app.CancelFunc()
+	app.Run()
+}
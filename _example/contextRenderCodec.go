@@ -0,0 +1,41 @@
+package main
+
+/*
+默认的Binder和Renderer注册表支持application/msgpack、application/protobuf和application/cbor格式，
+按照请求Content-Type选择Binder，按照Accept Header协商选择Renderer，RPC处理函数可以直接使用。
+
+msgpack和cbor结构体属性名称优先使用msgpack或cbor tag，其次使用json tag。
+protobuf对象需要实现proto.Message接口，默认使用消息的Marshal和Unmarshal方法，
+使用google.golang.org/protobuf时需要设置eudore.DefaultProtobufMarshal和eudore.DefaultProtobufUnmarshal。
+*/
+
+import (
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+)
+
+type codecRequest struct {
+	Name string `json:"name"`
+}
+
+type codecResponse struct {
+	Message string `json:"message"`
+}
+
+func main() {
+	app := eudore.NewApp()
+	app.PostFunc("/hello", func(ctx eudore.Context, req *codecRequest) (*codecResponse, error) {
+		return &codecResponse{Message: "hello " + req.Name}, nil
+	})
+
+	client := httptest.NewClient(app)
+	body, _ := eudore.MarshalMsgpack(codecRequest{Name: "eudore"})
+	client.NewRequest("POST", "/hello").WithHeaderValue(eudore.HeaderContentType, eudore.MimeApplicationMsgpack).
+		WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationMsgpack).WithBodyBytes(body).Do().CheckStatus(200).OutBody()
+	body, _ = eudore.MarshalCBOR(codecRequest{Name: "eudore"})
+	client.NewRequest("POST", "/hello").WithHeaderValue(eudore.HeaderContentType, eudore.MimeApplicationCBOR).
+		WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationJSON).WithBodyBytes(body).Do().CheckStatus(200).OutBody()
+
+	app.CancelFunc()
+	app.Run()
+}
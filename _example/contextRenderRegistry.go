@@ -0,0 +1,51 @@
+package main
+
+/*
+eudore.RendererRegistry按照mime类型注册Renderer，根据Accept Header的q权重、具体程度和通配符选择Renderer，
+注册多个Renderer时响应设置Vary: Accept，NotAcceptable为true时没有可接受的类型返回406。
+Renderer返回错误并且没有写入响应body时使用下一个可接受的Renderer，例如浏览器Accept偏好xml但是map无法序列化为xml。
+
+eudore.BinderRegistry按照Content-Type注册Binder，支持"type/*"和任意类型通配符。
+RenderDefault和BindDefault分别使用eudore.DefaultRendererRegistry和eudore.DefaultBinderRegistry。
+*/
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+)
+
+func main() {
+	// 默认注册表添加新的格式
+	eudore.DefaultRendererRegistry.Register("application/vnd.eudore+json", func(ctx eudore.Context, data interface{}) error {
+		ctx.SetHeader(eudore.HeaderContentType, "application/vnd.eudore+json")
+		return json.NewEncoder(ctx).Encode(map[string]interface{}{"data": data})
+	})
+	eudore.DefaultBinderRegistry.Register("application/vnd.eudore+json", func(ctx eudore.Context, r io.Reader, i interface{}) error {
+		return json.NewDecoder(r).Decode(i)
+	})
+	eudore.DefaultRendererRegistry.NotAcceptable = true
+
+	app := eudore.NewApp()
+	app.AnyFunc("/*", func(ctx eudore.Context) interface{} {
+		data := map[string]interface{}{"name": "eudore"}
+		if ctx.Method() != eudore.MethodGet {
+			ctx.Bind(&data)
+		}
+		return data
+	})
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/").WithHeaderValue(eudore.HeaderAccept, "application/xml;q=0.9, application/json").Do().CheckStatus(200).OutBody()
+	client.NewRequest("GET", "/").WithHeaderValue(eudore.HeaderAccept, "application/vnd.eudore+json, */*;q=0.1").Do().CheckStatus(200).OutBody()
+	client.NewRequest("GET", "/").WithHeaderValue(eudore.HeaderAccept, "text/*").Do().CheckStatus(200).OutBody()
+	client.NewRequest("GET", "/").WithHeaderValue(eudore.HeaderAccept, "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8").Do().CheckStatus(200).OutBody()
+	client.NewRequest("GET", "/").WithHeaderValue(eudore.HeaderAccept, "image/png").Do().CheckStatus(406).OutBody()
+	client.NewRequest("PUT", "/").WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationJSON).
+		WithHeaderValue(eudore.HeaderContentType, "application/vnd.eudore+json").WithBodyString(`{"version":"1"}`).Do().CheckStatus(200).OutBody()
+
+	app.CancelFunc()
+	app.Run()
+}
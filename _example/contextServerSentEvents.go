@@ -0,0 +1,51 @@
+package main
+
+/*
+eudore.WriteServerSentEvent写入一个Server-Sent Events事件并刷新缓冲，第一次写入时设置text/event-stream响应Header。
+eudore.ServerSentEvents的Stream方法从chan读取事件写入响应，间隔发送心跳注释，chan关闭或客户端断开连接时返回。
+
+eudore.ServerSentEvents的Resume函数在请求存在Last-Event-ID Header时调用，用于补发客户端重连前的事件。
+middleware.NewGzipFunc不会压缩text/event-stream响应。
+*/
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+	"github.com/eudore/eudore/middleware"
+)
+
+func main() {
+	app := eudore.NewApp()
+	app.AddMiddleware(middleware.NewGzipFunc(5))
+	app.GetFunc("/events", func(ctx eudore.Context) error {
+		sse := &eudore.ServerSentEvents{
+			Heartbeat: 15 * time.Second,
+			Resume: func(ctx eudore.Context, id string) error {
+				return eudore.WriteServerSentEvent(ctx, &eudore.ServerSentEvent{ID: id, Event: "resume", Data: "resume from " + id})
+			},
+		}
+		ch := make(chan *eudore.ServerSentEvent)
+		go func() {
+			defer close(ch)
+			for i := 0; i < 3; i++ {
+				select {
+				case ch <- &eudore.ServerSentEvent{ID: fmt.Sprint(i), Data: map[string]int{"count": i}}:
+				case <-ctx.GetContext().Done():
+					return
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+		}()
+		return sse.Stream(ctx, ch)
+	})
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/events").WithHeaderValue(eudore.HeaderAcceptEncoding, "gzip").Do().CheckStatus(200).OutBody()
+	client.NewRequest("GET", "/events").WithHeaderValue(eudore.HeaderLastEventID, "2").Do().CheckStatus(200).OutBody()
+
+	app.CancelFunc()
+	app.Run()
+}
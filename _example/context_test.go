@@ -1,12 +1,17 @@
 package eudore_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/eudore/eudore"
 	"github.com/eudore/eudore/component/httptest"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestContext2(t *testing.T) {
@@ -84,3 +89,357 @@ func TestReadWriteError2(t *testing.T) {
 	app.CancelFunc()
 	app.Run()
 }
+
+type bindStreamItem struct {
+	Name string `json:"name" validate:"nozero"`
+	Age  int    `json:"age"`
+}
+
+func TestContextBindStream2(t *testing.T) {
+	app := eudore.NewApp()
+	app.PostFunc("/stream", func(ctx eudore.Context) error {
+		var count int
+		err := eudore.BindStreamWithLimit(ctx, func(item *bindStreamItem) error {
+			count++
+			ctx.Debug("item", item.Name, item.Age)
+			return nil
+		}, 1024, 3)
+		if err != nil {
+			return err
+		}
+		return ctx.WriteString(fmt.Sprint(count))
+	})
+	app.PostFunc("/value", func(ctx eudore.Context) error {
+		return eudore.BindStream(ctx, func(item map[string]interface{}) error {
+			ctx.Debug("value", item)
+			return nil
+		})
+	})
+	app.PostFunc("/func", func(ctx eudore.Context) error {
+		return eudore.BindStream(ctx, func(*bindStreamItem) {})
+	})
+
+	client := httptest.NewClient(app)
+	client.NewRequest("POST", "/stream").WithBodyString(`[{"name":"a","age":1}, {"name":"b","age":2}]`).Do().CheckStatus(200).CheckBodyString("2")
+	client.NewRequest("POST", "/stream").WithBodyString("{\"name\":\"a\"}\n{\"name\":\"b\"}\n{\"name\":\"c\"}\n").Do().CheckStatus(200).CheckBodyString("3")
+	client.NewRequest("POST", "/stream").WithBodyString(`[]`).Do().CheckStatus(200).CheckBodyString("0")
+	client.NewRequest("POST", "/stream").WithBodyString(`[{"name":"a"},{"name":"b"},{"name":"c"},{"name":"d"}]`).Do().CheckStatus(413)
+	client.NewRequest("POST", "/stream").WithBodyString(`[{"name":"a"},{"name":""}]`).Do().CheckStatus(422)
+	client.NewRequest("POST", "/stream").WithBodyString(`[{"name":"a"},{"name":1}]`).Do().CheckStatus(422)
+	client.NewRequest("POST", "/stream").WithBodyString(`[{"name":"a"},`).Do().CheckStatus(422)
+	client.NewRequest("POST", "/stream").WithBodyString(`[{"name":"` + strings.Repeat("a", 2048) + `"}]`).Do().CheckStatus(413)
+	client.NewRequest("POST", "/value").WithBodyString(`[{"name":"a"},{"name":"b"}]`).Do().CheckStatus(200)
+	client.NewRequest("POST", "/func").WithBodyString(`[]`).Do().CheckStatus(500)
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestContextRendererRegistry2(t *testing.T) {
+	renders := eudore.NewRendererRegistry()
+	renders.Register(eudore.MimeApplicationJSON, eudore.RenderJSON)
+	renders.Register(eudore.MimeApplicationXML, eudore.RenderXML)
+	renders.Register(eudore.MimeTextPlain, eudore.RenderText)
+	renders.Register(eudore.MimeTextPlain, nil)
+	renders.NotAcceptable = true
+	binders := eudore.NewBinderRegistry()
+	binders.Register(eudore.MimeApplicationJSON, eudore.BindJSON)
+	binders.Register("text/*", func(ctx eudore.Context, r io.Reader, i interface{}) error {
+		return eudore.BindURL(ctx, r, i)
+	})
+
+	app := eudore.NewApp()
+	app.Binder = binders.Bind
+	app.Renderer = renders.Render
+	app.AnyFunc("/render", func(ctx eudore.Context) interface{} {
+		var data map[string]interface{}
+		if ctx.Method() != eudore.MethodGet {
+			err := ctx.Bind(&data)
+			if err != nil {
+				ctx.Error(err)
+			}
+		}
+		return []string{"eudore", "render"}
+	})
+
+	client := httptest.NewClient(app)
+	accepts := []struct {
+		accept string
+		status int
+		mime   string
+	}{
+		{"", 200, eudore.MimeApplicationJSON},
+		{"*/*", 200, eudore.MimeApplicationJSON},
+		// q权重
+		{"application/xml;q=0.9, application/json", 200, eudore.MimeApplicationJSON},
+		{"application/json;q=0.5, application/xml", 200, eudore.MimeApplicationXML},
+		// 权重相同选择Accept靠前的类型
+		{"application/xml, application/json", 200, eudore.MimeApplicationXML},
+		// 具体程度，具体类型的q=0覆盖通配符
+		{"application/*;q=0.5, application/json;q=0", 200, eudore.MimeApplicationXML},
+		{"application/json;q=0.1, application/*;q=0.5", 200, eudore.MimeApplicationXML},
+		{"application/json;v=1;q=0.1, application/json;q=0.5, */*", 200, eudore.MimeApplicationXML},
+		// 通配符权重相同按照注册顺序
+		{"text/*, */*;q=0.1", 200, eudore.MimeApplicationJSON},
+		{"image/png", 406, eudore.MimeTextPlain},
+		{"application/*;q=0", 406, eudore.MimeTextPlain},
+	}
+	for _, accept := range accepts {
+		resp := client.NewRequest("GET", "/render").WithHeaderValue(eudore.HeaderAccept, accept.accept).Do()
+		mime := resp.Header().Get(eudore.HeaderContentType)
+		if resp.Code != accept.status || !strings.HasPrefix(mime, accept.mime) || resp.Header().Get(eudore.HeaderVary) != eudore.HeaderAccept {
+			t.Errorf("accept %q render %d %s, want %d %s", accept.accept, resp.Code, mime, accept.status, accept.mime)
+		}
+	}
+	client.NewRequest("PUT", "/render").WithHeaderValue(eudore.HeaderContentType, "application/json; charset=utf-8").WithBodyString(`{"name":"eudore"}`).Do().CheckStatus(200)
+	client.NewRequest("PUT", "/render").WithHeaderValue(eudore.HeaderContentType, "text/plain").Do().CheckStatus(200)
+	client.NewRequest("PUT", "/render").WithHeaderValue(eudore.HeaderContentType, "image/png").Do().CheckStatus(200)
+
+	// 默认注册表，浏览器Accept偏好xml，map无法序列化为xml时使用下一个可接受的Renderer
+	app.Renderer = eudore.RenderDefault
+	app.GetFunc("/map", func(ctx eudore.Context) interface{} {
+		return map[string]interface{}{"name": "eudore"}
+	})
+	browser := "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+	resp := client.NewRequest("GET", "/map").WithHeaderValue(eudore.HeaderAccept, browser).Do()
+	if resp.Code != 200 || !strings.HasPrefix(resp.Header().Get(eudore.HeaderContentType), eudore.MimeTextPlain) {
+		t.Errorf("browser accept render %d %s", resp.Code, resp.Header().Get(eudore.HeaderContentType))
+	}
+	resp = client.NewRequest("GET", "/render").WithHeaderValue(eudore.HeaderAccept, browser).Do()
+	if resp.Code != 200 || !strings.HasPrefix(resp.Header().Get(eudore.HeaderContentType), eudore.MimeApplicationXML) {
+		t.Errorf("browser accept render %d %s", resp.Code, resp.Header().Get(eudore.HeaderContentType))
+	}
+	resp = client.NewRequest("GET", "/render").WithHeaderValue(eudore.HeaderAccept, "image/png").Do()
+	if resp.Code != 200 || !strings.HasPrefix(resp.Header().Get(eudore.HeaderContentType), eudore.MimeTextPlain) {
+		t.Errorf("not acceptable render %d %s", resp.Code, resp.Header().Get(eudore.HeaderContentType))
+	}
+
+	app.CancelFunc()
+	app.Run()
+}
+
+type codecRequest struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+type codecResponse struct {
+	Message string `msgpack:"message" cbor:"msg"`
+	Count   int    `json:"count"`
+}
+
+// protoMessage 手动实现proto.Message和Marshal/Unmarshal方法，字段1为name。
+type protoMessage struct {
+	Name string
+}
+
+func (*protoMessage) Reset()         {}
+func (*protoMessage) String() string { return "" }
+func (*protoMessage) ProtoMessage()  {}
+func (m *protoMessage) Marshal() ([]byte, error) {
+	return append([]byte{0x0a, byte(len(m.Name))}, m.Name...), nil
+}
+func (m *protoMessage) Unmarshal(data []byte) error {
+	if len(data) < 2 || data[0] != 0x0a || int(data[1]) != len(data)-2 {
+		return errors.New("invalid protobuf data")
+	}
+	m.Name = string(data[2:])
+	return nil
+}
+
+func TestContextCodec2(t *testing.T) {
+	app := eudore.NewApp()
+	app.AnyFunc("/rpc", func(ctx eudore.Context, req *codecRequest) (*codecResponse, error) {
+		return &codecResponse{Message: "hello " + req.Name, Count: len(req.Tags)}, nil
+	})
+	app.AnyFunc("/proto", func(ctx eudore.Context, req *protoMessage) (*protoMessage, error) {
+		return &protoMessage{Name: "hello " + req.Name}, nil
+	})
+	app.AnyFunc("/proto/invalid", func(ctx eudore.Context) interface{} {
+		return map[string]string{"name": "eudore"}
+	})
+
+	client := httptest.NewClient(app)
+	body, _ := eudore.MarshalMsgpack(codecRequest{Name: "eudore", Tags: []string{"a", "b"}})
+	resp := client.NewRequest("POST", "/rpc").WithHeaderValue(eudore.HeaderContentType, eudore.MimeApplicationMsgpack).
+		WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationMsgpack).WithBodyBytes(body).Do().CheckStatus(200).
+		CheckHeader(eudore.HeaderContentType, eudore.MimeApplicationMsgpack)
+	var data codecResponse
+	t.Log(eudore.UnmarshalMsgpack(resp.Body.Bytes(), &data), data)
+
+	body, _ = eudore.MarshalCBOR(map[string]interface{}{"name": "eudore", "tags": []string{"a"}})
+	resp = client.NewRequest("POST", "/rpc").WithHeaderValue(eudore.HeaderContentType, eudore.MimeApplicationCBOR).
+		WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationCBOR).WithBodyBytes(body).Do().CheckStatus(200).
+		CheckHeader(eudore.HeaderContentType, eudore.MimeApplicationCBOR)
+	var msg map[string]interface{}
+	t.Log(eudore.UnmarshalCBOR(resp.Body.Bytes(), &msg), msg)
+	client.NewRequest("POST", "/rpc").WithHeaderValue(eudore.HeaderContentType, eudore.MimeApplicationCBOR).
+		WithBodyBytes(body[:len(body)-1]).Do().CheckStatus(500)
+
+	body, _ = (&protoMessage{Name: "eudore"}).Marshal()
+	resp = client.NewRequest("POST", "/proto").WithHeaderValue(eudore.HeaderContentType, "application/x-protobuf").
+		WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationProtobuf).WithBodyBytes(body).Do().CheckStatus(200).
+		CheckHeader(eudore.HeaderContentType, eudore.MimeApplicationProtobuf)
+	t.Logf("%q", resp.Body.String())
+	client.NewRequest("GET", "/proto/invalid").WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationProtobuf).Do().CheckStatus(500)
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestContextCodecVector2(t *testing.T) {
+	type codec struct {
+		marshal   func(interface{}) ([]byte, error)
+		unmarshal func([]byte, interface{}) error
+		vector    []byte
+		invalid   error
+		// 声明长度远大于实际数据
+		oversized []byte
+	}
+	codecs := map[string]codec{
+		"msgpack": {eudore.MarshalMsgpack, eudore.UnmarshalMsgpack, []byte{0x81, 0xa1, 0x61, 0x01}, eudore.ErrMsgpackDataInvalid, []byte{0xdb, 0xff, 0xff, 0xff, 0xff, 0x61}},
+		"cbor":    {eudore.MarshalCBOR, eudore.UnmarshalCBOR, []byte{0xa1, 0x61, 0x61, 0x01}, eudore.ErrCBORDataInvalid, []byte{0x7a, 0xff, 0xff, 0xff, 0xff, 0x61}},
+	}
+	for name, c := range codecs {
+		// 已知编码向量
+		body, err := c.marshal(map[string]int{"a": 1})
+		if err != nil || !bytes.Equal(body, c.vector) {
+			t.Errorf("%s marshal % x %v, want % x", name, body, err, c.vector)
+		}
+		var vector map[string]int
+		err = c.unmarshal(c.vector, &vector)
+		if err != nil || len(vector) != 1 || vector["a"] != 1 {
+			t.Errorf("%s unmarshal %v %v", name, vector, err)
+		}
+
+		// 往返编解码
+		req := codecRequest{Name: "eudore", Tags: []string{"a", "b"}}
+		body, err = c.marshal(req)
+		var data codecRequest
+		if err == nil {
+			err = c.unmarshal(body, &data)
+		}
+		if err != nil || data.Name != req.Name || strings.Join(data.Tags, ",") != "a,b" {
+			t.Errorf("%s round trip %v %v", name, data, err)
+		}
+
+		// 截断、多余和超长数据
+		for _, body := range [][]byte{c.vector[:len(c.vector)-1], append(c.vector[:len(c.vector):len(c.vector)], 0x00), c.oversized} {
+			if err := c.unmarshal(body, &vector); err != c.invalid {
+				t.Errorf("%s unmarshal invalid data % x error: %v", name, body, err)
+			}
+		}
+	}
+
+	// 默认protobuf编码函数需要消息实现Marshal方法
+	err := eudore.RenderProtobuf(eudore.NewContextBase(nil), &struct{ protoEmpty }{})
+	if err == nil || !strings.Contains(err.Error(), "eudore.DefaultProtobufMarshal") {
+		t.Error("protobuf marshal error:", err)
+	}
+}
+
+// protoEmpty 只实现proto.Message接口，没有Marshal和Unmarshal方法。
+type protoEmpty struct{}
+
+func (protoEmpty) ProtoMessage() {}
+
+func TestContextServerSentEvents2(t *testing.T) {
+	app := eudore.NewApp()
+	app.GetFunc("/events", func(ctx eudore.Context) error {
+		ch := make(chan *eudore.ServerSentEvent)
+		go func() {
+			defer close(ch)
+			for i := 0; i < 3; i++ {
+				ch <- &eudore.ServerSentEvent{ID: fmt.Sprint(i), Event: "message", Data: fmt.Sprintf("line %d\nline end", i)}
+			}
+			ch <- &eudore.ServerSentEvent{Event: "json", Data: map[string]int{"count": 3}, Retry: 3 * time.Second}
+		}()
+		return (&eudore.ServerSentEvents{}).Stream(ctx, ch)
+	})
+	app.GetFunc("/events/heartbeat", func(ctx eudore.Context) error {
+		c, cancel := context.WithTimeout(ctx.GetContext(), 50*time.Millisecond)
+		defer cancel()
+		ctx.WithContext(c)
+		return (&eudore.ServerSentEvents{Heartbeat: 10 * time.Millisecond}).Stream(ctx, make(chan *eudore.ServerSentEvent))
+	})
+	app.GetFunc("/events/resume", func(ctx eudore.Context) error {
+		sse := &eudore.ServerSentEvents{
+			Resume: func(ctx eudore.Context, id string) error {
+				return eudore.WriteServerSentEvent(ctx, &eudore.ServerSentEvent{ID: id, Data: "resume from " + id})
+			},
+		}
+		ch := make(chan *eudore.ServerSentEvent, 1)
+		ch <- &eudore.ServerSentEvent{ID: "next", Data: "next"}
+		close(ch)
+		return sse.Stream(ctx, ch)
+	})
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/events").Do().CheckStatus(200).CheckHeader(eudore.HeaderContentType, eudore.MimeTextEventStream).
+		CheckBodyContainString("id: 0\nevent: message\ndata: line 0\ndata: line end\n\n", "retry: 3000\ndata: {\"count\":3}\n\n").OutBody()
+	client.NewRequest("GET", "/events/heartbeat").Do().CheckStatus(200).CheckBodyContainString(": heartbeat\n\n")
+	client.NewRequest("GET", "/events/resume").WithHeaderValue(eudore.HeaderLastEventID, "2").Do().CheckStatus(200).
+		CheckBodyContainString("id: 2\ndata: resume from 2\n\n", "id: next\n").OutBody()
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestContextWebsocket2(t *testing.T) {
+	app := eudore.NewApp()
+	app.GetFunc("/ws", func(ctx eudore.Context) {
+		conn, err := eudore.DefaultWebsocketUpgrader.Upgrade(ctx)
+		if err != nil {
+			return
+		}
+		conn.ReadLimit = 1 << 10
+		go func() {
+			defer conn.Close()
+			for {
+				messageType, data, err := conn.ReadMessage()
+				if err != nil {
+					t.Log("server read:", err)
+					return
+				}
+				if conn.WriteMessage(messageType, data) != nil {
+					return
+				}
+			}
+		}()
+	})
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/ws").Do().CheckStatus(400)
+	client.NewRequest("POST", "/ws").Do().CheckStatus(405)
+
+	done := make(chan struct{})
+	client.NewRequest("GET", "/ws").WithWebsocketConn(func(conn *eudore.WebsocketConn) {
+		defer close(done)
+		defer conn.Close()
+		pong := make(chan string, 1)
+		conn.PongHandler = func(data []byte) error {
+			pong <- string(data)
+			return nil
+		}
+		t.Log("compression:", conn.Compression())
+		go func() {
+			conn.WriteText("hello websocket")
+			conn.WriteMessage(eudore.WebsocketMessageBinary, bytes.Repeat([]byte("eudore"), 100))
+			conn.WritePing([]byte("ping"))
+			conn.WriteText(strings.Repeat("large", 1<<10))
+		}()
+		for i := 0; i < 2; i++ {
+			messageType, data, err := conn.ReadMessage()
+			t.Log(messageType, len(data), err)
+		}
+		_, _, err := conn.ReadMessage()
+		t.Log("pong:", <-pong, "close:", err)
+		if e, ok := err.(*eudore.WebsocketCloseError); !ok || e.Code != eudore.WebsocketCloseMessageTooBig {
+			t.Error("websocket read limit close error:", err)
+		}
+	}).Do().CheckStatus(101)
+	<-done
+
+	app.CancelFunc()
+	app.Run()
+}
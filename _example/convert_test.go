@@ -5,6 +5,7 @@ import (
 	"github.com/eudore/eudore"
 	"github.com/kr/pretty"
 	"testing"
+	"time"
 )
 
 type (
@@ -155,3 +156,49 @@ func TestConvertTo4(*testing.T) {
 		fmt.Printf("struct: %# v\n", pretty.Formatter(m3))
 	}
 }
+
+type convertTextUnmarshaler struct {
+	Text string
+}
+
+func (t *convertTextUnmarshaler) UnmarshalText(data []byte) error {
+	if string(data) == "error" {
+		return fmt.Errorf("convert text unmarshaler error")
+	}
+	t.Text = "text:" + string(data)
+	return nil
+}
+
+func TestConvertTextUnmarshaler2(t *testing.T) {
+	type config struct {
+		Level eudore.LoggerLevel     `alias:"level"`
+		Text  convertTextUnmarshaler `alias:"text"`
+		Time  time.Time              `alias:"time"`
+	}
+	var data config
+	err := eudore.ConvertTo(map[string]interface{}{
+		"level": " error ",
+		"text":  "eudore",
+		"time":  "2006-01-02T15:04:05Z",
+	}, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Level != eudore.LogError || data.Text.Text != "text:eudore" || data.Time.Year() != 2006 {
+		t.Fatalf("convert TextUnmarshaler result: %#v", data)
+	}
+
+	if eudore.Set(&data, "text", "error") == nil {
+		t.Fatal("set TextUnmarshaler error not return")
+	}
+	if eudore.Set(&data, "level", "unknown") == nil {
+		t.Fatal("set LoggerLevel unknown not return error")
+	}
+	// 数值和空字符串按照原有int规则解析。
+	if err := eudore.Set(&data, "level", ""); err != nil || data.Level != eudore.LogDebug {
+		t.Fatalf("set LoggerLevel empty: %v %v", err, data.Level)
+	}
+	if err := eudore.Set(&data, "level", "3"); err != nil || data.Level != eudore.LogError {
+		t.Fatalf("set LoggerLevel 3: %v %v", err, data.Level)
+	}
+}
@@ -10,6 +10,17 @@ Link 如果非空会作为软连接的目标路径。
 Level 日志输出级别。
 TimeFormat 日志输出时间格式化格式。
 FileLine 是否输出调用日志输出的函数和文件位置
+Encoder 设置日志条目编码函数，如果为空会使用Format和Key创建一个LoggerEncoderFunc。
+Format 指定日志输出格式，可以为json、logfmt、console，默认为json。
+TimeKey LevelKey MessageKey 指定json和logfmt格式的时间、级别、消息的键名。
+CallerKey 如果非空，调用位置使用一个键输出"file:line"，否则使用name、file、line三个键输出。
+Rotate 指定按时间切割周期，可以为hour或day，Path中没有时间字符串会在文件扩展名前添加时间。
+MaxAge MaxBackups MaxTotalSize 指定切割文件保留的时间、数量和总大小。
+Compress 是否使用gzip压缩切割后的文件。
+AsyncSize 如果大于0使用异步写入，指定缓冲的日志条目数量。
+AsyncPolicy 指定异步缓冲满时的处理策略，可以为block、droplow、dropall，默认为block。
+SamplingInitial 如果大于0开启日志采样，每秒内相同级别和消息的日志输出前SamplingInitial条。
+SamplingThereafter 每秒超过SamplingInitial条后每SamplingThereafter条输出一条，丢弃数量在Sync时输出。
 
 LoggerStd的配置，可以使用*LoggerStdConfig或者map类型。
 
@@ -22,6 +33,21 @@ type LoggerStdConfig struct {
 	Level      LoggerLevel  `json:"level" alias:"level"`
 	TimeFormat string       `json:"timeformat" alias:"timeformat"`
 	FileLine   bool         `json:"fileline" alias:"fileline"`
+	Encoder    LoggerEncoderFunc `json:"-" alias:"encoder"`
+	Format     string            `json:"format" alias:"format"`
+	TimeKey    string            `json:"timekey" alias:"timekey"`
+	LevelKey   string            `json:"levelkey" alias:"levelkey"`
+	MessageKey string            `json:"messagekey" alias:"messagekey"`
+	CallerKey  string            `json:"callerkey" alias:"callerkey"`
+	Rotate       string        `json:"rotate" alias:"rotate"`
+	MaxAge       time.Duration `json:"maxage" alias:"maxage"`
+	MaxBackups   int           `json:"maxbackups" alias:"maxbackups"`
+	MaxTotalSize uint64        `json:"maxtotalsize" alias:"maxtotalsize"`
+	Compress     bool          `json:"compress" alias:"compress"`
+	AsyncSize    int           `json:"asyncsize" alias:"asyncsize"`
+	AsyncPolicy  string        `json:"asyncpolicy" alias:"asyncpolicy"`
+	SamplingInitial    int `json:"samplinginitial" alias:"samplinginitial"`
+	SamplingThereafter int `json:"samplingthereafter" alias:"samplingthereafter"`
 }
 */
 
@@ -0,0 +1,46 @@
+package main
+
+/*
+LoggerStdConfig的AsyncSize属性大于0时使用eudore.NewLoggerWriterAsync异步写入日志。
+
+异步写入流使用固定大小的环形缓冲，后台goroutine批量写入文件，AsyncPolicy指定缓冲满时的处理策略:
+	block	等待缓冲有空闲位置，默认策略。
+	droplow	Debug和Info日志在缓冲使用超过3/4时丢弃，其他级别日志在缓冲满时丢弃。
+	dropall	缓冲满时丢弃全部级别日志。
+
+未知的AsyncPolicy会返回错误，App.Run结束时调用Logger.Sync等待缓冲日志全部写入。
+Dropped方法返回每个级别丢弃的日志数量，需要自行创建异步写入流设置为Writer。
+*/
+
+import (
+	"os"
+
+	"github.com/eudore/eudore"
+)
+
+func main() {
+	defer os.Remove("async.log")
+	lw, err := eudore.NewLoggerWriterFile("async.log", false)
+	if err != nil {
+		panic(err)
+	}
+	aw, err := eudore.NewLoggerWriterAsync(lw, 1024, "droplow")
+	if err != nil {
+		panic(err)
+	}
+	app := eudore.NewApp(eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Writer: aw,
+	}))
+
+	for i := 0; i < 10000; i++ {
+		app.Info("info message", i)
+		app.Error("error message", i)
+	}
+	app.Sync()
+	dropped := aw.Dropped()
+	app.Warningf("async dropped info %d error %d", dropped[eudore.LogInfo], dropped[eudore.LogError])
+
+	app.CancelFunc()
+	app.Run()
+	aw.Close()
+}
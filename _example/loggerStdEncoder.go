@@ -0,0 +1,38 @@
+package main
+
+/*
+LoggerStdConfig的Format属性选择日志输出格式:
+	json     每行一个json对象，默认格式，可以使用TimeKey、LevelKey、MessageKey、CallerKey修改键名。
+	logfmt   key=value格式，字符串包含空格时使用引号。
+	console  开发环境使用的彩色终端格式。
+
+Encoder属性可以设置自定义的日志条目编码函数，全部编码函数使用相同的方法格式化属性值。
+*/
+
+import (
+	"github.com/eudore/eudore"
+)
+
+func main() {
+	for _, format := range []string{"json", "logfmt", "console"} {
+		log := eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+			Std:        true,
+			Format:     format,
+			FileLine:   true,
+			TimeKey:    "ts",
+			MessageKey: "msg",
+			CallerKey:  "caller",
+		})
+		log.WithField("format", format).WithField("route", "/api/v1/*").Info("hello eudore")
+		log.WithField("data", map[string]int{"a": 1}).Warning("warning message")
+		log.Sync()
+	}
+
+	app := eudore.NewApp(eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Std:     true,
+		Encoder: eudore.NewLoggerEncoderLogfmt("ts", "lvl", "msg"),
+	}))
+	app.Info("custom encoder")
+	app.CancelFunc()
+	app.Run()
+}
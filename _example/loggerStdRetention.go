@@ -0,0 +1,40 @@
+package main
+
+/*
+LoggerStdConfig的Rotate属性设置按时间切割，可以为hour或day，和MaxSize按大小切割独立设置。
+如果Path中没有时间字符串，会在文件扩展名前添加时间，例如logger-index.log切割为logger-index-2006-01-02.log。
+
+MaxAge、MaxBackups、MaxTotalSize设置切割文件的保留时间、保留数量和总大小，Compress使用gzip压缩切割后的文件。
+清理和压缩在每次切割后使用后台goroutine执行，不会处理正在写入的文件，Link软连接指向正在写入的文件。
+
+也可以使用eudore.NewLoggerWriterRotateWithConfig函数创建LoggerWriter。
+*/
+
+import (
+	"os"
+	"time"
+
+	"github.com/eudore/eudore"
+)
+
+func main() {
+	defer os.RemoveAll("logger")
+	app := eudore.NewApp(eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Path:         "logger/logger-index.log",
+		Link:         "logger/logger.log",
+		MaxSize:      1 << 10, // 1k
+		Rotate:       "day",
+		MaxAge:       7 * 24 * time.Hour,
+		MaxBackups:   5,
+		MaxTotalSize: 4 << 10,
+		Compress:     true,
+	}))
+
+	for i := 0; i < 100; i++ {
+		app.Info("now is", time.Now().String())
+	}
+	app.Sync()
+
+	app.CancelFunc()
+	app.Run()
+}
@@ -0,0 +1,27 @@
+package main
+
+/*
+LoggerStdConfig的SamplingInitial和SamplingThereafter属性开启日志采样，用于限制错误风暴时的日志输出。
+
+每秒内相同级别和消息的日志输出前SamplingInitial条，之后每SamplingThereafter条输出一条，Fatal级别日志不会被丢弃。
+丢弃的日志数量在Sync时输出一条Warning日志，App.Run会定时调用Sync。
+*/
+
+import (
+	"github.com/eudore/eudore"
+)
+
+func main() {
+	app := eudore.NewApp(eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Std:                true,
+		SamplingInitial:    10,
+		SamplingThereafter: 100,
+	}))
+	for i := 0; i < 1000; i++ {
+		app.Error("database connection refused")
+	}
+	app.Info("info message")
+
+	app.CancelFunc()
+	app.Run()
+}
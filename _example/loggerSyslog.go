@@ -0,0 +1,58 @@
+package main
+
+/*
+NewLoggerWriterSyslog函数创建syslog写入流，NewLoggerEncoderSyslog函数创建RFC 5424格式编码。
+NewLoggerWriterJournald函数创建journald写入流，NewLoggerEncoderJournald函数创建journald原生协议编码。
+
+日志级别转换为syslog severity，WithField设置的属性在syslog中写入structured data，在journald中写入大写的字段名称。
+LoggerStdConfig的Format属性可以设置为syslog或journald使用默认编码。
+
+示例使用一个本地unixgram socket模拟syslog服务。
+*/
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/eudore/eudore"
+)
+
+func main() {
+	addr := "/tmp/eudore-syslog.sock"
+	os.Remove(addr)
+	defer os.Remove(addr)
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		panic(err)
+	}
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			fmt.Printf("syslog receive: %s\n", buf[:n])
+		}
+	}()
+
+	// 连接默认syslog使用eudore.NewLoggerWriterSyslog("", "")
+	lw, err := eudore.NewLoggerWriterSyslog("unixgram", addr)
+	if err != nil {
+		panic(err)
+	}
+	app := eudore.NewApp(eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Writer:  lw,
+		Encoder: eudore.NewLoggerEncoderSyslog(1, "eudore"),
+	}))
+	app.WithField("route", "/api/v1/*").Info("hello syslog")
+	app.WithField("error", "connection refused").Error("database error")
+
+	time.Sleep(100 * time.Millisecond)
+
+	app.CancelFunc()
+	app.Run()
+	conn.Close()
+}
@@ -1,11 +1,17 @@
 package eudore_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"net"
 	"os"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"bou.ke/monkey"
 	"github.com/eudore/eudore"
@@ -299,3 +305,286 @@ func BenchmarkLoggerStd(b *testing.B) {
 	log.Sync()
 	os.Remove("t2.log")
 }
+
+func TestLoggerStdEncoder2(t *testing.T) {
+	for _, format := range []string{"json", "logfmt", "console"} {
+		log := eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+			Std:        true,
+			Format:     format,
+			FileLine:   true,
+			TimeKey:    "ts",
+			MessageKey: "msg",
+			CallerKey:  "caller",
+		})
+		log.WithField("str", "hello world").WithField("int", 1).WithField("map", map[string]int{"a": 1}).Info("hello")
+		log.WithField("empty", "").Warning()
+		log.Sync()
+	}
+
+	log := eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Std:     true,
+		Encoder: eudore.NewLoggerEncoderJSON("ts", "lvl", "msg"),
+	})
+	log.Info("custom encoder")
+	log.Sync()
+}
+
+func TestLoggerStdSampling2(t *testing.T) {
+	log := eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Std:                true,
+		SamplingInitial:    3,
+		SamplingThereafter: 10,
+	})
+	for i := 0; i < 50; i++ {
+		log.Error("error storm")
+		log.WithField("index", i).Warning("warning storm")
+	}
+	log.Info("info")
+	log.Fatal("fatal")
+	log.Sync()
+	log.Sync()
+
+	log = eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Std:             true,
+		SamplingInitial: 1,
+	})
+	log.Info("info")
+	log.Info("info")
+	log.Sync()
+}
+
+func TestLoggerStdRetention2(t *testing.T) {
+	defer os.RemoveAll("logger")
+	log := eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Path:       "logger/logger-index.log",
+		Link:       "logger/logger.log",
+		MaxSize:    1 << 10,
+		Rotate:     "hour",
+		MaxAge:     time.Hour,
+		MaxBackups: 3,
+		Compress:   true,
+	})
+	for i := 0; i < 200; i++ {
+		log.Info("hello retention", i)
+	}
+	log.Sync()
+	time.Sleep(200 * time.Millisecond)
+
+	// Link匹配切割文件规则时不会被压缩和删除
+	log = eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Path:       "logger/link/index.log",
+		Link:       "logger/link/current.log",
+		MaxSize:    1 << 10,
+		MaxBackups: 1,
+		Compress:   true,
+	})
+	for i := 0; i < 200; i++ {
+		log.Info("hello retention link", i)
+	}
+	log.Sync()
+	time.Sleep(200 * time.Millisecond)
+	stat, err := os.Lstat("logger/link/current.log")
+	if err != nil || stat.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("rotate link removed: %v", err)
+	}
+	if _, err := os.Stat("logger/link/current.log.gz"); !os.IsNotExist(err) {
+		t.Fatalf("rotate link compressed: %v", err)
+	}
+
+	lw, err := eudore.NewLoggerWriterRotateWithConfig(&eudore.LoggerWriterRotateConfig{
+		Path:         "logger/writer.log",
+		Rotate:       "day",
+		MaxTotalSize: 1 << 10,
+	})
+	t.Log(err)
+	lw.Write([]byte("hello writer\n"))
+	lw.Sync()
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestLoggerStdAsync2(t *testing.T) {
+	for _, policy := range []string{"block", "droplow", "dropall"} {
+		log := eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+			Path:        "async.log",
+			AsyncSize:   8,
+			AsyncPolicy: policy,
+		})
+		for i := 0; i < 100; i++ {
+			log.Debug("debug")
+			log.Info("info")
+			log.Error("error")
+		}
+		log.Sync()
+	}
+	os.Remove("async.log")
+
+	lw, _ := eudore.NewLoggerWriterAsync(eudore.NewLoggerWriterStd(), 0, "")
+	lw.Write([]byte("async write\n"))
+	t.Log(lw.Sync(), lw.Dropped())
+	t.Log(lw.Close(), lw.Close())
+	_, err := lw.Write([]byte("closed\n"))
+	t.Log(err)
+
+	_, err = eudore.NewLoggerWriterAsync(eudore.NewLoggerWriterStd(), 0, "drop")
+	if err == nil {
+		t.Fatal("async unknown policy not error")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("LoggerStd async unknown policy not panic")
+		}
+	}()
+	eudore.NewLoggerStd(&eudore.LoggerStdConfig{AsyncSize: 8, AsyncPolicy: "drop"})
+}
+
+// loggerWriterBlock 阻塞写入直到release关闭，用于填满异步缓冲。
+type loggerWriterBlock struct {
+	release chan struct{}
+	count   int32
+}
+
+func (w *loggerWriterBlock) Write(p []byte) (int, error) {
+	<-w.release
+	atomic.AddInt32(&w.count, 1)
+	return len(p), nil
+}
+
+func (w *loggerWriterBlock) Sync() error {
+	return nil
+}
+
+func TestLoggerStdAsyncDropped2(t *testing.T) {
+	// 缓冲为4，写入阻塞时缓冲条目不会释放。
+	for _, policy := range []string{"droplow", "dropall"} {
+		bw := &loggerWriterBlock{release: make(chan struct{})}
+		lw, err := eudore.NewLoggerWriterAsync(bw, 4, policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			lw.WriteLevel(eudore.LogInfo, []byte("info\n"))
+		}
+		lw.WriteLevel(eudore.LogError, []byte("error\n"))
+		lw.WriteLevel(eudore.LogError, []byte("error\n"))
+		close(bw.release)
+		lw.Sync()
+
+		dropped := lw.Dropped()
+		// droplow在缓冲使用3/4后丢弃info，error在缓冲满时丢弃
+		want := [5]uint64{0, 6, 0, 2, 0}
+		if policy == "droplow" {
+			want = [5]uint64{0, 7, 0, 1, 0}
+		}
+		if dropped != want || uint64(atomic.LoadInt32(&bw.count))+dropped[1]+dropped[3] != 12 {
+			t.Errorf("policy %s dropped %v, want %v, written %d", policy, dropped, want, bw.count)
+		}
+		lw.Close()
+	}
+}
+
+func TestLoggerSyslog2(t *testing.T) {
+	addr := "eudore-syslog.sock"
+	os.Remove(addr)
+	defer os.Remove(addr)
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	lw, err := eudore.NewLoggerWriterSyslog("unixgram", addr)
+	t.Log(err)
+	log := eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Writer:   lw,
+		Format:   "syslog",
+		FileLine: true,
+	})
+	log.WithField("key", "a]b").WithField("int", 1).Warning("hello syslog")
+	log.Info("no fields")
+
+	lw, err = eudore.NewLoggerWriterJournald(addr)
+	t.Log(err)
+	log = eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Writer:  lw,
+		Encoder: eudore.NewLoggerEncoderJournald("eudore"),
+	})
+	log.WithField("request-id", "123").WithField("1key", 1).Error("multi\nline")
+
+	buf := make([]byte, 4096)
+	for i := 0; i < 3; i++ {
+		n, _ := conn.Read(buf)
+		t.Logf("%q", buf[:n])
+	}
+
+	_, err = eudore.NewLoggerWriterSyslog("tcp", "127.0.0.1:1")
+	t.Log(err)
+}
+
+func TestLoggerLevels2(t *testing.T) {
+	levels := eudore.NewLoggerLevels()
+	log := eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Std:    true,
+		Levels: levels,
+	})
+	log.SetLevel(eudore.LogInfo)
+	dblog := log.WithField("logger", "db.mysql").WithFields(nil, nil)
+	dblog.Debug("hidden")
+	levels.Set("db.*", eudore.LogDebug)
+	dblog.Debug("show")
+	log.WithField("logger", "db.redis").Debug("show")
+	levels.Set("db.mysql", eudore.LogError)
+	dblog.Warning("hidden")
+	levels.Delete("db.mysql")
+	levels.Delete("db.*")
+	dblog.Debug("hidden")
+	dblog.Info("show")
+	t.Log(levels.List())
+	t.Log(levels.Match("db.mysql"))
+	log.Sync()
+}
+
+func TestLoggerLevelsMaxCells2(t *testing.T) {
+	defer func(n int) { eudore.DefaultLoggerLevelsMaxCells = n }(eudore.DefaultLoggerLevelsMaxCells)
+	eudore.DefaultLoggerLevelsMaxCells = 1
+	buf := &loggerWriterBuffer{}
+	levels := eudore.NewLoggerLevels()
+	log := eudore.NewLoggerStd(&eudore.LoggerStdConfig{
+		Writer: buf,
+		Levels: levels,
+	})
+	log.SetLevel(eudore.LogInfo)
+	loga := log.WithField("logger", "a").WithFields(nil, nil)
+	logb := log.WithField("logger", "b").WithFields(nil, nil)
+	levels.Set("a", eudore.LogDebug)
+	levels.Set("b", eudore.LogDebug)
+	levels.Set("c", eudore.LogDebug)
+	loga.Debug("a show")
+	// 超过保存数量的名称使用创建时的级别
+	logb.Debug("b hidden")
+	log.WithField("logger", "c").Debug("c show")
+	log.Sync()
+	if buf.Count("show") != 2 || buf.Count("hidden") != 0 {
+		t.Fatalf("logger levels max cells output: %s", buf.String())
+	}
+}
+
+type loggerWriterBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (w *loggerWriterBuffer) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	return w.Buffer.Write(p)
+}
+
+func (w *loggerWriterBuffer) Sync() error {
+	return nil
+}
+
+func (w *loggerWriterBuffer) Count(str string) int {
+	w.Lock()
+	defer w.Unlock()
+	return strings.Count(w.Buffer.String(), str)
+}
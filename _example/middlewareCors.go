@@ -31,6 +31,7 @@ func main() {
 	}))
 	app.AnyFunc("/*", eudore.HandlerEmpty)
 	app.AddHandler("404", "", eudore.HandlerRouter404)
+	app.AddHandler("Allow", "", eudore.HandlerRouterOptions)
 
 	client := httptest.NewClient(app)
 	client.NewRequest("OPTIONS", "/1").Do()
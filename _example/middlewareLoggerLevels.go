@@ -0,0 +1,53 @@
+package main
+
+/*
+eudore.LoggerLevels定义日志级别注册表，按Logger名称或路由前缀设置日志级别，可以在运行时修改。
+LoggerStd默认使用eudore.DefaultLoggerLevels，使用WithField("logger", name)设置Logger名称后使用注册表中名称对应的日志级别。
+
+middleware.NewLoggerLevelsFunc使用请求route参数匹配注册表设置请求的日志级别，并注入日志级别管理接口:
+	GET /eudore/debug/logger/levels
+	PUT /eudore/debug/logger/levels?name=/api/orders/*&level=debug
+	DELETE /eudore/debug/logger/levels?name=/api/orders/*
+
+管理接口可以修改全部日志级别，注入的路由器需要添加认证中间件。
+*/
+
+import (
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+	"github.com/eudore/eudore/middleware"
+)
+
+func main() {
+	app := eudore.NewApp()
+	app.SetLevel(eudore.LogInfo)
+	app.Options(app.Logger)
+
+	// 包级别的Logger
+	dblog := app.WithField("logger", "db").WithFields(nil, nil)
+	dblog.Debug("db debug hidden")
+	eudore.DefaultLoggerLevels.Set("db", eudore.LogDebug)
+	dblog.Debug("db debug show")
+
+	admin := app.Group("/eudore/debug")
+	admin.AddMiddleware(middleware.NewBasicAuthFunc(map[string]string{"root": "111"}))
+	app.AddMiddleware(middleware.NewLoggerLevelsFunc(nil, admin))
+	app.GetFunc("/api/orders/:id", func(ctx eudore.Context) {
+		ctx.Debug("get order", ctx.GetParam("id"))
+	})
+	app.GetFunc("/api/users/:id", func(ctx eudore.Context) {
+		ctx.Debug("get user", ctx.GetParam("id"))
+	})
+
+	client := httptest.NewClient(app)
+	client.NewRequest("PUT", "/eudore/debug/logger/levels?name=/api/orders/*&level=debug").Do().CheckStatus(401)
+	client.NewRequest("PUT", "/eudore/debug/logger/levels?name=/api/orders/*&level=debug").WithHeaderValue(eudore.HeaderAuthorization, "Basic cm9vdDoxMTE=").Do().CheckStatus(200)
+	client.NewRequest("GET", "/eudore/debug/logger/levels").WithHeaderValue(eudore.HeaderAuthorization, "Basic cm9vdDoxMTE=").Do().CheckStatus(200).OutBody()
+	client.NewRequest("GET", "/api/orders/1").Do()
+	client.NewRequest("GET", "/api/users/1").Do()
+	client.NewRequest("DELETE", "/eudore/debug/logger/levels?name=/api/orders/*").WithHeaderValue(eudore.HeaderAuthorization, "Basic cm9vdDoxMTE=").Do().CheckStatus(200)
+	client.NewRequest("GET", "/api/orders/2").Do()
+
+	app.CancelFunc()
+	app.Run()
+}
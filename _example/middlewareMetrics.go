@@ -0,0 +1,39 @@
+package main
+
+/*
+middleware.NewMetricsFunc按路由和方法记录请求数量、处理中请求数量、请求耗时和响应大小，
+并注入GET /metrics路由使用Prometheus文本格式输出指标，可以和pprof控制器注册在同一个路由组中。
+
+指标:
+	eudore_http_requests_total         请求数量，code标签为状态码分类1xx-5xx
+	eudore_http_requests_in_flight     处理中的请求数量
+	eudore_http_request_duration_seconds    请求耗时直方图
+	eudore_http_response_size_bytes    响应大小直方图
+*/
+
+import (
+	"time"
+
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+	"github.com/eudore/eudore/middleware"
+)
+
+func main() {
+	app := eudore.NewApp()
+	debug := app.Group("/eudore/debug")
+	debug.AddController(middleware.NewPprofController())
+	app.AddMiddleware(middleware.NewMetricsFunc(debug))
+	app.GetFunc("/api/:id", func(ctx eudore.Context) {
+		time.Sleep(10 * time.Millisecond)
+		ctx.WriteString("hello " + ctx.GetParam("id"))
+	})
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/api/1").Do().CheckStatus(200)
+	client.NewRequest("GET", "/api/2").Do().CheckStatus(200)
+	client.NewRequest("GET", "/eudore/debug/metrics").Do().CheckStatus(200).OutBody()
+
+	app.CancelFunc()
+	app.Run()
+}
@@ -0,0 +1,53 @@
+package main
+
+/*
+middleware.NewTracerFunc解析请求W3C traceparent和tracestate Header创建server Span，traceparent无效时创建新的Trace。
+eudore.GetTraceSpan(ctx.GetContext())获取请求的Span，请求日志自动添加trace-id和span-id属性。
+
+eudore.NewTraceClient创建的http.Client发送请求时，使用req.WithContext(ctx.GetContext())创建client Span并写入traceparent Header。
+Span结束时导出到eudore.TraceExporter接口，eudore.NewTraceExporterMemory()将Span保存在内存中用于测试。
+*/
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+	"github.com/eudore/eudore/middleware"
+)
+
+func main() {
+	exporter := eudore.NewTraceExporterMemory()
+	client := eudore.NewTraceClient(nil)
+
+	app := eudore.NewApp()
+	app.AddMiddleware(middleware.NewLoggerFunc(app, "route"))
+	app.AddMiddleware(middleware.NewTracerFunc(exporter))
+	app.GetFunc("/backend", func(ctx eudore.Context) {
+		ctx.Info("backend traceparent", ctx.GetHeader(eudore.HeaderTraceparent))
+	})
+	app.GetFunc("/api/:id", func(ctx eudore.Context) error {
+		span := eudore.GetTraceSpan(ctx.GetContext())
+		ctx.Info("trace", span.TraceID, span.SpanID)
+		req, _ := http.NewRequest("GET", "http://localhost:8088/backend", nil)
+		resp, err := client.Do(req.WithContext(ctx.GetContext()))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return ctx.WriteString(string(body))
+	})
+	app.Listen(":8088")
+
+	c := httptest.NewClient(app)
+	c.NewRequest("GET", "/api/1").WithHeaderValue(eudore.HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01").Do().CheckStatus(200)
+	c.NewRequest("GET", "/api/2").Do().CheckStatus(200)
+	for _, span := range exporter.Spans() {
+		app.Info(span.Kind, span.Name, span.Traceparent(), span.ParentID)
+	}
+
+	app.CancelFunc()
+	app.Run()
+}
@@ -15,6 +15,7 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
@@ -262,6 +263,34 @@ func BenchmarkMiddlewareRewriteWithZero(b *testing.B) {
 	}
 }
 
+func TestMiddlewareRouterOptions2(t *testing.T) {
+	app := eudore.NewApp(eudore.NewLoggerInit())
+	app.AddMiddleware("global", middleware.NewRouterFunc(map[string]interface{}{
+		"GET /api/*": func(ctx eudore.Context) {
+			ctx.SetHeader("X-Router", "api")
+		},
+	}))
+	app.AddHandler("OPTIONS", "/*", func(ctx eudore.Context) {
+		ctx.WriteString("app options")
+	})
+	app.AnyFunc("/*", eudore.HandlerEmpty)
+
+	// Router中间件不会自动响应OPTIONS请求
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/api/1", nil))
+	if w.Code != 200 || w.Body.String() != "app options" || w.Header().Get(eudore.HeaderAllow) != "" {
+		t.Errorf("router middleware options response %d %q allow: %s", w.Code, w.Body.String(), w.Header().Get(eudore.HeaderAllow))
+	}
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/api/1", nil))
+	if w.Header().Get("X-Router") != "api" {
+		t.Error("router middleware not match GET /api/1")
+	}
+
+	app.CancelFunc()
+	app.Run()
+}
+
 func BenchmarkMiddlewareRewriteWithRouter(b *testing.B) {
 	routerdata := map[string]interface{}{
 		"/js/*0":                     newRewriteFunc("/public/js/$0"),
@@ -313,3 +342,138 @@ func newRewriteFunc(path string) eudore.HandlerFunc {
 		ctx.Request().URL.Path = buffer.String()
 	}
 }
+
+func TestMiddlewareTracer2(t *testing.T) {
+	exporter := eudore.NewTraceExporterMemory()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(eudore.HeaderTraceparent)))
+	}))
+	defer backend.Close()
+	client := eudore.NewTraceClient(nil)
+
+	app := eudore.NewApp()
+	app.AddMiddleware(middleware.NewTracerFunc(exporter))
+	app.GetFunc("/trace/:id", func(ctx eudore.Context) {
+		span := eudore.GetTraceSpan(ctx.GetContext())
+		if span == nil {
+			ctx.Fatal("request span not found")
+			return
+		}
+		ctx.Info("trace", span.TraceID, span.SpanID)
+		req, _ := http.NewRequest("GET", backend.URL, nil)
+		resp, err := client.Do(req.WithContext(ctx.GetContext()))
+		if err != nil {
+			ctx.Fatal(err)
+			return
+		}
+		defer resp.Body.Close()
+		body := new(bytes.Buffer)
+		body.ReadFrom(resp.Body)
+		ctx.Info("backend traceparent", body.String())
+	})
+
+	traceparents := []string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"",
+	}
+	for _, traceparent := range traceparents {
+		w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/trace/1", nil)
+		r.Header.Set(eudore.HeaderTraceparent, traceparent)
+		r.Header.Set(eudore.HeaderTracestate, "vendor=value")
+		app.ServeHTTP(w, r)
+		if w.Code != 200 {
+			t.Errorf("trace request %s status %d", traceparent, w.Code)
+		}
+		t.Log(traceparent, w.Header().Get(eudore.HeaderXTraceID))
+	}
+	for _, span := range exporter.Spans() {
+		t.Log(span.Kind, span.Name, span.TraceID, span.SpanID, span.ParentID, span.TraceState, span.Attributes)
+	}
+	exporter.Reset()
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestMiddlewareMetrics2(t *testing.T) {
+	app := eudore.NewApp(eudore.NewLoggerInit())
+	app.AddMiddleware(middleware.NewMetricsFunc(app.Group("/eudore/debug")))
+	app.GetFunc("/api/:id", func(ctx eudore.Context) {
+		ctx.WriteString("hello " + ctx.GetParam("id"))
+	})
+	app.PostFunc("/api/:id", func(ctx eudore.Context) {
+		ctx.WriteHeader(500)
+	})
+
+	for _, method := range []string{"GET", "GET", "POST"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/api/1", nil))
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/eudore/debug/metrics", nil))
+	t.Log(w.Body.String())
+
+	// 全局中间件记录非标准方法使用OTHER标签
+	app.AddMiddleware("global", middleware.NewMetricsFunc(app.Group("/eudore/global")))
+	for _, method := range []string{"GET", "FOO", "BAR"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/api/1", nil))
+	}
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/eudore/global/metrics", nil))
+	body := w.Body.String()
+	if strings.Contains(body, `method="FOO"`) || strings.Contains(body, `method="BAR"`) || !strings.Contains(body, `eudore_http_requests_total{method="OTHER",route="",code="4xx"} 2`) {
+		t.Error("metrics not map non-standard methods to OTHER:", body)
+	}
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestMiddlewareGzipServerSentEvents2(t *testing.T) {
+	app := eudore.NewApp(eudore.NewLoggerInit())
+	app.AddMiddleware(middleware.NewGzipFunc(5))
+	app.GetFunc("/events", func(ctx eudore.Context) error {
+		return eudore.WriteServerSentEvent(ctx, &eudore.ServerSentEvent{ID: "1", Data: "hello"})
+	})
+	app.GetFunc("/text", func(ctx eudore.Context) {
+		ctx.WriteString("hello")
+	})
+
+	for _, path := range []string{"/events", "/text"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set(eudore.HeaderAcceptEncoding, "gzip")
+		app.ServeHTTP(w, r)
+		t.Logf("%s %s %q", path, w.Header().Get(eudore.HeaderContentEncoding), w.Body.String())
+		if path == "/events" && (w.Header().Get(eudore.HeaderContentEncoding) != "" || w.Body.String() != "id: 1\ndata: hello\n\n") {
+			t.Error("gzip compress text/event-stream response")
+		}
+	}
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func BenchmarkMiddlewareMetrics(b *testing.B) {
+	app := eudore.NewApp(eudore.NewLoggerInit())
+	app.AddMiddleware(middleware.NewMetricsFunc(nil))
+	app.AnyFunc("/*", eudore.HandlerEmpty)
+	w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		app.ServeHTTP(w, r)
+	}
+}
+
+func BenchmarkMiddlewareMetricsWithZero(b *testing.B) {
+	app := eudore.NewApp(eudore.NewLoggerInit())
+	app.AnyFunc("/*", eudore.HandlerEmpty)
+	w, r := httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		app.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+/*
+RouterCoreStd自动处理HEAD和OPTIONS请求。
+
+路由存在GET方法未注册HEAD方法时，HEAD请求使用GET方法的处理函数，响应body会被丢弃，Header保持一致。
+
+路由未注册OPTIONS方法时，OPTIONS请求自动响应204和Allow Header，Allow包含自动处理的HEAD和OPTIONS方法，405响应的Allow Header相同。
+
+自动响应OPTIONS的处理可以注册Allow方法替换，在注册中间件后注册Allow方法可以使用中间件，例如Cors中间件处理跨域预检请求。
+*/
+
+import (
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+	"github.com/eudore/eudore/middleware"
+)
+
+func main() {
+	app := eudore.NewApp()
+	app.AddMiddleware("global", middleware.NewCorsFunc([]string{"example.com"}, map[string]string{
+		"Access-Control-Allow-Methods": "GET, POST",
+	}))
+	app.AddHandler("Allow", "", eudore.HandlerRouterOptions)
+	app.GetFunc("/users", func(ctx eudore.Context) {
+		ctx.WriteString("users")
+	})
+	app.PostFunc("/users", eudore.HandlerEmpty)
+
+	client := httptest.NewClient(app)
+	client.NewRequest("HEAD", "/users").Do().CheckStatus(200).CheckBodyString("")
+	client.NewRequest("OPTIONS", "/users").Do().CheckStatus(204).CheckHeader(eudore.HeaderAllow, "GET, POST, HEAD, OPTIONS")
+	client.NewRequest("OPTIONS", "/users").WithHeaderValue("Origin", "http://example.com").Do().CheckStatus(204).OutHeader()
+	client.NewRequest("PUT", "/users").Do().CheckStatus(405).CheckHeader(eudore.HeaderAllow, "GET, POST, HEAD, OPTIONS")
+
+	app.CancelFunc()
+	app.Run()
+}
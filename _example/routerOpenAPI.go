@@ -0,0 +1,55 @@
+package main
+
+/*
+eudore.NewRouterCoreOpenAPI创建一个生成OpenAPI 3.0文档的路由器核心，注册路由时添加文档。
+
+路径参数和通配符参数的校验规则转换成参数schema，isnum、min、max规则的参数类型为integer，正则规则转换成pattern；
+RPC处理函数func(Context, Request) (Response, error)的请求和响应类型转换成schema，结构体字段名称使用json tag，
+validate tag的nozero、min、max、len、regexp规则转换成required、minimum、maximum、minLength/maxLength、pattern；
+GET和HEAD方法的请求类型转换成query参数，其他方法的请求类型转换成json请求body。
+
+路由参数name或action作为operationId，controllergroup作为tags，openapi=off的路由不会生成文档。
+
+只有包装后注册的路由会生成文档，需要忽略大小写重定向时使用NewRouterCoreOpenAPI(NewRouterCoreRedirect(nil), doc)。
+
+OpenAPI.HandleHTTP方法返回json文档，NewOpenAPIUIHandler函数创建Swagger UI页面，静态资源地址需要固定版本或者自托管。
+*/
+
+import (
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+)
+
+type userRequest struct {
+	Name string `json:"name" form:"name" validate:"nozero"`
+	Age  int    `json:"age" form:"age" validate:"min:0,max:150"`
+}
+
+type userResponse struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func main() {
+	doc := eudore.NewOpenAPI("eudore", "v1")
+	doc.Servers = []eudore.OpenAPIServer{{URL: "http://localhost:8088"}}
+	app := eudore.NewApp(eudore.NewRouterStd(eudore.NewRouterCoreOpenAPI(nil, doc)))
+	app.GetFunc("/openapi.json openapi=off", doc.HandleHTTP)
+	app.GetFunc("/swagger openapi=off", eudore.NewOpenAPIUIHandler("/openapi.json", "https://unpkg.com/swagger-ui-dist@5.17.14"))
+
+	api := app.Group("/api/v1")
+	api.GetFunc("/users", func(ctx eudore.Context, req *userRequest) ([]userResponse, error) {
+		return []userResponse{{ID: 1, Name: req.Name}}, nil
+	})
+	api.PostFunc("/users name=CreateUser", func(ctx eudore.Context, req *userRequest) (*userResponse, error) {
+		return &userResponse{ID: 2, Name: req.Name}, nil
+	})
+	api.GetFunc("/users/:id|isnum name=GetUser", eudore.HandlerEmpty)
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/openapi.json").Do().CheckStatus(200).OutBody()
+	client.NewRequest("GET", "/swagger").Do().CheckStatus(200)
+
+	app.CancelFunc()
+	app.Run()
+}
@@ -0,0 +1,37 @@
+package main
+
+/*
+RouterStd支持在一个路径段内混合常量和多个参数，例如'/:year|^\d{4}$-:month|^\d{2}$.html'和'/file/{name}.{ext}'。
+
+参数可以使用':name|check'或'{name|check}'格式，':'格式的参数名称和非正则校验规则遇到'.'结束，其他分隔符需要使用'{}'格式；
+'^'开头的正则校验规则直到'$'结束，规则内可以使用'{}'。
+
+多参数路径段和变量校验具有相同优先级，两个参数之间必须存在常量，参数之间的常量使用最左匹配，参数校验失败时回溯下一个常量位置，参数值不能为空。
+*/
+
+import (
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+)
+
+func main() {
+	app := eudore.NewApp()
+	app.GetFunc("/:year|^\\d{4}$-:month|^\\d{2}$.html", func(ctx eudore.Context) {
+		ctx.WriteString("archive " + ctx.GetParam("year") + " " + ctx.GetParam("month"))
+	})
+	app.GetFunc("/file/{name}.{ext|^(js|css)$} name=file", func(ctx eudore.Context) {
+		ctx.WriteString("file " + ctx.GetParam("name") + " " + ctx.GetParam("ext"))
+	})
+	app.GetFunc("/file/:name", func(ctx eudore.Context) {
+		ctx.WriteString("name " + ctx.GetParam("name"))
+	})
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/2020-01.html").Do().CheckStatus(200).CheckBodyString("archive 2020 01")
+	client.NewRequest("GET", "/file/app.min.js").Do().CheckStatus(200).CheckBodyString("file app.min js")
+	client.NewRequest("GET", "/file/app.html").Do().CheckStatus(200).CheckBodyString("name app.html")
+	app.Info(app.URL("file", "name", "index", "ext", "css"))
+
+	app.CancelFunc()
+	app.Run()
+}
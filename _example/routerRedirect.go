@@ -0,0 +1,37 @@
+package main
+
+/*
+eudore.NewRouterCoreRedirect创建一个自动重定向的路由器核心，请求未匹配时修正路径并重定向。
+
+依次尝试清理后的路径(path.Clean、重复斜杠)、切换结尾斜杠的路径和忽略大小写的路径，
+修正后的路径可以匹配当前方法时，GET和HEAD请求响应301，其他方法响应308，请求query会保留；
+请求匹配成功时不会有额外开销。
+
+注册中间件后需要注册一次Redirect方法，重定向才会经过中间件处理，例如访问日志和Recover。
+
+如果需要运行时动态修改路由，使用NewRouterCoreLock包装Redirect核心。
+*/
+
+import (
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+	"github.com/eudore/eudore/middleware"
+)
+
+func main() {
+	app := eudore.NewApp(eudore.NewRouterStd(eudore.NewRouterCoreRedirect(nil)))
+	app.AddMiddleware(middleware.NewLoggerFunc(app, "route"))
+	app.AddHandler("Redirect", "", eudore.HandlerRouterRedirect)
+	app.GetFunc("/users", eudore.HandlerEmpty)
+	app.PostFunc("/api/", eudore.HandlerEmpty)
+	app.GetFunc("/Docs/:name", eudore.HandlerEmpty)
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/users/?page=2").Do().CheckStatus(301).CheckHeader(eudore.HeaderLocation, "/users?page=2")
+	client.NewRequest("GET", "//users").Do().CheckStatus(301).CheckHeader(eudore.HeaderLocation, "/users")
+	client.NewRequest("GET", "/docs/eudore/").Do().CheckStatus(301).CheckHeader(eudore.HeaderLocation, "/Docs/eudore")
+	client.NewRequest("POST", "/api").Do().CheckStatus(308).CheckHeader(eudore.HeaderLocation, "/api/")
+
+	app.CancelFunc()
+	app.Run()
+}
@@ -0,0 +1,49 @@
+package main
+
+/*
+路由参数name定义路由名称，Router.URL方法使用路由名称和参数生成url。
+
+参数为多个键值对，依次填充路由中的':id'参数、'*path'通配符参数，并使用路由注册的校验函数检查参数值，
+其他键值对作为query参数，url.Values参数会合并到query参数。
+
+Router.URL可以作为模板函数使用，避免在模板和重定向中硬编码路径。
+*/
+
+import (
+	"html/template"
+	"os"
+
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+)
+
+func main() {
+	app := eudore.NewApp()
+	api := app.Group("/api/v1")
+	api.GetFunc("/user/:id|isnum name=user", func(ctx eudore.Context) {
+		ctx.WriteString("user " + ctx.GetParam("id"))
+	})
+	app.GetFunc("/static/*path name=static", eudore.HandlerEmpty)
+	app.GetFunc("/login", func(ctx eudore.Context) {
+		url, err := app.URL("user", "id", 1, "tab", "info")
+		if err != nil {
+			ctx.Fatal(err)
+			return
+		}
+		ctx.Redirect(302, url)
+	})
+
+	url, err := app.URL("static", "path", "js/app.js", "v", "1.0")
+	app.Info(url, err)
+	_, err = app.URL("user", "id", "eudore")
+	app.Info(err)
+
+	temp := template.Must(template.New("").Funcs(template.FuncMap{"url": app.URL}).Parse(`<a href="{{url "user" "id" 2}}">user</a>` + "\n"))
+	temp.Execute(os.Stdout, nil)
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/login").Do().CheckStatus(302).CheckHeader(eudore.HeaderLocation, "/api/v1/user/1?tab=info")
+
+	app.CancelFunc()
+	app.Run()
+}
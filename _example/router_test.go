@@ -1,6 +1,8 @@
 package eudore_test
 
 import (
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/eudore/eudore"
@@ -96,3 +98,323 @@ func TestRouter2(t *testing.T) {
 	app.CancelFunc()
 	app.Run()
 }
+
+func TestRouterStdURL2(t *testing.T) {
+	app := eudore.NewApp()
+	api := app.Group("/api/v1")
+	api.GetFunc("/user/:id|isnum name=user", func(ctx eudore.Context) {
+		ctx.WriteString(ctx.GetParam("name"))
+	})
+	api.GetFunc("/user/:id/:name name=user.name", eudore.HandlerEmpty)
+	app.GetFunc("/static/*path|{^js/\\S+$} name=static", eudore.HandlerEmpty)
+	app.GetFunc("/files/* name=files", eudore.HandlerEmpty)
+	app.GetFunc("/redirect", func(ctx eudore.Context) {
+		url, err := app.URL("user", "id", 3, "tab", "info")
+		if err != nil {
+			ctx.Fatal(err)
+			return
+		}
+		ctx.Redirect(302, url)
+	})
+
+	for _, args := range [][]interface{}{
+		{"user", "id", 1},
+		{"user", "id", "2", "page", 1, "tag", []string{"a", "b"}},
+		{"user.name", "id", 1, "name", "eudore name/中文"},
+		{"static", "path", "js/app.js"},
+		{"files"},
+		{"files", "*", "a b/c.txt", url.Values{"v": {"1"}}},
+		// 错误
+		{"user", "id", "abc"},
+		{"user"},
+		{"user", "id"},
+		{"static", "path", "css/app.css"},
+		{"none"},
+	} {
+		url, err := app.URL(args[0].(string), args[1:]...)
+		t.Log(url, err)
+	}
+
+	// 重复名称注册其他路径返回错误，保留第一次注册的路径
+	if api.AddHandler("POST", "/user/:id|isnum name=user", eudore.HandlerEmpty) != nil {
+		t.Error("register same name and path error")
+	}
+	if app.AddHandler("GET", "/users/:id name=user", eudore.HandlerEmpty) == nil {
+		t.Error("register duplicate name not error")
+	}
+	if url, _ := app.URL("user", "id", 1); url != "/api/v1/user/1" {
+		t.Error("duplicate name overwrite route:", url)
+	}
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/redirect").Do().CheckStatus(302).CheckHeader(eudore.HeaderLocation, "/api/v1/user/3?tab=info")
+	client.NewRequest("GET", "/api/v1/user/3").Do().CheckStatus(200).CheckBodyString("user")
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestRouterStdPattern2(t *testing.T) {
+	app := eudore.NewApp()
+	app.GetFunc("/:year|^\\d{4}$-:month|^\\d{2}$.html", func(ctx eudore.Context) {
+		ctx.WriteString(ctx.GetParam("year") + " " + ctx.GetParam("month"))
+	})
+	app.GetFunc("/file/{name}.{ext} name=file", func(ctx eudore.Context) {
+		ctx.WriteString(ctx.GetParam("name") + " " + ctx.GetParam("ext"))
+	})
+	app.GetFunc("/file/:name|^\\w+$.:ext|{^(js|css)$}/info", func(ctx eudore.Context) {
+		ctx.WriteString(ctx.GetParam("name") + " " + ctx.GetParam("ext"))
+	})
+	app.GetFunc("/file/:name", func(ctx eudore.Context) {
+		ctx.WriteString(ctx.GetParam("name"))
+	})
+	app.GetFunc("/:id", func(ctx eudore.Context) {
+		ctx.WriteString(ctx.GetParam("id"))
+	})
+	app.GetFunc("/2020-01.html", func(ctx eudore.Context) {
+		ctx.WriteString("const")
+	})
+	app.GetFunc("/{year}{month}", eudore.HandlerEmpty)
+	app.GetFunc("/archive/{year}-{month}", func(ctx eudore.Context) {
+		ctx.WriteString(ctx.GetParam("year") + " " + ctx.GetParam("month"))
+	})
+	t.Log(app.URL("file", "name", "app", "ext", "js"))
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/2020-01.html").Do().CheckStatus(200).CheckBodyString("const")
+	client.NewRequest("GET", "/2021-02.html").Do().CheckStatus(200).CheckBodyString("2021 02")
+	client.NewRequest("GET", "/20210-02.html").Do().CheckStatus(200).CheckBodyString("20210-02.html")
+	client.NewRequest("GET", "/file/app.min.js").Do().CheckStatus(200).CheckBodyString("app min.js")
+	client.NewRequest("GET", "/file/app.js/info").Do().CheckStatus(200).CheckBodyString("app js")
+	client.NewRequest("GET", "/file/app.min.js/info").Do().CheckStatus(404)
+	client.NewRequest("GET", "/file/app").Do().CheckStatus(200).CheckBodyString("app")
+	client.NewRequest("GET", "/file/.js").Do().CheckStatus(200).CheckBodyString(".js")
+	client.NewRequest("GET", "/archive/2021-02").Do().CheckStatus(200).CheckBodyString("2021 02")
+	client.NewRequest("GET", "/archive/2021").Do().CheckStatus(404)
+
+	// ':'参数名称直到'/'或'|'，不会在'-'和'.'处拆分成多参数
+	app.GetFunc("/user/:user-id", func(ctx eudore.Context) {
+		ctx.WriteString(ctx.GetParam("user-id"))
+	})
+	app.GetFunc("/static/:file.json", func(ctx eudore.Context) {
+		ctx.WriteString(ctx.GetParam("file.json"))
+	})
+	for path, body := range map[string]string{"/user/eudore-1": "eudore-1", "/static/app.min": "app.min"} {
+		resp := client.NewRequest("GET", path).Do()
+		if resp.Code != 200 || resp.Body.String() != body {
+			t.Errorf("GET %s response %d %q, want %q", path, resp.Code, resp.Body.String(), body)
+		}
+	}
+
+	app.AddHandler("GET", "/file/{name}.{ext} register=off", eudore.HandlerEmpty)
+	client.NewRequest("GET", "/file/app.js").Do().CheckStatus(200).CheckBodyString("app.js")
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestRouterStdDeleteParam2(t *testing.T) {
+	app := eudore.NewApp()
+	app.GetFunc("/node/:id|isnum", func(ctx eudore.Context) {
+		ctx.WriteString("id " + ctx.GetParam("id"))
+	})
+	app.GetFunc("/node/:name", func(ctx eudore.Context) {
+		ctx.WriteString("name " + ctx.GetParam("name"))
+	})
+	app.GetFunc("/node/:name/info", eudore.HandlerEmpty)
+
+	client := httptest.NewClient(app)
+	check := func(path string, status int, body string) {
+		resp := client.NewRequest("GET", path).Do()
+		if resp.Code != status || (body != "" && resp.Body.String() != body) {
+			t.Errorf("GET %s response %d %q, want %d %q", path, resp.Code, resp.Body.String(), status, body)
+		}
+	}
+
+	// 删除Param节点不会删除ParamValid节点
+	app.AddHandler("GET", "/node/:name/info register=off", eudore.HandlerEmpty)
+	app.AddHandler("GET", "/node/:name register=off", eudore.HandlerEmpty)
+	check("/node/1", 200, "id 1")
+	check("/node/abc", 404, "")
+	check("/node/abc/info", 404, "")
+
+	// 删除ParamValid节点不会删除Param节点
+	app.GetFunc("/node/:name", func(ctx eudore.Context) {
+		ctx.WriteString("name " + ctx.GetParam("name"))
+	})
+	app.AddHandler("GET", "/node/:id|isnum register=off", eudore.HandlerEmpty)
+	check("/node/1", 200, "name 1")
+	check("/node/abc", 200, "name abc")
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestRouterCoreRedirect2(t *testing.T) {
+	app := eudore.NewApp(eudore.NewRouterStd(eudore.NewRouterCoreRedirect(nil)))
+	app.GetFunc("/users", eudore.HandlerEmpty)
+	app.AnyFunc("/api/", eudore.HandlerEmpty)
+	app.GetFunc("/Docs/:name/Info", eudore.HandlerEmpty)
+	app.GetFunc("/static/*", eudore.HandlerEmpty)
+
+	client := httptest.NewClient(app)
+	client.NewRequest("GET", "/users").Do().CheckStatus(200)
+	client.NewRequest("GET", "/users/").Do().CheckStatus(301).CheckHeader(eudore.HeaderLocation, "/users")
+	client.NewRequest("GET", "/users/?page=2").Do().CheckStatus(301).CheckHeader(eudore.HeaderLocation, "/users?page=2")
+	client.NewRequest("GET", "/a/..//USERS").Do().CheckStatus(301).CheckHeader(eudore.HeaderLocation, "/users")
+	client.NewRequest("GET", "/docs/eudore/info/").Do().CheckStatus(301).CheckHeader(eudore.HeaderLocation, "/Docs/eudore/Info")
+	client.NewRequest("POST", "/api").Do().CheckStatus(308).CheckHeader(eudore.HeaderLocation, "/api/")
+	client.NewRequest("POST", "/users/").Do().CheckStatus(404)
+	client.NewRequest("GET", "/none").Do().CheckStatus(404)
+
+	// 修正路径的每一段转义，不能重定向到其他host或者修改query
+	app.GetFunc("/:name", eudore.HandlerEmpty)
+	app.GetFunc("/users/:name", eudore.HandlerEmpty)
+	for path, location := range map[string]string{
+		"/a/..//%5Cevil.com": "/%5Cevil.com",
+		"/%5Cevil.com/":      "/%5Cevil.com",
+		"/users/a%3Fb/":      "/users/a%3Fb",
+		"/users/a%3Fb/?c=d":  "/users/a%3Fb?c=d",
+	} {
+		resp := client.NewRequest("GET", path).Do()
+		if resp.Code != 301 || resp.Header().Get(eudore.HeaderLocation) != location {
+			t.Errorf("redirect %s response %d %s, want %s", path, resp.Code, resp.Header().Get(eudore.HeaderLocation), location)
+		}
+	}
+
+	// HEAD请求和GET相同响应301，注册Redirect方法后重定向经过中间件处理
+	client.NewRequest("HEAD", "/users/").Do().CheckStatus(301)
+	app.AddMiddleware(func(ctx eudore.Context) {
+		ctx.SetHeader("X-Middleware", "redirect")
+	})
+	app.AddHandler("Redirect", "", eudore.HandlerRouterRedirect)
+	for _, method := range []string{"GET", "HEAD"} {
+		resp := client.NewRequest(method, "/users/").Do()
+		if resp.Code != 301 || resp.Header().Get(eudore.HeaderLocation) != "/users" || resp.Header().Get("X-Middleware") != "redirect" {
+			t.Errorf("redirect %s response %d %s %v", method, resp.Code, resp.Header().Get(eudore.HeaderLocation), resp.Header())
+		}
+	}
+
+	// 空路径不修正，返回404
+	params := new(eudore.Params)
+	eudore.NewRouterCoreRedirect(nil).Match("GET", "", params)
+	if params.Get(eudore.ParamRoute) != "404" {
+		t.Error("redirect empty path route:", params.Get(eudore.ParamRoute))
+	}
+
+	app.CancelFunc()
+	app.Run()
+}
+
+func TestRouterStdHeadOptions2(t *testing.T) {
+	app := eudore.NewApp()
+	app.AddMiddleware("global", middleware.NewCorsFunc([]string{"example.com"}, map[string]string{
+		"Access-Control-Allow-Methods": "GET, POST",
+	}))
+	app.AddHandler("Allow", "", eudore.HandlerRouterOptions)
+	app.GetFunc("/users", func(ctx eudore.Context) {
+		ctx.SetHeader("X-Users", "get")
+		ctx.WriteString("users")
+	})
+	app.PostFunc("/users", eudore.HandlerEmpty)
+	app.GetFunc("/head", eudore.HandlerEmpty)
+	app.HeadFunc("/head", func(ctx eudore.Context) {
+		ctx.SetHeader("X-Head", "head")
+	})
+	app.AddHandler("OPTIONS", "/options", func(ctx eudore.Context) {
+		ctx.WriteHeader(200)
+	})
+	app.PutFunc("/put", eudore.HandlerEmpty)
+
+	client := httptest.NewClient(app)
+	client.NewRequest("HEAD", "/users").Do().CheckStatus(200).CheckHeader("X-Users", "get").CheckBodyString("")
+	client.NewRequest("HEAD", "/head").Do().CheckStatus(200).CheckHeader("X-Head", "head")
+	client.NewRequest("HEAD", "/put").Do().CheckStatus(405).CheckHeader(eudore.HeaderAllow, "PUT, OPTIONS")
+	client.NewRequest("OPTIONS", "/users").Do().CheckStatus(204).CheckHeader(eudore.HeaderAllow, "GET, POST, HEAD, OPTIONS")
+	client.NewRequest("OPTIONS", "/users").WithHeaderValue("Origin", "http://example.com").Do().CheckStatus(204).
+		CheckHeader("Access-Control-Allow-Methods", "GET, POST")
+	client.NewRequest("OPTIONS", "/options").Do().CheckStatus(200)
+	client.NewRequest("DELETE", "/users").Do().CheckStatus(405).CheckHeader(eudore.HeaderAllow, "GET, POST, HEAD, OPTIONS")
+	client.NewRequest("OPTIONS", "/none").Do().CheckStatus(404)
+
+	app.CancelFunc()
+	app.Run()
+}
+
+type openapiUserRequest struct {
+	Name   string            `json:"name" form:"name" validate:"nozero,len:<32"`
+	Age    int               `json:"age" form:"age" validate:"min:0,max:150"`
+	Email  string            `json:"email,omitempty" form:"-" validate:"regexp:^\\S+@\\S+$"`
+	Labels map[string]string `json:"labels,omitempty" form:"-"`
+}
+
+type openapiUser struct {
+	ID      int64          `json:"id"`
+	Name    string         `json:"name"`
+	Friends []*openapiUser `json:"friends,omitempty"`
+}
+
+type openapiCodeRequest struct {
+	Code  string `json:"code" validate:"min:1,max:10"`
+	Count int    `json:"count" validate:"min:1,max:10"`
+}
+
+func TestRouterOpenAPI2(t *testing.T) {
+	doc := eudore.NewOpenAPI("eudore", "v1")
+	app := eudore.NewApp(eudore.NewRouterStd(eudore.NewRouterCoreOpenAPI(nil, doc)))
+	app.GetFunc("/openapi.json openapi=off", doc.HandleHTTP)
+	app.GetFunc("/swagger/* openapi=off", eudore.NewOpenAPIUIHandler("/openapi.json", "/static/swagger-ui/"))
+	app.GetFunc("/users", func(ctx eudore.Context, req *openapiUserRequest) ([]openapiUser, error) {
+		return nil, nil
+	})
+	app.PostFunc("/users name=CreateUser", func(ctx eudore.Context, req *openapiUserRequest) (*openapiUser, error) {
+		return &openapiUser{ID: 1, Name: req.Name}, nil
+	})
+	app.GetFunc("/users/:id|isnum", func(ctx eudore.Context, req map[string]interface{}) (interface{}, error) {
+		return req, nil
+	})
+	app.GetFunc("/files/{name}.{ext|^(js|css)$}", eudore.HandlerEmpty)
+	app.AnyFunc("/static/*path", eudore.HandlerEmpty)
+	app.AddHandler("404", "", eudore.HandlerRouter404)
+	app.GetFunc("/delete", eudore.HandlerEmpty)
+	app.AddHandler("GET", "/delete register=off", eudore.HandlerEmpty)
+
+	client := httptest.NewClient(app)
+	client.NewRequest("POST", "/users").WithHeaderValue(eudore.HeaderAccept, eudore.MimeApplicationJSON).
+		WithBodyJSON(map[string]interface{}{"name": "eudore"}).Do().CheckStatus(200).CheckBodyContainString("eudore")
+	client.NewRequest("GET", "/openapi.json").Do().CheckStatus(200).CheckBodyContainString("#/components/schemas/openapiUser", "/files/{name}.{ext}")
+	client.NewRequest("GET", "/swagger/index.html").Do().CheckStatus(200).CheckBodyContainString("/openapi.json", "/static/swagger-ui/swagger-ui-bundle.js")
+
+	// minimum和maximum只用于数值类型
+	app.PutFunc("/codes", func(ctx eudore.Context, req *openapiCodeRequest) (interface{}, error) {
+		return req, nil
+	})
+	schema := doc.Components.Schemas["openapiCodeRequest"]
+	if schema == nil || schema.Properties["code"].Minimum != nil || schema.Properties["code"].Maximum != nil ||
+		schema.Properties["count"].Minimum == nil || *schema.Properties["count"].Maximum != 10 {
+		t.Error("openapi schema min/max invalid:", schema)
+	}
+	// Any方法使用RouterAnyMethod
+	for _, method := range eudore.RouterAnyMethod {
+		if doc.Paths["/static/{path}"][strings.ToLower(method)] == nil {
+			t.Errorf("openapi any route not has method %s", method)
+		}
+	}
+
+	// 包装前core中已经注册的路由添加到文档
+	core := eudore.NewRouterCoreRedirect(nil)
+	core.HandleFunc("GET", "/before/:id name=GetBefore", eudore.HandlerFuncs{eudore.HandlerEmpty})
+	core.HandleFunc("ANY", "/before/*", eudore.HandlerFuncs{eudore.HandlerEmpty})
+	core.HandleFunc("POST", "/before/*", eudore.HandlerFuncs{eudore.HandlerEmpty})
+	doc = eudore.NewOpenAPI("eudore", "v1")
+	eudore.NewRouterCoreOpenAPI(eudore.NewRouterCoreLock(core), doc)
+	if op := doc.Paths["/before/{id}"]["get"]; op == nil || op.OperationID != "GetBefore" {
+		t.Errorf("openapi registered route /before/{id}: %v", doc.Paths["/before/{id}"])
+	}
+	if ops := doc.Paths["/before/{*}"]; len(ops) != len(eudore.RouterAnyMethod) || ops["post"] == nil {
+		t.Errorf("openapi registered route /before/*: %v", ops)
+	}
+
+	app.CancelFunc()
+	app.Run()
+}
@@ -0,0 +1,62 @@
+package main
+
+/*
+eudore.DefaultWebsocketUpgrader.Upgrade检查握手请求并劫持连接，返回*eudore.WebsocketConn，不依赖第三方库。
+eudore.NewWebsocketUpgrader()可以创建自定义的升级对象，设置读取限制、压缩、子协议和Origin检查。
+
+WebsocketConn.ReadMessage读取完整消息，自动合并分片、解压permessage-deflate数据、回复ping和关闭帧；
+写入方法可以并发调用，Close方法发送关闭帧后关闭连接。
+
+httptest使用WithWebsocketConn方法创建websocket客户端，可以在进程内测试websocket路由。
+*/
+
+import (
+	"github.com/eudore/eudore"
+	"github.com/eudore/eudore/component/httptest"
+)
+
+func main() {
+	app := eudore.NewApp()
+	app.GetFunc("/ws", func(ctx eudore.Context) {
+		conn, err := eudore.DefaultWebsocketUpgrader.Upgrade(ctx)
+		if err != nil {
+			return
+		}
+
+		// 取出请求上下文的Logger，否则Context在sync.Pool再次分配后可能竞态冲突。
+		log := ctx.Logger()
+		go func() {
+			defer conn.Close()
+			for {
+				messageType, data, err := conn.ReadMessage()
+				if err != nil {
+					log.Info("websocket closed:", err)
+					return
+				}
+				log.Info("websocket read:", string(data))
+				if conn.WriteMessage(messageType, data) != nil {
+					return
+				}
+			}
+		}()
+	})
+	app.Listen(":8088")
+
+	client := httptest.NewClient(app)
+	done := make(chan struct{})
+	client.NewRequest("GET", "/ws").WithWebsocketConn(func(conn *eudore.WebsocketConn) {
+		defer close(done)
+		conn.WriteText("hello eudore websocket")
+		_, data, err := conn.ReadMessage()
+		app.Info("client read:", string(data), err)
+		// 关闭握手
+		conn.WriteClose(eudore.WebsocketCloseNormalClosure, "bye")
+		_, _, err = conn.ReadMessage()
+		app.Info("client close:", err)
+		conn.Close()
+	}).Do().CheckStatus(101)
+	<-done
+
+	app.CancelFunc()
+	app.Run()
+}
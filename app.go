@@ -19,6 +19,7 @@ package eudore // import "github.com/eudore/eudore"
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"sync"
@@ -41,6 +42,7 @@ App 组合主要功能接口，本身仅实现简单的基本方法。
 	启动端口监听
 	阻塞运行服务
 	获取配置值并转换类型
+	生命周期钩子
 */
 type App struct {
 	context.Context    `alias:"context"`
@@ -57,6 +59,24 @@ type App struct {
 	ContextPool        sync.Pool `alias:"contextpool"`
 	CancelError        error     `alias:"cancelerror"`
 	cancelMutex        sync.Mutex
+	hookMutex          sync.Mutex
+	hooks              []AppHook
+	hookStarted        []AppHook
+	hookRunning        bool
+	serveGroup         sync.WaitGroup
+}
+
+// AppHook 定义App生命周期钩子，Start在App启动时执行，Stop在App关闭时执行。
+//
+// Depends 指定依赖的钩子名称，启动时先执行依赖钩子的Start，关闭时后执行依赖钩子的Stop。
+//
+// Timeout 指定Start和Stop每次执行的超时时间，为0时使用DefaultAppHookTimeout。
+type AppHook struct {
+	Name    string                      `alias:"name" json:"name"`
+	Depends []string                    `alias:"depends" json:"depends"`
+	Timeout time.Duration               `alias:"timeout" json:"timeout"`
+	Start   func(context.Context) error `alias:"start" json:"-"`
+	Stop    func(context.Context) error `alias:"stop" json:"-"`
 }
 
 // NewApp function creates an App object.
@@ -85,10 +105,11 @@ func NewApp(options ...interface{}) *App {
 }
 
 // Options method loads the app component. When the option type is context.Context, Logger, Config, Server, Router, Binder, Renderer, Validater, the app property will be set,
-// and the print property of the component will be set. If the type is error, it will be the app end error Return to the Run method.
+// and the print property of the component will be set. If the type is AppHook, it will be added as a lifecycle hook.
+// If the type is error, it will be the app end error Return to the Run method.
 //
 // Options 方法加载app组件，option类型为context.Context、Logger、Config、Server、Router、Binder、Renderer、Validater时会设置app属性，
-// 并设置组件的print属性，如果类型为error将作为app结束错误返回给Run方法。
+// 并设置组件的print属性，如果类型为AppHook会添加生命周期钩子，如果类型为error将作为app结束错误返回给Run方法。
 func (app *App) Options(options ...interface{}) {
 	for _, i := range options {
 		if i == nil {
@@ -123,6 +144,8 @@ func (app *App) Options(options ...interface{}) {
 			app.Renderer = val
 		case Validater:
 			app.Validater = val
+		case AppHook:
+			app.AddHook(val)
 		case error:
 			app.Error("eudore app cannel context on handler error: " + val.Error())
 			app.cancelMutex.Lock()
@@ -141,7 +164,14 @@ func (app *App) Options(options ...interface{}) {
 
 // Run method starts the App and blocks and waits for the end of the App, and periodically calls app.Logger.Sync() to output the log.
 //
+// If Start has not been called by Serve, the hooks will be started first.
+// When the App ends, first close all Server listeners, then wait for Serve to return, then execute the Stop of hooks in reverse dependency order,
+// and finally return the aggregated error through CancelError.
+//
 // Run 方法启动App阻塞等待App结束，并周期调用app.Logger.Sync()将日志输出。
+//
+// 如果未调用Start或Serve方法会先执行启动钩子；App结束时先关闭Server全部监听并等待Serve返回，
+// 然后按依赖逆序执行钩子Stop，最后将全部错误合并保存到CancelError并返回。
 func (app *App) Run() error {
 	ticker := time.NewTicker(time.Millisecond * 80)
 	defer ticker.Stop()
@@ -151,15 +181,160 @@ func (app *App) Run() error {
 		}
 	}()
 
+	app.Options(app.Start())
 	<-app.Done()
-	time.Sleep(time.Millisecond * 100)
-	app.Shutdown(context.Background())
-	time.Sleep(time.Millisecond * 100)
+
+	errs := &muliterror{}
+	app.cancelMutex.Lock()
+	errs.HandleError(app.CancelError)
+	app.cancelMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultAppShutdownTimeout)
+	errs.HandleError(app.Shutdown(ctx))
+	cancel()
+	app.serveGroup.Wait()
+	errs.HandleError(app.stopHooks())
+	app.Logger.Sync()
+
 	app.cancelMutex.Lock()
 	defer app.cancelMutex.Unlock()
+	app.CancelError = errs.GetError()
 	return app.CancelError
 }
 
+// AddHook method adds App lifecycle hooks, the hook name must be unique.
+//
+// AddHook 方法添加App生命周期钩子，钩子名称必须唯一。
+func (app *App) AddHook(hooks ...AppHook) error {
+	app.hookMutex.Lock()
+	defer app.hookMutex.Unlock()
+	for _, hook := range hooks {
+		if hook.Name == "" || app.getHook(hook.Name) != nil {
+			err := fmt.Errorf(ErrFormatAppHookInvalidName, hook.Name)
+			app.Error(err)
+			return err
+		}
+		if hook.Timeout <= 0 {
+			hook.Timeout = DefaultAppHookTimeout
+		}
+		app.hooks = append(app.hooks, hook)
+	}
+	return nil
+}
+
+// Start method executes Start of all hooks in dependency order, only executes once.
+// Serve and Run will call Start, so the hooks are started before any request is served.
+//
+// If a hook fails to start, the hooks after it will not be executed, and the started hooks will be stopped when the App ends.
+//
+// Start 方法按依赖顺序执行全部钩子的Start，仅执行一次。
+// Serve和Run方法会调用Start，因此钩子会在处理请求之前启动。
+//
+// 如果一个钩子启动失败，后续钩子不会执行，已启动的钩子会在App结束时关闭。
+func (app *App) Start() error {
+	app.hookMutex.Lock()
+	defer app.hookMutex.Unlock()
+	if app.hookRunning {
+		return nil
+	}
+	app.hookRunning = true
+	hooks, err := app.sortHooks()
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if hook.Start != nil {
+			app.Debugf("eudore app start hook %s", hook.Name)
+			err = runAppHook(hook.Start, hook.Timeout)
+			if err != nil {
+				return fmt.Errorf(ErrFormatAppHookStart, hook.Name, err)
+			}
+		}
+		app.hookStarted = append(app.hookStarted, hook)
+	}
+	return nil
+}
+
+// stopHooks 方法按启动逆序执行已启动钩子的Stop，返回合并的错误。
+func (app *App) stopHooks() error {
+	app.hookMutex.Lock()
+	defer app.hookMutex.Unlock()
+	errs := &muliterror{}
+	for i := len(app.hookStarted) - 1; i > -1; i-- {
+		hook := app.hookStarted[i]
+		if hook.Stop != nil {
+			app.Debugf("eudore app stop hook %s", hook.Name)
+			err := runAppHook(hook.Stop, hook.Timeout)
+			if err != nil {
+				err = fmt.Errorf(ErrFormatAppHookStop, hook.Name, err)
+				app.Error(err)
+				errs.HandleError(err)
+			}
+		}
+	}
+	app.hookStarted = nil
+	return errs.GetError()
+}
+
+func (app *App) getHook(name string) *AppHook {
+	for i := range app.hooks {
+		if app.hooks[i].Name == name {
+			return &app.hooks[i]
+		}
+	}
+	return nil
+}
+
+// sortHooks 方法按依赖关系拓扑排序钩子，相同层级保持添加顺序，依赖不存在或循环依赖返回错误。
+func (app *App) sortHooks() ([]AppHook, error) {
+	hooks := make([]AppHook, 0, len(app.hooks))
+	states := make(map[string]int, len(app.hooks))
+	var visit func(*AppHook) error
+	visit = func(hook *AppHook) error {
+		switch states[hook.Name] {
+		case 1:
+			return fmt.Errorf(ErrFormatAppHookDependCycle, hook.Name)
+		case 2:
+			return nil
+		}
+		states[hook.Name] = 1
+		for _, name := range hook.Depends {
+			depend := app.getHook(name)
+			if depend == nil {
+				return fmt.Errorf(ErrFormatAppHookDependNotFound, hook.Name, name)
+			}
+			if err := visit(depend); err != nil {
+				return err
+			}
+		}
+		states[hook.Name] = 2
+		hooks = append(hooks, *hook)
+		return nil
+	}
+	for i := range app.hooks {
+		if err := visit(&app.hooks[i]); err != nil {
+			return nil, err
+		}
+	}
+	return hooks, nil
+}
+
+// runAppHook 函数执行一个钩子函数，超时后不等待钩子函数返回。
+func runAppHook(fn func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ch := make(chan error, 1)
+	go func() {
+		ch <- fn(ctx)
+	}()
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // serveContext Implement the request context function.
 // serveContext 实现处理请求上下文函数。
 func (app *App) serveContext(ctx Context) {
@@ -247,9 +422,23 @@ func (app *App) ListenTLS(addr, key, cert string) error {
 
 // Serve method starts a Server monitor non-blocking, and uses the app to process the monitor and return an error.
 //
-// Serve 方法非阻塞启动一个Server监听，并使用app处理监听结束返回错误。
+// Before serving, the Start of hooks will be executed, if the hook fails to start or the App has ended, the listener will be closed.
+//
+// Serve 方法非阻塞启动一个Server监听，并使用app处理监听结束返回错误，Run方法会等待全部监听结束。
+//
+// 开始处理请求前会先执行钩子Start，如果钩子启动失败或App已经结束会关闭监听。
 func (app *App) Serve(ln net.Listener) {
+	app.Options(app.Start())
+	if app.Err() != nil {
+		ln.Close()
+		return
+	}
+	app.serveGroup.Add(1)
 	go func() {
-		app.Options(app.Server.Serve(ln))
+		defer app.serveGroup.Done()
+		err := app.Server.Serve(ln)
+		if err != http.ErrServerClosed {
+			app.Options(err)
+		}
 	}()
 }
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"net/http"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 /*
@@ -22,23 +25,71 @@ Renderer对象更加Accept Header选择数据对象序列化的方法。
 // Binder 定义Bind函数处理请求。
 type Binder func(Context, io.Reader, interface{}) error
 
-// BindDefault 函数实现默认Binder。
+// BindDefault 函数实现默认Binder，get和head方法使用BindURL，其他方法使用DefaultBinderRegistry根据Content-Type选择Binder。
 func BindDefault(ctx Context, r io.Reader, i interface{}) error {
 	if ctx.Method() == MethodGet || ctx.Method() == MethodHead {
 		return BindURL(ctx, r, i)
 	}
-	switch strings.SplitN(ctx.GetHeader(HeaderContentType), ";", 2)[0] {
-	case MimeApplicationJSON:
-		return BindJSON(ctx, r, i)
-	case MimeApplicationForm:
-		return BindURL(ctx, r, i)
-	case MimeMultipartForm:
-		return BindForm(ctx, r, i)
-	case MimeTextXML, MimeApplicationXML:
-		return BindXML(ctx, r, i)
-	default:
+	return DefaultBinderRegistry.Bind(ctx, r, i)
+}
+
+// newBinderRegistryDefault 函数创建默认的Binder注册表，支持json、form、multipart和xml。
+func newBinderRegistryDefault() *BinderRegistry {
+	reg := NewBinderRegistry()
+	reg.Register(MimeApplicationJSON, BindJSON)
+	reg.Register(MimeApplicationForm, BindURL)
+	reg.Register(MimeMultipartForm, BindForm)
+	reg.Register(MimeTextXML, BindXML)
+	reg.Register(MimeApplicationXML, BindXML)
+	reg.Register(MimeApplicationMsgpack, BindMsgpack)
+	reg.Register("application/x-msgpack", BindMsgpack)
+	reg.Register(MimeApplicationProtobuf, BindProtobuf)
+	reg.Register("application/x-protobuf", BindProtobuf)
+	reg.Register(MimeApplicationCBOR, BindCBOR)
+	return reg
+}
+
+// BinderRegistry 定义按照Content-Type选择Binder的注册表。
+//
+// 匹配顺序为完整的mime类型、"type/*"、"*/*"，Content-Type的参数会被忽略。
+type BinderRegistry struct {
+	sync.RWMutex
+	binders map[string]Binder
+}
+
+// NewBinderRegistry 函数创建一个Binder注册表。
+func NewBinderRegistry() *BinderRegistry {
+	return &BinderRegistry{binders: make(map[string]Binder)}
+}
+
+// Register 方法注册一个mime类型对应的Binder，fn为空删除mime类型。
+func (reg *BinderRegistry) Register(mime string, fn Binder) {
+	mime = strings.ToLower(mime)
+	reg.Lock()
+	if fn == nil {
+		delete(reg.binders, mime)
+	} else {
+		reg.binders[mime] = fn
+	}
+	reg.Unlock()
+}
+
+// Bind 方法实现Binder，如果Content-Type没有注册的Binder返回错误。
+func (reg *BinderRegistry) Bind(ctx Context, r io.Reader, i interface{}) error {
+	mime, _ := parseMediaType(ctx.GetHeader(HeaderContentType))
+	reg.RLock()
+	fn, ok := reg.binders[mime]
+	if !ok {
+		fn, ok = reg.binders[mime[:strings.IndexByte(mime+"/", '/')]+"/*"]
+	}
+	if !ok {
+		fn, ok = reg.binders["*/*"]
+	}
+	reg.RUnlock()
+	if !ok {
 		return fmt.Errorf(ErrFormatBindDefaultNotSupportContentType, ctx.GetHeader(HeaderContentType))
 	}
+	return fn(ctx, r, i)
 }
 
 // BindURL 函数使用url参数实现bind。
@@ -96,22 +147,354 @@ func NewBinderValidater(fn Binder) Binder {
 	}
 }
 
+// BindStreamError 定义流式绑定的错误，超出限制Status为413，解析或校验失败Status为422。
+type BindStreamError struct {
+	Status  int    `json:"status"`
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+// Error 方法实现error接口。
+func (err *BindStreamError) Error() string {
+	return fmt.Sprintf("bind stream item %d error: %s", err.Index, err.Message)
+}
+
+// bindStreamReader 定义限制读取长度的io.Reader，超出长度的数据不会返回。
+type bindStreamReader struct {
+	io.Reader
+	size int64
+	max  int64
+}
+
+func (r *bindStreamReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.size += int64(n)
+	if r.max > 0 && r.size > r.max {
+		return n - int(r.size-r.max), ErrBindStreamTooLarge
+	}
+	return n, err
+}
+
+// BindStream 函数使用DefaultBindStreamMaxBytes和DefaultBindStreamMaxItems限制执行BindStreamWithLimit。
+func BindStream(ctx Context, fn interface{}) error {
+	return BindStreamWithLimit(ctx, fn, DefaultBindStreamMaxBytes, DefaultBindStreamMaxItems)
+}
+
+// BindStreamWithLimit 函数逐个解析json数组或ndjson格式的请求body，每个元素调用一次fn，不会读取全部body到内存。
+//
+// fn类型为func(*T) error或func(T) error，每个元素会使用app.Validater校验，fn返回的error直接返回。
+//
+// maxbytes和maxitems限制body长度和元素数量，为0不限制，超出限制返回Status为413的*BindStreamError，
+// 解析和校验失败返回Status为422的*BindStreamError，返回*BindStreamError时会写入对应的响应状态码。
+func BindStreamWithLimit(ctx Context, fn interface{}, maxbytes int64, maxitems int) error {
+	err := bindStream(ctx, fn, maxbytes, maxitems)
+	if e, ok := err.(*BindStreamError); ok {
+		ctx.WriteHeader(e.Status)
+	}
+	return err
+}
+
+func bindStream(ctx Context, fn interface{}, maxbytes int64, maxitems int) error {
+	fValue := reflect.ValueOf(fn)
+	fType := fValue.Type()
+	if fType.Kind() != reflect.Func || fType.NumIn() != 1 || fType.NumOut() != 1 || fType.Out(0) != typeError {
+		return ErrBindStreamFuncInvalid
+	}
+	iType := fType.In(0)
+	isptr := iType.Kind() == reflect.Ptr
+	if isptr {
+		iType = iType.Elem()
+	}
+
+	decoder := json.NewDecoder(&bindStreamReader{Reader: ctx, max: maxbytes})
+	isarray := false
+	if bindStreamPeek(decoder) == '[' {
+		decoder.Token()
+		isarray = true
+	}
+	for index := 0; ; index++ {
+		if isarray && !decoder.More() {
+			_, err := decoder.Token()
+			return newBindStreamError(index, err)
+		}
+		if maxitems > 0 && index >= maxitems {
+			// ndjson没有更多数据时不是错误
+			if !isarray && bindStreamPeek(decoder) == 0 {
+				return nil
+			}
+			return &BindStreamError{Status: 413, Index: index, Message: ErrBindStreamTooManyItems.Error(), Err: ErrBindStreamTooManyItems}
+		}
+
+		item := reflect.New(iType)
+		err := decoder.Decode(item.Interface())
+		if err == io.EOF && !isarray {
+			return nil
+		}
+		if err != nil {
+			return newBindStreamError(index, err)
+		}
+		err = ctx.Validate(item.Interface())
+		if err != nil {
+			return &BindStreamError{Status: 422, Index: index, Message: err.Error(), Err: err}
+		}
+		if !isptr {
+			item = item.Elem()
+		}
+		out := fValue.Call([]reflect.Value{item})
+		if !out[0].IsNil() {
+			return out[0].Interface().(error)
+		}
+	}
+}
+
+// bindStreamPeek 函数返回json.Decoder下一个非空白字符，没有数据或下一个字符是']'、'}'返回0。
+func bindStreamPeek(decoder *json.Decoder) byte {
+	// More方法会读取数据并跳过空白字符
+	if !decoder.More() {
+		return 0
+	}
+	var b [1]byte
+	buf := decoder.Buffered()
+	for {
+		n, _ := buf.Read(b[:])
+		if n == 0 {
+			return 0
+		}
+		if b[0] != ' ' && b[0] != '\t' && b[0] != '\r' && b[0] != '\n' {
+			return b[0]
+		}
+	}
+}
+
+func newBindStreamError(index int, err error) error {
+	switch err {
+	case nil:
+		return nil
+	case ErrBindStreamTooLarge:
+		return &BindStreamError{Status: 413, Index: index, Message: err.Error(), Err: err}
+	case io.EOF:
+		err = io.ErrUnexpectedEOF
+	}
+	return &BindStreamError{Status: 422, Index: index, Message: err.Error(), Err: err}
+}
+
 // Renderer 接口定义根据请求接受的数据类型来序列化数据。
 type Renderer func(Context, interface{}) error
 
-// RenderDefault 函数是默认Render，更加Accent Header选择Json、Xml、Text三种Render。
+// RenderDefault 函数是默认Render，使用DefaultRendererRegistry根据Accept Header选择Renderer。
 func RenderDefault(ctx Context, data interface{}) error {
-	for _, accept := range strings.Split(ctx.GetHeader(HeaderAccept), ",") {
-		switch strings.TrimSpace(accept) {
-		case MimeApplicationJSON:
-			return RenderJSON(ctx, data)
-		case MimeApplicationXML, MimeTextXML:
-			return RenderXML(ctx, data)
-		case MimeTextPlain, MimeText:
-			return RenderText(ctx, data)
+	return DefaultRendererRegistry.Render(ctx, data)
+}
+
+// newRendererRegistryDefault 函数创建默认的Renderer注册表，没有可接受的类型时使用RenderText。
+func newRendererRegistryDefault() *RendererRegistry {
+	reg := NewRendererRegistry()
+	reg.Register(MimeTextPlain, RenderText)
+	reg.Register(MimeApplicationJSON, RenderJSON)
+	reg.Register(MimeApplicationXML, RenderXML)
+	reg.Register(MimeTextXML, RenderXML)
+	reg.Register(MimeApplicationMsgpack, RenderMsgpack)
+	reg.Register("application/x-msgpack", RenderMsgpack)
+	reg.Register(MimeApplicationProtobuf, RenderProtobuf)
+	reg.Register("application/x-protobuf", RenderProtobuf)
+	reg.Register(MimeApplicationCBOR, RenderCBOR)
+	return reg
+}
+
+// RendererRegistry 定义按照Accept Header内容协商选择Renderer的注册表。
+//
+// Accept支持q权重和"type/*"、"*/*"通配符，mime类型使用最具体的Accept范围的权重，
+// 权重相同时选择匹配范围在Accept中靠前的类型，再按照注册顺序选择，Accept为空时使用第一个注册的Renderer。
+type RendererRegistry struct {
+	sync.RWMutex
+	mimes   []string
+	renders map[string]Renderer
+	// NotAcceptable 为true时没有可接受的Renderer返回406，否则使用第一个注册的Renderer。
+	NotAcceptable bool
+}
+
+// acceptRange 定义一个Accept媒体范围。
+type acceptRange struct {
+	mime        string
+	quality     float64
+	specificity int
+}
+
+// NewRendererRegistry 函数创建一个Renderer注册表。
+func NewRendererRegistry() *RendererRegistry {
+	return &RendererRegistry{renders: make(map[string]Renderer)}
+}
+
+// Register 方法注册一个mime类型对应的Renderer，注册顺序作为权重相同时的优先顺序，fn为空删除mime类型。
+func (reg *RendererRegistry) Register(mime string, fn Renderer) {
+	mime = strings.ToLower(mime)
+	reg.Lock()
+	defer reg.Unlock()
+	_, ok := reg.renders[mime]
+	switch {
+	case fn != nil:
+		if !ok {
+			reg.mimes = append(reg.mimes, mime)
+		}
+		reg.renders[mime] = fn
+	case ok:
+		delete(reg.renders, mime)
+		for i := range reg.mimes {
+			if reg.mimes[i] == mime {
+				reg.mimes = append(reg.mimes[:i], reg.mimes[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Negotiate 方法根据Accept Header返回选择的mime类型和Renderer，没有可接受的类型返回空。
+func (reg *RendererRegistry) Negotiate(accept string) (string, Renderer) {
+	reg.RLock()
+	defer reg.RUnlock()
+	mimes := reg.negotiate(accept)
+	if len(mimes) == 0 {
+		return "", nil
+	}
+	return mimes[0], reg.renders[mimes[0]]
+}
+
+// negotiate 方法按照优先顺序返回Accept Header全部可接受的mime类型，需要持有读锁。
+func (reg *RendererRegistry) negotiate(accept string) []string {
+	if len(reg.mimes) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(accept) == "" {
+		return reg.mimes[:1]
+	}
+
+	ranges := parseAccept(accept)
+	var mimes []string
+	var qualities []float64
+	var indexs []int
+	for _, m := range reg.mimes {
+		q, i := getAcceptQuality(ranges, m)
+		if q == 0 {
+			continue
+		}
+		// 插入排序，权重降序，权重相同时Accept索引升序，再按照注册顺序
+		pos := len(mimes)
+		for pos > 0 && (q > qualities[pos-1] || (q == qualities[pos-1] && i < indexs[pos-1])) {
+			pos--
+		}
+		mimes = append(mimes[:pos], append([]string{m}, mimes[pos:]...)...)
+		qualities = append(qualities[:pos], append([]float64{q}, qualities[pos:]...)...)
+		indexs = append(indexs[:pos], append([]int{i}, indexs[pos:]...)...)
+	}
+	return mimes
+}
+
+// Render 方法实现Renderer，注册多个Renderer时设置Vary: Accept Header。
+//
+// Renderer返回错误并且没有写入响应body时，使用下一个可接受的Renderer，
+// 例如Accept偏好xml但是数据无法序列化为xml时使用json。
+//
+// 如果没有可接受的Renderer，NotAcceptable为true时返回406和ErrRendererNotAcceptable，否则使用第一个注册的Renderer。
+func (reg *RendererRegistry) Render(ctx Context, data interface{}) error {
+	reg.RLock()
+	var fns []Renderer
+	for _, mime := range reg.negotiate(ctx.GetHeader(HeaderAccept)) {
+		fns = append(fns, reg.renders[mime])
+	}
+	if len(fns) == 0 && !reg.NotAcceptable && len(reg.mimes) > 0 {
+		fns = append(fns, reg.renders[reg.mimes[0]])
+	}
+	vary := len(reg.mimes) > 1
+	reg.RUnlock()
+
+	header := ctx.Response().Header()
+	if vary && !headerContainsToken(header[HeaderVary], HeaderAccept) {
+		header.Add(HeaderVary, HeaderAccept)
+	}
+	if len(fns) == 0 {
+		header.Set(HeaderContentType, MimeTextPlainCharsetUtf8)
+		ctx.WriteHeader(StatusNotAcceptable)
+		ctx.WriteString(http.StatusText(StatusNotAcceptable))
+		return ErrRendererNotAcceptable
+	}
+
+	contenttype := header[HeaderContentType]
+	for i, fn := range fns {
+		err := fn(ctx, data)
+		if err == nil || i == len(fns)-1 || ctx.Response().Size() > 0 {
+			return err
+		}
+		// 恢复Renderer设置的Content-Type
+		if contenttype == nil {
+			header.Del(HeaderContentType)
+		} else {
+			header[HeaderContentType] = contenttype
+		}
+	}
+	return nil
+}
+
+// parseAccept 函数解析Accept Header的全部媒体范围。
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mime, params := parseMediaType(part)
+		if mime == "" {
+			continue
+		}
+		// 具体程度: "*/*"为0，"type/*"为2，"type/subtype"为4，带有q以外的参数加1
+		r := acceptRange{mime: mime, quality: 1, specificity: 4}
+		switch {
+		case mime == "*/*":
+			r.specificity = 0
+		case strings.HasSuffix(mime, "/*"):
+			r.specificity = 2
+		}
+		for _, param := range params {
+			k, v := split2byte(param, '=')
+			if strings.TrimSpace(k) == "q" {
+				q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err == nil && q >= 0 && q <= 1 {
+					r.quality = q
+				}
+			} else if r.specificity%2 == 0 {
+				r.specificity++
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// getAcceptQuality 函数返回mime类型匹配的最具体媒体范围的权重和索引，没有匹配返回0。
+func getAcceptQuality(ranges []acceptRange, mime string) (float64, int) {
+	quality, specificity, index := 0.0, -1, 0
+	for i, r := range ranges {
+		if r.specificity > specificity && (r.mime == mime || r.mime == "*/*" ||
+			(strings.HasSuffix(r.mime, "/*") && strings.HasPrefix(mime, r.mime[:len(r.mime)-1]))) {
+			quality, specificity, index = r.quality, r.specificity, i
+		}
+	}
+	return quality, index
+}
+
+// parseMediaType 函数解析媒体类型，返回小写的mime类型和参数。
+func parseMediaType(s string) (string, []string) {
+	parts := strings.Split(s, ";")
+	return strings.ToLower(strings.TrimSpace(parts[0])), parts[1:]
+}
+
+// headerContainsToken 函数检查逗号分隔的Header值是否包含token。
+func headerContainsToken(vals []string, token string) bool {
+	for _, val := range vals {
+		for _, v := range strings.Split(val, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
 		}
 	}
-	return RenderText(ctx, data)
+	return false
 }
 
 // RenderText 函数Render Text，使用fmt.Fprint函数写入。
@@ -0,0 +1,1232 @@
+package eudore
+
+// bindrenderercodec 实现msgpack、cbor和protobuf格式的Binder和Renderer，msgpack和cbor使用反射编解码。
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codecMaxDepth 定义msgpack和cbor解码的最大嵌套层数。
+const codecMaxDepth = 1000
+
+var (
+	typeTime            = reflect.TypeOf((*time.Time)(nil)).Elem()
+	typeBytes           = reflect.TypeOf((*[]byte)(nil)).Elem()
+	typeTextMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	typeTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	codecFieldsCache    sync.Map
+)
+
+// BindMsgpack 函数使用msgpack格式body实现bind，结构体属性名称使用msgpack tag，其次使用json tag。
+func BindMsgpack(_ Context, r io.Reader, i interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return UnmarshalMsgpack(data, i)
+}
+
+// RenderMsgpack 函数使用msgpack格式Render。
+func RenderMsgpack(ctx Context, data interface{}) error {
+	body, err := MarshalMsgpack(data)
+	if err != nil {
+		return err
+	}
+	header := ctx.Response().Header()
+	if val := header.Get(HeaderContentType); len(val) == 0 {
+		header.Add(HeaderContentType, MimeApplicationMsgpack)
+	}
+	_, err = ctx.Write(body)
+	return err
+}
+
+// BindCBOR 函数使用cbor格式body实现bind，结构体属性名称使用cbor tag，其次使用json tag。
+func BindCBOR(_ Context, r io.Reader, i interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return UnmarshalCBOR(data, i)
+}
+
+// RenderCBOR 函数使用cbor格式Render。
+func RenderCBOR(ctx Context, data interface{}) error {
+	body, err := MarshalCBOR(data)
+	if err != nil {
+		return err
+	}
+	header := ctx.Response().Header()
+	if val := header.Get(HeaderContentType); len(val) == 0 {
+		header.Add(HeaderContentType, MimeApplicationCBOR)
+	}
+	_, err = ctx.Write(body)
+	return err
+}
+
+// BindProtobuf 函数使用protobuf格式body实现bind，对象需要实现proto.Message接口，使用DefaultProtobufUnmarshal解码。
+func BindProtobuf(_ Context, r io.Reader, i interface{}) error {
+	if _, ok := i.(interface{ ProtoMessage() }); !ok {
+		return fmt.Errorf(ErrFormatProtobufNotMessage, i)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return DefaultProtobufUnmarshal(data, i)
+}
+
+// RenderProtobuf 函数使用protobuf格式Render，数据需要实现proto.Message接口，使用DefaultProtobufMarshal编码。
+func RenderProtobuf(ctx Context, data interface{}) error {
+	if _, ok := data.(interface{ ProtoMessage() }); !ok {
+		return fmt.Errorf(ErrFormatProtobufNotMessage, data)
+	}
+	body, err := DefaultProtobufMarshal(data)
+	if err != nil {
+		return err
+	}
+	header := ctx.Response().Header()
+	if val := header.Get(HeaderContentType); len(val) == 0 {
+		header.Add(HeaderContentType, MimeApplicationProtobuf)
+	}
+	_, err = ctx.Write(body)
+	return err
+}
+
+// protobufMarshal 函数使用消息的Marshal方法编码，gogo/protobuf和旧版本protoc-gen-go生成的消息实现该方法。
+//
+// google.golang.org/protobuf生成的消息没有Marshal方法，返回错误提示设置DefaultProtobufMarshal。
+func protobufMarshal(i interface{}) ([]byte, error) {
+	m, ok := i.(interface{ Marshal() ([]byte, error) })
+	if !ok {
+		return nil, fmt.Errorf(ErrFormatProtobufMethodNotFound, i, "Marshal")
+	}
+	return m.Marshal()
+}
+
+// protobufUnmarshal 函数使用消息的Unmarshal方法解码，没有Unmarshal方法返回错误提示设置DefaultProtobufUnmarshal。
+func protobufUnmarshal(data []byte, i interface{}) error {
+	m, ok := i.(interface{ Unmarshal([]byte) error })
+	if !ok {
+		return fmt.Errorf(ErrFormatProtobufMethodNotFound, i, "Unmarshal")
+	}
+	return m.Unmarshal(data)
+}
+
+// MarshalMsgpack 函数将对象编码成msgpack格式，time.Time使用timestamp扩展类型，实现encoding.TextMarshaler的对象编码成字符串。
+func MarshalMsgpack(i interface{}) ([]byte, error) {
+	enc := &msgpackEncoder{}
+	err := codecEncode(enc, reflect.ValueOf(i), "msgpack")
+	return enc.buf, err
+}
+
+// UnmarshalMsgpack 函数解码msgpack数据到对象，对象必须是指针。
+func UnmarshalMsgpack(data []byte, i interface{}) error {
+	dec := &msgpackDecoder{data: data}
+	val, err := dec.decode(0)
+	if err == nil && dec.pos != len(data) {
+		err = ErrMsgpackDataInvalid
+	}
+	if err != nil {
+		return err
+	}
+	return codecUnmarshal(val, i, "msgpack")
+}
+
+// MarshalCBOR 函数将对象编码成cbor格式，time.Time使用tag 0编码成RFC3339字符串，实现encoding.TextMarshaler的对象编码成字符串。
+func MarshalCBOR(i interface{}) ([]byte, error) {
+	enc := &cborEncoder{}
+	err := codecEncode(enc, reflect.ValueOf(i), "cbor")
+	return enc.buf, err
+}
+
+// UnmarshalCBOR 函数解码cbor数据到对象，对象必须是指针，支持不定长度的字符串、数组和map。
+func UnmarshalCBOR(data []byte, i interface{}) error {
+	dec := &cborDecoder{data: data}
+	val, err := dec.decode(0)
+	if err == nil && dec.pos != len(data) {
+		err = ErrCBORDataInvalid
+	}
+	if err != nil {
+		return err
+	}
+	return codecUnmarshal(val, i, "cbor")
+}
+
+// codecEncoder 定义msgpack和cbor的基础类型编码。
+type codecEncoder interface {
+	writeNil()
+	writeBool(bool)
+	writeInt(int64)
+	writeUint(uint64)
+	writeFloat(float64, int)
+	writeString(string)
+	writeBytes([]byte)
+	writeArray(int)
+	writeMap(int)
+	writeTime(time.Time)
+}
+
+// codecPair 定义解码的map键值对，保留非字符串类型的键。
+type codecPair struct {
+	key interface{}
+	val interface{}
+}
+
+// codecField 定义结构体属性的编码名称。
+type codecField struct {
+	name      string
+	index     []int
+	omitempty bool
+}
+
+type codecFieldsKey struct {
+	iType reflect.Type
+	tag   string
+}
+
+// getCodecFields 函数返回结构体属性的编码名称，名称依次使用tag、json tag和属性名称，匿名结构体属性会展开。
+func getCodecFields(iType reflect.Type, tag string) []codecField {
+	key := codecFieldsKey{iType, tag}
+	fields, ok := codecFieldsCache.Load(key)
+	if ok {
+		return fields.([]codecField)
+	}
+	var result []codecField
+	for i := 0; i < iType.NumField(); i++ {
+		field := iType.Field(i)
+		name := field.Tag.Get(tag)
+		if name == "" {
+			name = field.Tag.Get("json")
+		}
+		name, opts := split2byte(name, ',')
+		if name == "-" && opts == "" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for _, f := range getCodecFields(field.Type, tag) {
+				f.index = append([]int{i}, f.index...)
+				result = append(result, f)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		result = append(result, codecField{name: name, index: []int{i}, omitempty: strings.Contains(opts, "omitempty")})
+	}
+	codecFieldsCache.Store(key, result)
+	return result
+}
+
+// codecEncode 函数使用反射编码对象。
+func codecEncode(enc codecEncoder, iValue reflect.Value, tag string) error {
+	if !iValue.IsValid() {
+		enc.writeNil()
+		return nil
+	}
+	iType := iValue.Type()
+	if iType == typeTime {
+		enc.writeTime(iValue.Interface().(time.Time))
+		return nil
+	}
+	if iType.Implements(typeTextMarshaler) && (iValue.Kind() != reflect.Ptr || !iValue.IsNil()) {
+		text, err := iValue.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		enc.writeString(string(text))
+		return nil
+	}
+
+	switch iValue.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if iValue.IsNil() {
+			enc.writeNil()
+			return nil
+		}
+		return codecEncode(enc, iValue.Elem(), tag)
+	case reflect.Bool:
+		enc.writeBool(iValue.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		enc.writeInt(iValue.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		enc.writeUint(iValue.Uint())
+	case reflect.Float32:
+		enc.writeFloat(iValue.Float(), 32)
+	case reflect.Float64:
+		enc.writeFloat(iValue.Float(), 64)
+	case reflect.String:
+		enc.writeString(iValue.String())
+	case reflect.Slice:
+		if iValue.IsNil() {
+			enc.writeNil()
+			return nil
+		}
+		if iType.Elem().Kind() == reflect.Uint8 {
+			enc.writeBytes(iValue.Bytes())
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		enc.writeArray(iValue.Len())
+		for i := 0; i < iValue.Len(); i++ {
+			if err := codecEncode(enc, iValue.Index(i), tag); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if iValue.IsNil() {
+			enc.writeNil()
+			return nil
+		}
+		keys := iValue.MapKeys()
+		if iType.Key().Kind() == reflect.String {
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].String() < keys[j].String()
+			})
+		}
+		enc.writeMap(len(keys))
+		for _, key := range keys {
+			if err := codecEncode(enc, key, tag); err != nil {
+				return err
+			}
+			if err := codecEncode(enc, iValue.MapIndex(key), tag); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := getCodecFields(iType, tag)
+		values := make([]reflect.Value, 0, len(fields))
+		names := make([]string, 0, len(fields))
+		for _, field := range fields {
+			val, ok := codecFieldByIndex(iValue, field.index)
+			if !ok || (field.omitempty && isZeroValue(val)) {
+				continue
+			}
+			values = append(values, val)
+			names = append(names, field.name)
+		}
+		enc.writeMap(len(values))
+		for i := range values {
+			enc.writeString(names[i])
+			if err := codecEncode(enc, values[i], tag); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf(ErrFormatCodecUnsupportedType, iType.String())
+	}
+	return nil
+}
+
+// codecFieldByIndex 函数获取嵌套属性，匿名指针为空时返回false。
+func codecFieldByIndex(iValue reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 && iValue.Kind() == reflect.Ptr {
+			if iValue.IsNil() {
+				return iValue, false
+			}
+			iValue = iValue.Elem()
+		}
+		iValue = iValue.Field(idx)
+	}
+	return iValue, true
+}
+
+// isZeroValue 函数检查omitempty的空值。
+func isZeroValue(iValue reflect.Value) bool {
+	switch iValue.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return iValue.Len() == 0
+	case reflect.Bool:
+		return !iValue.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return iValue.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return iValue.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return iValue.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return iValue.IsNil()
+	}
+	return false
+}
+
+// codecUnmarshal 函数将解码的数据赋值给指针对象。
+func codecUnmarshal(val interface{}, i interface{}, tag string) error {
+	iValue := reflect.ValueOf(i)
+	if iValue.Kind() != reflect.Ptr || iValue.IsNil() {
+		return ErrConverterInputDataNotPtr
+	}
+	return codecAssign(iValue.Elem(), val, tag)
+}
+
+// codecAssign 函数将解码的数据赋值给反射对象。
+//
+// 解码数据类型为nil、bool、int64、uint64、float64、string、[]byte、time.Time、[]interface{}、[]codecPair。
+func codecAssign(iValue reflect.Value, val interface{}, tag string) error {
+	if val == nil {
+		iValue.Set(reflect.Zero(iValue.Type()))
+		return nil
+	}
+	iType := iValue.Type()
+	if iType == typeTime {
+		switch v := val.(type) {
+		case time.Time:
+			iValue.Set(reflect.ValueOf(v))
+			return nil
+		case int64, uint64, float64:
+			sec, _ := codecToFloat(v)
+			iValue.Set(reflect.ValueOf(time.Unix(0, int64(sec*1e9))))
+			return nil
+		}
+	}
+	if iValue.Kind() == reflect.Ptr {
+		if iValue.IsNil() {
+			iValue.Set(reflect.New(iType.Elem()))
+		}
+		return codecAssign(iValue.Elem(), val, tag)
+	}
+	if reflect.PtrTo(iType).Implements(typeTextUnmarshaler) && iValue.CanAddr() {
+		switch v := val.(type) {
+		case string:
+			return iValue.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v))
+		case []byte:
+			return iValue.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(v)
+		}
+	}
+
+	switch iValue.Kind() {
+	case reflect.Interface:
+		if iType.NumMethod() == 0 {
+			iValue.Set(reflect.ValueOf(codecToInterface(val)))
+			return nil
+		}
+	case reflect.Bool:
+		if v, ok := val.(bool); ok {
+			iValue.SetBool(v)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, ok := codecToInt(val); ok && !iValue.OverflowInt(v) {
+			iValue.SetInt(v)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v, ok := codecToInt(val); ok && v >= 0 && !iValue.OverflowUint(uint64(v)) {
+			iValue.SetUint(uint64(v))
+			return nil
+		}
+		if v, ok := val.(uint64); ok && !iValue.OverflowUint(v) {
+			iValue.SetUint(v)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if v, ok := codecToFloat(val); ok {
+			iValue.SetFloat(v)
+			return nil
+		}
+	case reflect.String:
+		switch v := val.(type) {
+		case string:
+			iValue.SetString(v)
+			return nil
+		case []byte:
+			iValue.SetString(string(v))
+			return nil
+		}
+	case reflect.Slice:
+		if iType == typeBytes || iType.Elem().Kind() == reflect.Uint8 {
+			switch v := val.(type) {
+			case []byte:
+				iValue.SetBytes(append([]byte(nil), v...))
+				return nil
+			case string:
+				iValue.SetBytes([]byte(v))
+				return nil
+			}
+		}
+		if vals, ok := val.([]interface{}); ok {
+			slice := reflect.MakeSlice(iType, len(vals), len(vals))
+			for i := range vals {
+				if err := codecAssign(slice.Index(i), vals[i], tag); err != nil {
+					return err
+				}
+			}
+			iValue.Set(slice)
+			return nil
+		}
+	case reflect.Array:
+		if vals, ok := val.([]interface{}); ok {
+			for i := 0; i < iValue.Len(); i++ {
+				var v interface{}
+				if i < len(vals) {
+					v = vals[i]
+				}
+				if err := codecAssign(iValue.Index(i), v, tag); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		if pairs, ok := val.([]codecPair); ok {
+			if iValue.IsNil() {
+				iValue.Set(reflect.MakeMap(iType))
+			}
+			for _, pair := range pairs {
+				key := reflect.New(iType.Key()).Elem()
+				k := pair.key
+				if iType.Key().Kind() == reflect.String {
+					k = codecKeyString(pair.key)
+				}
+				if err := codecAssign(key, k, tag); err != nil {
+					return err
+				}
+				elem := reflect.New(iType.Elem()).Elem()
+				if err := codecAssign(elem, pair.val, tag); err != nil {
+					return err
+				}
+				iValue.SetMapIndex(key, elem)
+			}
+			return nil
+		}
+	case reflect.Struct:
+		if pairs, ok := val.([]codecPair); ok {
+			fields := getCodecFields(iType, tag)
+			for _, pair := range pairs {
+				field, ok := codecFindField(fields, codecKeyString(pair.key))
+				if !ok {
+					continue
+				}
+				if err := codecAssign(codecFieldByIndexAlloc(iValue, field.index), pair.val, tag); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf(ErrFormatCodecAssign, codecTypeName(val), iType.String())
+}
+
+// codecFindField 函数查找名称匹配的属性，优先完全匹配，其次忽略大小写匹配。
+func codecFindField(fields []codecField, name string) (codecField, bool) {
+	for _, field := range fields {
+		if field.name == name {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.name, name) {
+			return field, true
+		}
+	}
+	return codecField{}, false
+}
+
+// codecFieldByIndexAlloc 函数获取嵌套属性，匿名指针为空时创建对象。
+func codecFieldByIndexAlloc(iValue reflect.Value, index []int) reflect.Value {
+	for i, idx := range index {
+		if i > 0 && iValue.Kind() == reflect.Ptr {
+			if iValue.IsNil() {
+				iValue.Set(reflect.New(iValue.Type().Elem()))
+			}
+			iValue = iValue.Elem()
+		}
+		iValue = iValue.Field(idx)
+	}
+	return iValue
+}
+
+func codecToInt(val interface{}) (int64, bool) {
+	switch v := val.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float64:
+		return int64(v), v == math.Trunc(v) && v >= math.MinInt64 && v <= math.MaxInt64
+	}
+	return 0, false
+}
+
+func codecToFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func codecKeyString(key interface{}) string {
+	switch k := key.(type) {
+	case string:
+		return k
+	case []byte:
+		return string(k)
+	default:
+		return fmt.Sprint(k)
+	}
+}
+
+// codecToInterface 函数将解码的数据转换成interface{}使用的类型，map转换成map[string]interface{}。
+func codecToInterface(val interface{}) interface{} {
+	switch v := val.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = codecToInterface(v[i])
+		}
+		return v
+	case []codecPair:
+		data := make(map[string]interface{}, len(v))
+		for _, pair := range v {
+			data[codecKeyString(pair.key)] = codecToInterface(pair.val)
+		}
+		return data
+	}
+	return val
+}
+
+func codecTypeName(val interface{}) string {
+	switch val.(type) {
+	case []interface{}:
+		return "array"
+	case []codecPair:
+		return "map"
+	}
+	return fmt.Sprintf("%T", val)
+}
+
+// msgpackEncoder 实现msgpack格式编码。
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (enc *msgpackEncoder) writeNil() {
+	enc.buf = append(enc.buf, 0xc0)
+}
+
+func (enc *msgpackEncoder) writeBool(b bool) {
+	if b {
+		enc.buf = append(enc.buf, 0xc3)
+	} else {
+		enc.buf = append(enc.buf, 0xc2)
+	}
+}
+
+func (enc *msgpackEncoder) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		enc.writeUint(uint64(i))
+	case i >= -32:
+		enc.buf = append(enc.buf, byte(i))
+	case i >= math.MinInt8:
+		enc.buf = append(enc.buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		enc.buf = append(enc.buf, 0xd1, byte(i>>8), byte(i))
+	case i >= math.MinInt32:
+		enc.buf = append(enc.buf, 0xd2)
+		enc.buf = appendUint(enc.buf, uint64(i), 4)
+	default:
+		enc.buf = append(enc.buf, 0xd3)
+		enc.buf = appendUint(enc.buf, uint64(i), 8)
+	}
+}
+
+func (enc *msgpackEncoder) writeUint(i uint64) {
+	switch {
+	case i < 128:
+		enc.buf = append(enc.buf, byte(i))
+	case i <= math.MaxUint8:
+		enc.buf = append(enc.buf, 0xcc, byte(i))
+	case i <= math.MaxUint16:
+		enc.buf = append(enc.buf, 0xcd, byte(i>>8), byte(i))
+	case i <= math.MaxUint32:
+		enc.buf = append(enc.buf, 0xce)
+		enc.buf = appendUint(enc.buf, i, 4)
+	default:
+		enc.buf = append(enc.buf, 0xcf)
+		enc.buf = appendUint(enc.buf, i, 8)
+	}
+}
+
+func (enc *msgpackEncoder) writeFloat(f float64, bits int) {
+	if bits == 32 {
+		enc.buf = append(enc.buf, 0xca)
+		enc.buf = appendUint(enc.buf, uint64(math.Float32bits(float32(f))), 4)
+	} else {
+		enc.buf = append(enc.buf, 0xcb)
+		enc.buf = appendUint(enc.buf, math.Float64bits(f), 8)
+	}
+}
+
+func (enc *msgpackEncoder) writeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		enc.buf = append(enc.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		enc.buf = append(enc.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		enc.buf = append(enc.buf, 0xda, byte(n>>8), byte(n))
+	default:
+		enc.buf = append(enc.buf, 0xdb)
+		enc.buf = appendUint(enc.buf, uint64(n), 4)
+	}
+	enc.buf = append(enc.buf, s...)
+}
+
+func (enc *msgpackEncoder) writeBytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		enc.buf = append(enc.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		enc.buf = append(enc.buf, 0xc5, byte(n>>8), byte(n))
+	default:
+		enc.buf = append(enc.buf, 0xc6)
+		enc.buf = appendUint(enc.buf, uint64(n), 4)
+	}
+	enc.buf = append(enc.buf, b...)
+}
+
+func (enc *msgpackEncoder) writeArray(n int) {
+	switch {
+	case n < 16:
+		enc.buf = append(enc.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		enc.buf = append(enc.buf, 0xdc, byte(n>>8), byte(n))
+	default:
+		enc.buf = append(enc.buf, 0xdd)
+		enc.buf = appendUint(enc.buf, uint64(n), 4)
+	}
+}
+
+func (enc *msgpackEncoder) writeMap(n int) {
+	switch {
+	case n < 16:
+		enc.buf = append(enc.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		enc.buf = append(enc.buf, 0xde, byte(n>>8), byte(n))
+	default:
+		enc.buf = append(enc.buf, 0xdf)
+		enc.buf = appendUint(enc.buf, uint64(n), 4)
+	}
+}
+
+// writeTime 方法使用timestamp扩展类型-1编码时间，纳秒为0且秒数小于32位时使用timestamp 32格式。
+func (enc *msgpackEncoder) writeTime(t time.Time) {
+	sec, nsec := t.Unix(), int64(t.Nanosecond())
+	switch {
+	case nsec == 0 && sec >= 0 && sec <= math.MaxUint32:
+		enc.buf = append(enc.buf, 0xd6, 0xff)
+		enc.buf = appendUint(enc.buf, uint64(sec), 4)
+	case sec >= 0 && sec < 1<<34:
+		enc.buf = append(enc.buf, 0xd7, 0xff)
+		enc.buf = appendUint(enc.buf, uint64(nsec)<<34|uint64(sec), 8)
+	default:
+		enc.buf = append(enc.buf, 0xc7, 12, 0xff)
+		enc.buf = appendUint(enc.buf, uint64(nsec), 4)
+		enc.buf = appendUint(enc.buf, uint64(sec), 8)
+	}
+}
+
+func appendUint(buf []byte, i uint64, n int) []byte {
+	for n--; n >= 0; n-- {
+		buf = append(buf, byte(i>>(uint(n)*8)))
+	}
+	return buf
+}
+
+// msgpackDecoder 实现msgpack格式解码。
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (dec *msgpackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(dec.data)-dec.pos {
+		return nil, ErrMsgpackDataInvalid
+	}
+	b := dec.data[dec.pos : dec.pos+n]
+	dec.pos += n
+	return b, nil
+}
+
+func (dec *msgpackDecoder) readUint(n int) (uint64, error) {
+	b, err := dec.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var i uint64
+	for _, c := range b {
+		i = i<<8 | uint64(c)
+	}
+	return i, nil
+}
+
+func (dec *msgpackDecoder) decode(depth int) (interface{}, error) {
+	if depth > codecMaxDepth {
+		return nil, ErrCodecDataDepth
+	}
+	b, err := dec.read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return dec.decodeString(int(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return dec.decodeArray(int(c&0x0f), depth)
+	case c&0xf0 == 0x80:
+		return dec.decodeMap(int(c&0x0f), depth)
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		i, err := dec.readUint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if i <= math.MaxInt64 {
+			return int64(i), nil
+		}
+		return i, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := uint(1 << (c - 0xd0))
+		i, err := dec.readUint(int(n))
+		// 符号扩展
+		return int64(i<<(64-n*8)) >> (64 - n*8), err
+	case 0xca:
+		i, err := dec.readUint(4)
+		return float64(math.Float32frombits(uint32(i))), err
+	case 0xcb:
+		i, err := dec.readUint(8)
+		return math.Float64frombits(i), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := dec.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return dec.decodeString(int(n))
+	case 0xc4, 0xc5, 0xc6:
+		n, err := dec.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := dec.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xdc, 0xdd:
+		n, err := dec.readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return dec.decodeArray(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := dec.readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return dec.decodeMap(int(n), depth)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return dec.decodeExt(1 << (c - 0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := dec.readUint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return dec.decodeExt(int(n))
+	}
+	return nil, ErrMsgpackDataInvalid
+}
+
+func (dec *msgpackDecoder) decodeString(n int) (interface{}, error) {
+	b, err := dec.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (dec *msgpackDecoder) decodeArray(n int, depth int) (interface{}, error) {
+	if n > len(dec.data)-dec.pos {
+		return nil, ErrMsgpackDataInvalid
+	}
+	vals := make([]interface{}, n)
+	for i := range vals {
+		val, err := dec.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
+
+func (dec *msgpackDecoder) decodeMap(n int, depth int) (interface{}, error) {
+	if n*2 > len(dec.data)-dec.pos {
+		return nil, ErrMsgpackDataInvalid
+	}
+	pairs := make([]codecPair, n)
+	for i := range pairs {
+		key, err := dec.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		val, err := dec.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		pairs[i] = codecPair{key, val}
+	}
+	return pairs, nil
+}
+
+// decodeExt 方法解码扩展类型，支持timestamp扩展类型-1，其他扩展类型返回数据。
+func (dec *msgpackDecoder) decodeExt(n int) (interface{}, error) {
+	typ, err := dec.read(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := dec.read(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ[0]) != -1 {
+		return append([]byte(nil), data...), nil
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0), nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data))), nil
+	}
+	return nil, ErrMsgpackDataInvalid
+}
+
+// cborEncoder 实现cbor格式编码。
+type cborEncoder struct {
+	buf []byte
+}
+
+func (enc *cborEncoder) writeHead(major byte, i uint64) {
+	switch {
+	case i < 24:
+		enc.buf = append(enc.buf, major<<5|byte(i))
+	case i <= math.MaxUint8:
+		enc.buf = append(enc.buf, major<<5|24, byte(i))
+	case i <= math.MaxUint16:
+		enc.buf = append(enc.buf, major<<5|25, byte(i>>8), byte(i))
+	case i <= math.MaxUint32:
+		enc.buf = append(enc.buf, major<<5|26)
+		enc.buf = appendUint(enc.buf, i, 4)
+	default:
+		enc.buf = append(enc.buf, major<<5|27)
+		enc.buf = appendUint(enc.buf, i, 8)
+	}
+}
+
+func (enc *cborEncoder) writeNil() {
+	enc.buf = append(enc.buf, 0xf6)
+}
+
+func (enc *cborEncoder) writeBool(b bool) {
+	if b {
+		enc.buf = append(enc.buf, 0xf5)
+	} else {
+		enc.buf = append(enc.buf, 0xf4)
+	}
+}
+
+func (enc *cborEncoder) writeInt(i int64) {
+	if i >= 0 {
+		enc.writeHead(0, uint64(i))
+	} else {
+		enc.writeHead(1, uint64(-1-i))
+	}
+}
+
+func (enc *cborEncoder) writeUint(i uint64) {
+	enc.writeHead(0, i)
+}
+
+func (enc *cborEncoder) writeFloat(f float64, bits int) {
+	if bits == 32 {
+		enc.buf = append(enc.buf, 0xfa)
+		enc.buf = appendUint(enc.buf, uint64(math.Float32bits(float32(f))), 4)
+	} else {
+		enc.buf = append(enc.buf, 0xfb)
+		enc.buf = appendUint(enc.buf, math.Float64bits(f), 8)
+	}
+}
+
+func (enc *cborEncoder) writeString(s string) {
+	enc.writeHead(3, uint64(len(s)))
+	enc.buf = append(enc.buf, s...)
+}
+
+func (enc *cborEncoder) writeBytes(b []byte) {
+	enc.writeHead(2, uint64(len(b)))
+	enc.buf = append(enc.buf, b...)
+}
+
+func (enc *cborEncoder) writeArray(n int) {
+	enc.writeHead(4, uint64(n))
+}
+
+func (enc *cborEncoder) writeMap(n int) {
+	enc.writeHead(5, uint64(n))
+}
+
+// writeTime 方法使用tag 0编码RFC3339格式时间字符串。
+func (enc *cborEncoder) writeTime(t time.Time) {
+	enc.writeHead(6, 0)
+	enc.writeString(t.Format(time.RFC3339Nano))
+}
+
+// cborDecoder 实现cbor格式解码。
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (dec *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(dec.data)-dec.pos) {
+		return nil, ErrCBORDataInvalid
+	}
+	b := dec.data[dec.pos : dec.pos+int(n)]
+	dec.pos += int(n)
+	return b, nil
+}
+
+// readHead 方法读取数据头，返回主类型、附加信息和参数值。
+func (dec *cborDecoder) readHead() (byte, byte, uint64, error) {
+	b, err := dec.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		b, err = dec.read(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var i uint64
+		for _, c := range b {
+			i = i<<8 | uint64(c)
+		}
+		return major, info, i, nil
+	case info == 31 && major >= 2 && major <= 5:
+		return major, info, 0, nil
+	case info == 31 && major == 7:
+		return major, info, 0, nil
+	}
+	return 0, 0, 0, ErrCBORDataInvalid
+}
+
+func (dec *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > codecMaxDepth {
+		return nil, ErrCodecDataDepth
+	}
+	major, info, arg, err := dec.readHead()
+	if err != nil {
+		return nil, err
+	}
+	indefinite := info == 31
+	switch major {
+	case 0:
+		if arg <= math.MaxInt64 {
+			return int64(arg), nil
+		}
+		return arg, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return float64(-1) - float64(arg), nil
+		}
+		return -1 - int64(arg), nil
+	case 2, 3:
+		var b []byte
+		if indefinite {
+			b, err = dec.decodeChunks(major)
+		} else {
+			b, err = dec.read(arg)
+			b = append([]byte(nil), b...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if major == 3 {
+			return string(b), nil
+		}
+		return b, nil
+	case 4:
+		if !indefinite && arg > uint64(len(dec.data)-dec.pos) {
+			return nil, ErrCBORDataInvalid
+		}
+		vals := make([]interface{}, 0, int(arg))
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && dec.isBreak() {
+				break
+			}
+			val, err := dec.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		return vals, nil
+	case 5:
+		if !indefinite && arg*2 > uint64(len(dec.data)-dec.pos) {
+			return nil, ErrCBORDataInvalid
+		}
+		pairs := make([]codecPair, 0, int(arg))
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && dec.isBreak() {
+				break
+			}
+			key, err := dec.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			val, err := dec.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, codecPair{key, val})
+		}
+		return pairs, nil
+	case 6:
+		val, err := dec.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return cborDecodeTag(arg, val)
+	default:
+		return dec.decodeSimple(info, arg)
+	}
+}
+
+// isBreak 方法检查并跳过不定长度数据的结束标志0xff。
+func (dec *cborDecoder) isBreak() bool {
+	if dec.pos < len(dec.data) && dec.data[dec.pos] == 0xff {
+		dec.pos++
+		return true
+	}
+	return false
+}
+
+// decodeChunks 方法读取不定长度字符串的全部分块。
+func (dec *cborDecoder) decodeChunks(major byte) ([]byte, error) {
+	var data []byte
+	for !dec.isBreak() {
+		m, info, arg, err := dec.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || info == 31 {
+			return nil, ErrCBORDataInvalid
+		}
+		b, err := dec.read(arg)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b...)
+	}
+	return data, nil
+}
+
+func (dec *cborDecoder) decodeSimple(info byte, arg uint64) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return cborHalfFloat(uint16(arg)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case 27:
+		return math.Float64frombits(arg), nil
+	}
+	return nil, ErrCBORDataInvalid
+}
+
+// cborDecodeTag 函数处理tag 0和tag 1时间，其他tag返回内容。
+func cborDecodeTag(tag uint64, val interface{}) (interface{}, error) {
+	switch tag {
+	case 0:
+		s, ok := val.(string)
+		if !ok {
+			return nil, ErrCBORDataInvalid
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	case 1:
+		sec, ok := codecToFloat(val)
+		if !ok {
+			return nil, ErrCBORDataInvalid
+		}
+		return time.Unix(0, int64(sec*1e9)), nil
+	}
+	return val, nil
+}
+
+// cborHalfFloat 函数转换IEEE 754半精度浮点数。
+func cborHalfFloat(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var val float64
+	switch exp {
+	case 0:
+		val = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			val = math.Inf(1)
+		} else {
+			val = math.NaN()
+		}
+	default:
+		val = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -val
+	}
+	return val
+}
@@ -5,6 +5,10 @@ package command
 /*
 利用系统信号进制，执行start、daemon、stop、status、restart命令来操作进程。
 进程pid存储在pid文件中。
+
+restart命令向进程发送SIGUSR2信号，进程收到信号后执行HotRestart，
+fork/exec新进程并传递全部监听fd，等待新进程启动完成后结束当前App使用Server.Shutdown处理完已有连接。
+新进程启动失败或超时，结束新进程并重新锁定pid文件，当前进程继续提供服务。
 */
 
 import (
@@ -15,11 +19,17 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 )
 
+// defaultReadyTimeout 定义热重启默认等待新进程启动完成的超时时间。
+const defaultReadyTimeout = 30 * time.Second
+
 // Command is a command parser that performs the corresponding behavior based on the current command.
 //
 // Command 对象是一个命令解析器，根据当前命令执行对应行为。
@@ -29,15 +39,26 @@ type Command struct {
 	cmd     string
 	Args    []string
 	Envs    []string
-	file    *os.File
+	Cancel  context.CancelFunc
+	// ReadyTimeout 定义热重启等待新进程启动完成的超时时间。
+	ReadyTimeout time.Duration
+	file         *os.File
+	restart      bool
+	mu           sync.Mutex
 }
 
-// Init 函数初始化定义程序启动命令。
+// Init 函数初始化定义程序启动命令，start或daemon启动的进程收到SIGUSR2信号时会执行HotRestart。
 func Init(app *eudore.App) error {
 	cmd := eudore.GetString(app.Config.Get("command"), "start")
 	pid := eudore.GetString(app.Config.Get("pidfile"), "/var/run/eudore.pid")
 	app.Logger.Infof("current command is %s, pidfile in %s, process pid is %d.", cmd, pid, os.Getpid())
-	return NewCommand(app, cmd, pid).Run()
+	c := NewCommand(app, cmd, pid)
+	c.Cancel = app.CancelFunc
+	err := c.Run()
+	if err == nil && (cmd == "start" || cmd == "daemon") {
+		go c.notifyRestart(app.Logger)
+	}
+	return err
 }
 
 // NewCommand returns a command to parse the object, the current command and the process pid file path,
@@ -49,9 +70,10 @@ func NewCommand(ctx context.Context, cmd, pidfile string) *Command {
 		pidfile = "/var/run/eudore.pid"
 	}
 	return &Command{
-		ctx:     ctx,
-		cmd:     cmd,
-		pidfile: pidfile,
+		ctx:          ctx,
+		cmd:          cmd,
+		pidfile:      pidfile,
+		ReadyTimeout: defaultReadyTimeout,
 	}
 }
 
@@ -107,8 +129,46 @@ func (c *Command) Start() error {
 		return err
 	}
 
+	// 热重启的新进程在App启动钩子中写入pid，此时配置解析和监听已经完成。
+	if _, ok := os.LookupEnv(eudore.EnvEudoreReadyFD); ok {
+		if app, ok := c.ctx.(*eudore.App); ok {
+			return app.AddHook(eudore.AppHook{
+				Name: "command",
+				Start: func(context.Context) error {
+					return c.Ready()
+				},
+			})
+		}
+		return c.Ready()
+	}
+
 	// 写入pid
-	return c.writepid()
+	err = c.writepid()
+	if err == nil {
+		c.Release()
+	}
+	return err
+}
+
+// Ready 方法写入并锁定pid文件，如果是热重启的新进程则通知父进程启动完成，父进程收到通知后才会结束。
+//
+// 使用Init时会在App启动钩子中自动调用。
+func (c *Command) Ready() error {
+	err := c.writepid()
+	if err != nil {
+		return err
+	}
+	c.Release()
+
+	fd, err := strconv.Atoi(os.Getenv(eudore.EnvEudoreReadyFD))
+	if err != nil {
+		return nil
+	}
+	os.Unsetenv(eudore.EnvEudoreReadyFD)
+	file := os.NewFile(uintptr(fd), "ready")
+	defer file.Close()
+	_, err = file.Write([]byte(strconv.Itoa(os.Getpid())))
+	return err
 }
 
 // Daemon Start the process in the background. If it is not started in the background, create a background process.
@@ -147,6 +207,115 @@ func (c *Command) Restart() error {
 	return c.ExecSignal(syscall.Signal(0x0c))
 }
 
+// HotRestart 函数fork/exec一个新进程，使用exec.Cmd.ExtraFiles和环境变量EnvEudoreListeners传递全部监听，
+// 新进程使用eudore.ListenWithFD继承监听不会重新绑定端口。
+//
+// 新进程调用Ready方法写入pid文件并通过EnvEudoreReadyFD管道通知启动完成后，调用Cancel结束当前进程，
+// App.Run会使用Server.Shutdown处理完已有连接。
+// 新进程在ReadyTimeout内未完成启动或者退出，结束新进程并重新锁定pid文件，当前进程继续提供服务并返回错误。
+func (c *Command) HotRestart() error {
+	names, files := eudore.GetListenerFiles()
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Args = append(cmd.Args, c.Args...)
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, eudore.EnvEudoreListeners+"=") && !strings.HasPrefix(env, eudore.EnvEudoreReadyFD+"=") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", eudore.EnvEudoreListeners, strings.Join(names, ",")))
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", eudore.EnvEudoreReadyFD, 3+len(files)))
+	cmd.Env = append(cmd.Env, c.Envs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, writer)
+
+	// 释放pid文件锁，由新进程写入pid；新进程启动失败重新锁定pid文件。
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	locked := c.unlockpid()
+	err = cmd.Start()
+	writer.Close()
+	if err == nil {
+		err = c.waitReady(cmd, reader)
+	}
+	if err != nil {
+		if locked {
+			c.writepid()
+		}
+		return err
+	}
+	c.restart = true
+	if c.Cancel != nil {
+		c.Cancel()
+	}
+	return nil
+}
+
+// waitReady 方法等待新进程通知启动完成，新进程退出或超时返回错误并结束新进程。
+func (c *Command) waitReady(cmd *exec.Cmd, reader *os.File) error {
+	ch := make(chan error, 1)
+	go func() {
+		// 新进程退出时管道写入端关闭，读取返回EOF。
+		body, err := ioutil.ReadAll(reader)
+		if err == nil && len(body) == 0 {
+			err = fmt.Errorf("hot restart process %d exited before ready", cmd.Process.Pid)
+		}
+		ch <- err
+	}()
+
+	timeout := c.ReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case err = <-ch:
+		if err == nil {
+			go cmd.Wait()
+			return nil
+		}
+	case <-timer.C:
+		err = fmt.Errorf("hot restart process %d not ready in %s", cmd.Process.Pid, timeout)
+	}
+	cmd.Process.Kill()
+	cmd.Wait()
+	return err
+}
+
+// notifyRestart 方法监听SIGUSR2信号执行HotRestart。
+func (c *Command) notifyRestart(log eudore.Logger) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.Signal(0x0c))
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ch:
+			log.Infof("process %d receive restart signal, start new process.", os.Getpid())
+			err := c.HotRestart()
+			if err != nil {
+				log.Errorf("hot restart error: %v", err)
+				continue
+			}
+			return
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
 // ExecSignal 函数向pidfile内的进程发送指定命令
 func (c *Command) ExecSignal(sig os.Signal) error {
 	pid, err := c.readpid()
@@ -200,27 +369,39 @@ func (c *Command) writepid() (err error) {
 	err = syscall.Flock(int(c.file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
 	if err != nil {
 		c.file.Close()
+		c.file = nil
 		return
 	}
 
 	// write pid
+	c.file.Truncate(0)
 	body := []byte(fmt.Sprintf("%d", os.Getpid()))
-	_, err = c.file.Write(body)
-	if err != nil {
-		return
+	_, err = c.file.WriteAt(body, 0)
+	return err
+}
+
+// unlockpid 方法解除pid文件独占锁并关闭文件，不删除pid文件，返回之前是否锁定了pid文件。
+func (c *Command) unlockpid() bool {
+	if c.file == nil {
+		return false
 	}
-	c.Release()
-	return nil
+	syscall.Flock(int(c.file.Fd()), syscall.LOCK_UN)
+	c.file.Close()
+	c.file = nil
+	return true
 }
 
-// Release Close the delete pid file and release the exclusive lock
+// Release Close the delete pid file and release the exclusive lock, if hot restart is performed, the pid file will not be deleted.
 //
-// Release 函数关闭删除pid文件，并解除独占锁
+// Release 函数在ctx结束后关闭删除pid文件，并解除独占锁，如果执行了热重启不会删除pid文件。
 func (c *Command) Release() {
 	go func() {
 		<-c.ctx.Done()
-		syscall.Flock(int(c.file.Fd()), syscall.LOCK_UN)
-		c.file.Close()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.restart || !c.unlockpid() {
+			return
+		}
 		os.Remove(c.pidfile)
 	}()
 }
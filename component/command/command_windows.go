@@ -6,9 +6,13 @@ windows平台禁止使用，原因：pid文件锁不支持。
 
 import (
 	"context"
+	"errors"
 	"github.com/eudore/eudore"
 )
 
+// ErrHotRestartNotSupported 表示windows平台不支持热重启。
+var ErrHotRestartNotSupported = errors.New("command hot restart is not supported on windows")
+
 // Command is a command parser that performs the corresponding behavior based on the current command.
 //
 // Command 对象是一个命令解析器，根据当前命令执行对应行为。
@@ -30,3 +34,8 @@ func NewCommand(context.Context, string, string) *Command {
 func (c *Command) Run() error {
 	return nil
 }
+
+// HotRestart 函数在windows平台不支持，返回ErrHotRestartNotSupported。
+func (c *Command) HotRestart() error {
+	return ErrHotRestartNotSupported
+}
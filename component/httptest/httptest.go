@@ -17,6 +17,8 @@ var (
 	HTTPTestHost = "eudore-httptest"
 	// ErrResponseWriterTestNotSupportHijack ResponseWriterTest对象的Hijack不支持。
 	ErrResponseWriterTestNotSupportHijack = errors.New("ResponseWriterTest no support hijack")
+	// ErrWebsocketAcceptInvalid websocket握手响应的Sec-WebSocket-Accept Header无效。
+	ErrWebsocketAcceptInvalid = errors.New("websocket handshake Sec-WebSocket-Accept header is invalid")
 )
 
 type (
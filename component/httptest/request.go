@@ -15,6 +15,8 @@ import (
 	"net/url"
 	"os"
 	"strings"
+
+	"github.com/eudore/eudore"
 )
 
 // RequestReaderTest 实现protocol.RequestReader接口，用于执行测试请求。
@@ -27,6 +29,7 @@ type RequestReaderTest struct {
 	// data
 	*http.Request
 	websocketHandle func(net.Conn)
+	websocketHeader http.Header
 	json            interface{}
 	formValue       map[string][]string
 	formFile        map[string][]fileContent
@@ -243,6 +246,25 @@ func (r *RequestReaderTest) WithWebsocket(fn func(net.Conn)) *RequestReaderTest
 	return r
 }
 
+// WithWebsocketConn 方法使用eudore.WebsocketConn作为websocket客户端处理连接，握手时请求permessage-deflate扩展。
+//
+// 响应Sec-WebSocket-Accept无效时关闭连接，不会调用处理函数。
+func (r *RequestReaderTest) WithWebsocketConn(fn func(*eudore.WebsocketConn)) *RequestReaderTest {
+	key := eudore.NewWebsocketKey()
+	r.WithWebsocket(func(conn net.Conn) {
+		if r.websocketHeader.Get(eudore.HeaderSecWebSocketAccept) != eudore.GetWebsocketAccept(key) {
+			r.Client.Print(ErrWebsocketAcceptInvalid)
+			conn.Close()
+			return
+		}
+		compress := strings.HasPrefix(r.websocketHeader.Get(eudore.HeaderSecWebSocketExtensions), "permessage-deflate")
+		fn(eudore.NewWebsocketConn(conn, nil, false, compress))
+	})
+	r.Request.Header.Set("Sec-WebSocket-Key", key)
+	r.Request.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate; client_no_context_takeover; server_no_context_takeover")
+	return r
+}
+
 // Do 方法发送这个请求，使用客户端处理这个请求返回响应。
 func (r *RequestReaderTest) Do() *ResponseWriterTest {
 	if r.err != nil {
@@ -365,9 +387,11 @@ func (r *RequestReaderTest) DoHTTP() (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.ReadResponse(bufio.NewReader(conn), r.Request)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, r.Request)
 	if err == nil {
-		go r.websocketHandle(conn)
+		r.websocketHeader = resp.Header
+		go r.websocketHandle(&bufferConn{conn, reader})
 	}
 	return resp, err
 }
@@ -376,6 +400,16 @@ func (r *RequestReaderTest) handleCookie(resp *http.Response) {
 	r.Client.CookieJar.SetCookies(r.URL, resp.Cookies())
 }
 
+// bufferConn 定义读取握手响应后的连接，先读取已经缓冲的数据。
+type bufferConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
 var zeroDialer net.Dialer
 
 func (r *RequestReaderTest) dialConn() (net.Conn, error) {
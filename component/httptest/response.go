@@ -137,10 +137,12 @@ func (rw *ResponseWriterTest) Flush() {
 // Hijack 方法返回劫持的连接。
 func (rw *ResponseWriterTest) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if rw.Request.websocketHandle != nil {
+		// net.Pipe创建内存连接，服务端使用bufio.Writer缓冲写入。
 		serverConn, clientConn := net.Pipe()
 		rw.Add(1)
 		go func() {
-			resp, err := http.ReadResponse(bufio.NewReader(clientConn), rw.Request.Request)
+			reader := bufio.NewReader(clientConn)
+			resp, err := http.ReadResponse(reader, rw.Request.Request)
 			if err != nil {
 				clientConn.Close()
 				rw.Client.Print(err)
@@ -148,8 +150,9 @@ func (rw *ResponseWriterTest) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 				return
 			}
 			rw.HandleRespone(resp)
+			rw.Request.websocketHeader = resp.Header
 			rw.Done()
-			rw.Request.websocketHandle(clientConn)
+			rw.Request.websocketHandle(&bufferConn{clientConn, reader})
 		}()
 		return serverConn, bufio.NewReadWriter(bufio.NewReader(serverConn), bufio.NewWriter(serverConn)), nil
 	}
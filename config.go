@@ -1,11 +1,19 @@
 package eudore
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -25,12 +33,13 @@ Get/Set read and write data implementation:
 
 The default analysis function implementation:
 	Custom configuration analysis function
-	Parse multiple json files
+	Parse multiple json/yaml/toml files
 	Parse the length and short parameters of the command line
 	Parse Env environment variables
 	Configuration differentiation
 	Generate help information based on the structure
 	Switch working directory
+	Validate configuration using validate tags
 
 Config 定义配置管理，使用配置读写和解析功能。
 
@@ -41,12 +50,13 @@ Get/Set读写数据实现下列功能:
 
 默认解析函数实现下列功能:
 	自定义配置解析函数
-	解析多json文件
+	解析多json/yaml/toml文件
 	解析命令行长短参数
 	解析Env环境变量
 	配置差异化
 	根据结构体生成帮助信息
 	切换工作目录
+	使用validate tag验证配置
 */
 type Config interface {
 	Get(string) interface{}
@@ -57,10 +67,12 @@ type Config interface {
 
 // configMap 使用map保存配置。
 type configMap struct {
-	Keys   map[string]interface{} `alias:"keys"`
-	Print  func(...interface{})   `alias:"print"`
-	funcs  []ConfigParseFunc      `alias:"-"`
-	Locker sync.RWMutex           `alias:"-"`
+	Keys    map[string]interface{} `alias:"keys"`
+	Print   func(...interface{})   `alias:"print"`
+	funcs   []ConfigParseFunc      `alias:"-"`
+	sources map[string]string      `alias:"-"`
+	secrets []string               `alias:"-"`
+	Locker  sync.RWMutex           `alias:"-"`
 }
 
 // configEudore 使用结构体或map保存配置，通过属性或反射来读写属性。
@@ -68,6 +80,8 @@ type configEudore struct {
 	Keys          interface{}          `alias:"keys"`
 	Print         func(...interface{}) `alias:"print"`
 	funcs         []ConfigParseFunc    `alias:"-"`
+	sources       map[string]string    `alias:"-"`
+	secrets       []string             `alias:"-"`
 	configRLocker `alias:"-"`
 }
 
@@ -77,6 +91,14 @@ type configRLocker interface {
 	RUnlock()
 }
 
+// configSourcer 定义记录配置来源和secret键的方法，ConfigMap和ConfigEudore实现该接口。
+type configSourcer interface {
+	setSource(string, ...string)
+	getSource(string) string
+	setSecret(string)
+	isSecret(string) bool
+}
+
 // NewConfigMap 创建一个ConfigMap，如果传入参数为map[string]interface{},则作为初始化数据。
 //
 // ConfigMap将使用传入的map作为配置存储去Get/Set一个键值。
@@ -113,6 +135,8 @@ func (c *configMap) Set(key string, val interface{}) error {
 		keys, ok := val.(map[string]interface{})
 		if ok {
 			c.Keys = keys
+			c.sources = nil
+			c.secrets = nil
 		}
 	} else if key == "print" {
 		fn, ok := val.(func(...interface{}))
@@ -123,11 +147,38 @@ func (c *configMap) Set(key string, val interface{}) error {
 		}
 	} else {
 		c.Keys[key] = val
+		c.sources = configSetSource(c.sources, "set", key)
 	}
 	c.Locker.Unlock()
 	return nil
 }
 
+func (c *configMap) setSource(source string, keys ...string) {
+	c.Locker.Lock()
+	c.sources = configSetSource(c.sources, source, keys...)
+	c.Locker.Unlock()
+}
+
+func (c *configMap) getSource(key string) string {
+	c.Locker.RLock()
+	defer c.Locker.RUnlock()
+	return configGetSource(c.sources, key)
+}
+
+func (c *configMap) setSecret(key string) {
+	c.Locker.Lock()
+	if !sliceContains(c.secrets, key) {
+		c.secrets = append(c.secrets, key)
+	}
+	c.Locker.Unlock()
+}
+
+func (c *configMap) isSecret(key string) bool {
+	c.Locker.RLock()
+	defer c.Locker.RUnlock()
+	return sliceContains(c.secrets, key)
+}
+
 // ParseOption 执行一个配置解析函数选项。
 func (c *configMap) ParseOption(fn []ConfigParseFunc) []ConfigParseFunc {
 	c.funcs, fn = fn, c.funcs
@@ -185,11 +236,12 @@ func NewConfigEudore(i interface{}) Config {
 
 // Get 方法实现读取数据属性的一个属性。
 func (c *configEudore) Get(key string) (i interface{}) {
+	c.RLock()
 	if len(key) == 0 {
-		return c.Keys
+		i = c.Keys
+	} else {
+		i = Get(c.Keys, key)
 	}
-	c.RLock()
-	i = Get(c.Keys, key)
 	c.RUnlock()
 	return
 }
@@ -199,6 +251,8 @@ func (c *configEudore) Set(key string, val interface{}) (err error) {
 	c.Lock()
 	if len(key) == 0 {
 		c.Keys = val
+		c.sources = nil
+		c.secrets = nil
 	} else if key == "print" {
 		fn, ok := val.(func(...interface{}))
 		if ok {
@@ -208,11 +262,40 @@ func (c *configEudore) Set(key string, val interface{}) (err error) {
 		}
 	} else {
 		err = Set(c.Keys, key, val)
+		if err == nil {
+			c.sources = configSetSource(c.sources, "set", key)
+		}
 	}
 	c.Unlock()
 	return
 }
 
+func (c *configEudore) setSource(source string, keys ...string) {
+	c.Lock()
+	c.sources = configSetSource(c.sources, source, keys...)
+	c.Unlock()
+}
+
+func (c *configEudore) getSource(key string) string {
+	c.RLock()
+	defer c.RUnlock()
+	return configGetSource(c.sources, key)
+}
+
+func (c *configEudore) setSecret(key string) {
+	c.Lock()
+	if !sliceContains(c.secrets, key) {
+		c.secrets = append(c.secrets, key)
+	}
+	c.Unlock()
+}
+
+func (c *configEudore) isSecret(key string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return sliceContains(c.secrets, key)
+}
+
 // ParseOption 执行一个配置解析函数选项。
 func (c *configEudore) ParseOption(fn []ConfigParseFunc) []ConfigParseFunc {
 	c.funcs, fn = fn, c.funcs
@@ -249,16 +332,109 @@ func configPrint(c Config, args ...interface{}) {
 	c.Set("print", fmt.Sprint(args...))
 }
 
+// configSetSource 函数记录键的来源，同时删除子键的来源记录。
+func configSetSource(sources map[string]string, source string, keys ...string) map[string]string {
+	if sources == nil {
+		sources = make(map[string]string)
+	}
+	for _, key := range keys {
+		for k := range sources {
+			if strings.HasPrefix(k, key+".") {
+				delete(sources, k)
+			}
+		}
+		sources[key] = source
+	}
+	return sources
+}
+
+// configGetSource 函数获取键的来源，如果键没有记录使用父级键的来源，都没有记录返回"default"。
+func configGetSource(sources map[string]string, key string) string {
+	for {
+		source, ok := sources[key]
+		if ok {
+			return source
+		}
+		pos := strings.LastIndexByte(key, '.')
+		if pos == -1 {
+			return "default"
+		}
+		key = key[:pos]
+	}
+}
+
+// configSource 函数给支持记录来源的配置记录键的来源。
+func configSource(c Config, source string, keys ...string) {
+	sourcer, ok := c.(configSourcer)
+	if ok && len(keys) > 0 {
+		sourcer.setSource(source, keys...)
+	}
+}
+
+// ConfigValue 定义配置展开后的一个键值和来源。
+type ConfigValue struct {
+	Key    string      `alias:"key" json:"key"`
+	Value  interface{} `alias:"value" json:"value"`
+	Source string      `alias:"source" json:"source"`
+}
+
+// GetConfigValues 函数返回配置展开后的全部键值和来源，按键排序。
+//
+// 来源为文件路径、'--arg'、'ENV_'环境变量、'mods.xxx'、set或default，ConfigParseSecrets解析的键的值会被替换为"******"。
+func GetConfigValues(c Config) []ConfigValue {
+	values := configFlatten(c.Get(""))
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sourcer, _ := c.(configSourcer)
+	data := make([]ConfigValue, len(keys))
+	for i, key := range keys {
+		data[i] = ConfigValue{Key: key, Value: values[key], Source: "default"}
+		if sourcer != nil {
+			data[i].Source = sourcer.getSource(key)
+			if sourcer.isSecret(key) {
+				data[i].Value = "******"
+			}
+		}
+	}
+	return data
+}
+
 // ConfigParseJSON 方法解析json文件配置。
 func ConfigParseJSON(c Config) error {
+	return configParseFiles(c, configDecodeJSON)
+}
+
+func configDecodeJSON(c Config, _ string, r io.Reader) (map[string]interface{}, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(body, c)
+	if err != nil {
+		return nil, err
+	}
+	// 解析文件中的键用于记录配置来源
+	var data map[string]interface{}
+	json.Unmarshal(body, &data)
+	return data, nil
+}
+
+// configParseFiles 函数按顺序读取'config'配置的文件路径，使用第一个存在的文件解析配置，并记录文件内的键来源为文件路径。
+func configParseFiles(c Config, decode func(Config, string, io.Reader) (map[string]interface{}, error)) error {
 	configPrint(c, "config read paths: ", c.Get("config"))
 	for _, path := range GetStrings(c.Get("config")) {
 		file, err := os.Open(path)
+		var data map[string]interface{}
 		if err == nil {
-			err = json.NewDecoder(file).Decode(c)
+			data, err = decode(c, path, file)
 			file.Close()
 		}
 		if err == nil {
+			configSource(c, path, configFlattenKeys(data)...)
 			configPrint(c, "config load path: ", path)
 			return nil
 		}
@@ -290,6 +466,7 @@ func ConfigParseArgs(c Config) (err error) {
 				}
 				configPrint(c, fmt.Sprintf("config set short arg %s: --%s=%s", key[1:], lkey, val))
 				c.Set(lkey, val)
+				configSource(c, key, lkey)
 			}
 		} else if strings.HasPrefix(key, "--") {
 			if val == "" && reflect.ValueOf(c.Get(key[2:])).Kind() == reflect.Bool {
@@ -297,6 +474,7 @@ func ConfigParseArgs(c Config) (err error) {
 			}
 			configPrint(c, "config set arg: ", str)
 			c.Set(key[2:], val)
+			configSource(c, key, key[2:])
 		}
 	}
 	return
@@ -307,9 +485,10 @@ func ConfigParseEnvs(c Config) error {
 	for _, value := range os.Environ() {
 		if strings.HasPrefix(value, "ENV_") {
 			configPrint(c, "config set env: ", value)
-			k, v := split2byte(value, '=')
-			k = strings.ToLower(strings.Replace(k, "_", ".", -1))[4:]
+			env, v := split2byte(value, '=')
+			k := strings.ToLower(strings.Replace(env, "_", ".", -1))[4:]
 			c.Set(k, v)
+			configSource(c, env, k)
 		}
 	}
 	return nil
@@ -326,6 +505,7 @@ func ConfigParseMods(c Config) error {
 		if m != nil {
 			configPrint(c, "config load mod "+i)
 			ConvertTo(m, c.Get(""))
+			configSource(c, "mods."+i, configFlattenKeys(m)...)
 		}
 	}
 	return nil
@@ -341,6 +521,160 @@ func getOS() string {
 	return runtime.GOOS
 }
 
+// ConfigParseSecrets 函数解析配置中的secret引用，应该在ConfigParseFile、ConfigParseArgs、ConfigParseEnvs之后执行。
+//
+// 字符串值为"file://path"时读取文件内容替换，删除末尾换行；
+// 值为"enc:base64"时使用AES-GCM解密替换，密钥从环境变量EnvEudoreConfigKey读取，使用ConfigEncryptSecret函数生成加密值。
+//
+// 解析出的键会记录在ConfigMap和ConfigEudore中，GetConfigValues函数返回这些键的值时会替换为"******"。
+func ConfigParseSecrets(c Config) error {
+	errs := &muliterror{}
+	configEachSecret("", reflect.ValueOf(c.Get("")), func(key string, val reflect.Value) {
+		str, err := configResolveSecret(val.String())
+		if err != nil {
+			errs.HandleError(fmt.Errorf(ErrFormatConfigSecret, key, err))
+			return
+		}
+		configPrint(c, "config resolve secret: ", key)
+		source := val.String()
+		sourcer, ok := c.(configSourcer)
+		if ok {
+			sourcer.setSecret(key)
+			if !strings.HasPrefix(source, "file://") {
+				source = sourcer.getSource(key)
+			}
+		}
+		if val.CanSet() {
+			val.SetString(str)
+		} else {
+			c.Set(key, str)
+		}
+		configSource(c, source, key)
+	})
+	return errs.GetError()
+}
+
+// configEachSecret 函数遍历配置数据中的secret引用字符串，map中的值使用SetMapIndex写回。
+func configEachSecret(prefix string, iValue reflect.Value, fn func(string, reflect.Value)) {
+	switch iValue.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !iValue.IsNil() {
+			configEachSecret(prefix, iValue.Elem(), fn)
+		}
+	case reflect.Map:
+		for _, key := range iValue.MapKeys() {
+			val := iValue.MapIndex(key)
+			name := configJoinKey(prefix, fmt.Sprint(key.Interface()))
+			if val.Kind() == reflect.Interface {
+				val = val.Elem()
+			}
+			if val.Kind() == reflect.String && configIsSecret(val.String()) {
+				// map的值不可寻址，复制后写回
+				newValue := reflect.New(val.Type()).Elem()
+				newValue.Set(val)
+				fn(name, newValue)
+				if newValue.String() != val.String() {
+					iValue.SetMapIndex(key, newValue)
+				}
+				continue
+			}
+			configEachSecret(name, val, fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < iValue.Len(); i++ {
+			configEachSecret(configJoinKey(prefix, strconv.Itoa(i)), iValue.Index(i), fn)
+		}
+	case reflect.Struct:
+		iType := iValue.Type()
+		for i := 0; i < iType.NumField(); i++ {
+			field := iType.Field(i)
+			name := field.Tag.Get(DefaultGetSetTags[0])
+			if field.PkgPath != "" || name == "-" {
+				continue
+			}
+			if name == "" && !field.Anonymous {
+				name = field.Name
+			}
+			configEachSecret(configJoinKey(prefix, name), iValue.Field(i), fn)
+		}
+	case reflect.String:
+		if configIsSecret(iValue.String()) {
+			fn(prefix, iValue)
+		}
+	}
+}
+
+func configIsSecret(str string) bool {
+	return strings.HasPrefix(str, "file://") || strings.HasPrefix(str, "enc:")
+}
+
+// configResolveSecret 函数读取文件或解密字符串。
+func configResolveSecret(str string) (string, error) {
+	if strings.HasPrefix(str, "file://") {
+		body, err := ioutil.ReadFile(str[7:])
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(body), "\r\n"), nil
+	}
+
+	key, err := configSecretKey()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(str[4:])
+	if err != nil {
+		return "", err
+	}
+	gcm, err := configSecretGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", ErrConfigSecretInvalid
+	}
+	body, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// ConfigEncryptSecret 函数使用AES-GCM加密一个配置值，返回"enc:"为前缀的字符串，可以被ConfigParseSecrets解密。
+//
+// key长度为16、24、32字节，与环境变量EnvEudoreConfigKey的值相同，环境变量值使用"base64:"前缀表示base64编码的密钥。
+func ConfigEncryptSecret(key []byte, value string) (string, error) {
+	gcm, err := configSecretGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	return "enc:" + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+func configSecretKey() ([]byte, error) {
+	str := os.Getenv(EnvEudoreConfigKey)
+	if str == "" {
+		return nil, ErrConfigSecretKeyEmpty
+	}
+	if strings.HasPrefix(str, "base64:") {
+		return base64.StdEncoding.DecodeString(str[7:])
+	}
+	return []byte(str), nil
+}
+
+func configSecretGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 // ConfigParseWorkdir 函数初始化工作空间，从config获取workdir的值为工作空间，然后切换目录。
 func ConfigParseWorkdir(c Config) error {
 	dir := GetString(c.Get("workdir"))
@@ -387,6 +721,87 @@ func ConfigParseHelp(c Config) error {
 	return nil
 }
 
+// ConfigParseValidate 函数使用DefaultValidater验证配置结构体的validate tag，返回全部无效配置的路径。
+func ConfigParseValidate(c Config) error {
+	return NewConfigParseValidate(DefaultValidater)(c)
+}
+
+// NewConfigParseValidate 函数使用指定的Validater创建配置验证函数，例如app.Validater。
+//
+// 配置路径使用alias tag组成，会递归验证嵌套结构体和数组中的结构体，一次返回全部验证错误；
+// 不验证map中的数据，例如mods差异化配置；ConfigParseSecrets解析的键验证失败时不输出值。
+func NewConfigParseValidate(v Validater) ConfigParseFunc {
+	return func(c Config) error {
+		errs := &muliterror{}
+		sourcer, _ := c.(configSourcer)
+		configValidate(v, sourcer, errs, "", reflect.ValueOf(c.Get("")), make(map[uintptr]bool))
+		return errs.GetError()
+	}
+}
+
+func configValidate(v Validater, sourcer configSourcer, errs *muliterror, prefix string, iValue reflect.Value, repeat map[uintptr]bool) {
+	switch iValue.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		if iValue.IsNil() || repeat[iValue.Pointer()] {
+			return
+		}
+		repeat[iValue.Pointer()] = true
+	}
+
+	switch iValue.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !iValue.IsNil() {
+			configValidate(v, sourcer, errs, prefix, iValue.Elem(), repeat)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < iValue.Len(); i++ {
+			configValidate(v, sourcer, errs, configJoinKey(prefix, strconv.Itoa(i)), iValue.Index(i), repeat)
+		}
+	case reflect.Struct:
+		iType := iValue.Type()
+		for i := 0; i < iType.NumField(); i++ {
+			field := iType.Field(i)
+			name := field.Tag.Get(DefaultGetSetTags[0])
+			if field.PkgPath != "" || name == "-" {
+				continue
+			}
+			if name == "" && !field.Anonymous {
+				name = field.Name
+			}
+			key := configJoinKey(prefix, name)
+			for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+				if rule == "" {
+					continue
+				}
+				err := v.ValidateVar(configValidateValue(iValue.Field(i)), rule)
+				if err != nil {
+					// 验证错误包含值，secret键只输出规则。
+					if sourcer != nil && sourcer.isSecret(key) {
+						err = fmt.Errorf("validate secret value check rule %s fatal", rule)
+					}
+					errs.HandleError(fmt.Errorf(ErrFormatConfigValidate, key, err))
+				}
+			}
+			configValidate(v, sourcer, errs, key, iValue.Field(i), repeat)
+		}
+	}
+}
+
+// configValidateValue 函数将自定义的基础类型转换成int、uint、float64、string，用于匹配验证函数。
+func configValidateValue(iValue reflect.Value) interface{} {
+	switch iValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(iValue.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uint(iValue.Uint())
+	case reflect.Float32, reflect.Float64:
+		return iValue.Float()
+	case reflect.String:
+		return iValue.String()
+	}
+	return iValue.Interface()
+}
+
 type eachTags struct {
 	tag     string
 	Tags    []string
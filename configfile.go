@@ -0,0 +1,1093 @@
+package eudore
+
+// configfile 实现yaml和toml格式配置文件的解析，仅支持配置文件常用的语法子集。
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ConfigParseFile 函数解析配置文件，根据文件后缀选择格式，'.yaml'和'.yml'使用yaml，'.toml'使用toml，其他使用json。
+//
+// 按顺序读取'config'配置的路径，第一个存在的文件加载成功后结束解析。
+func ConfigParseFile(c Config) error {
+	return configParseFiles(c, func(c Config, path string, r io.Reader) (map[string]interface{}, error) {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			return configDecodeYAML(c, path, r)
+		case ".toml":
+			return configDecodeTOML(c, path, r)
+		default:
+			return configDecodeJSON(c, path, r)
+		}
+	})
+}
+
+// ConfigParseYAML 函数解析yaml文件配置，yaml数据使用alias tag转换到配置。
+//
+// 支持块/流格式的map和数组、注释、引号字符串和'|' '>'多行字符串，不支持锚点和多文档，仅解析第一个文档。
+func ConfigParseYAML(c Config) error {
+	return configParseFiles(c, configDecodeYAML)
+}
+
+// ConfigParseTOML 函数解析toml文件配置，toml数据使用alias tag转换到配置。
+//
+// 支持toml v1.0的表、数组表、内联表、多行字符串和时间，本地时间和日期保存为字符串。
+func ConfigParseTOML(c Config) error {
+	return configParseFiles(c, configDecodeTOML)
+}
+
+func configDecodeYAML(c Config, _ string, r io.Reader) (map[string]interface{}, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := decodeYAML(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return m, configConvertTo(c, m)
+}
+
+func configDecodeTOML(c Config, _ string, r io.Reader) (map[string]interface{}, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := decodeTOML(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return m, configConvertTo(c, m)
+}
+
+// configConvertTo 函数将解析的数据按顶级键写入配置，使用ConvertTo处理alias tag。
+func configConvertTo(c Config, data map[string]interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return ConvertTo(data, c.Get(""))
+}
+
+// configYAML 定义yaml解析状态，按行解析块结构。
+type configYAML struct {
+	lines []configYAMLLine
+	pos   int
+}
+
+type configYAMLLine struct {
+	num    int
+	indent int
+	text   string
+	raw    string
+}
+
+func decodeYAML(data string) (map[string]interface{}, error) {
+	p := &configYAML{}
+	for i, raw := range strings.Split(strings.Replace(data, "\r\n", "\n", -1), "\n") {
+		if strings.HasPrefix(raw, "%") && p.next() == nil {
+			raw = ""
+		}
+		if yamlIsMarker(raw, "...") || (yamlIsMarker(raw, "---") && p.next() != nil) {
+			// 仅解析第一个文档，忽略文档结束标记后的内容
+			break
+		}
+		if yamlIsMarker(raw, "---") {
+			raw = "   " + raw[3:]
+		}
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf(ErrFormatConfigParseYAML, i+1, "tab indentation")
+		}
+		p.lines = append(p.lines, configYAMLLine{
+			num:    i + 1,
+			indent: len(raw) - len(text),
+			text:   strings.TrimRight(yamlStripComment(text), " \t"),
+			raw:    raw,
+		})
+	}
+	p.pos = 0
+	line := p.next()
+	if line == nil {
+		return map[string]interface{}{}, nil
+	}
+	val, err := p.parseNode(0)
+	if err != nil {
+		return nil, err
+	}
+	if line = p.next(); line != nil {
+		return nil, fmt.Errorf(ErrFormatConfigParseYAML, line.num, "unexpected content "+line.text)
+	}
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf(ErrFormatConfigParseYAML, 1, "document root not is map")
+	}
+	return m, nil
+}
+
+// next 方法返回下一个非空行，不移动位置。
+func (p *configYAML) next() *configYAMLLine {
+	for p.pos < len(p.lines) {
+		if p.lines[p.pos].text != "" {
+			return &p.lines[p.pos]
+		}
+		p.pos++
+	}
+	return nil
+}
+
+func (p *configYAML) parseNode(indent int) (interface{}, error) {
+	line := p.next()
+	if line == nil || line.indent < indent {
+		return nil, nil
+	}
+	if yamlIsSeq(line.text) {
+		return p.parseSeq(line.indent)
+	}
+	if _, _, ok := yamlSplitKey(line.text); ok {
+		return p.parseMap(line.indent)
+	}
+	// 多行纯量或流格式
+	p.pos++
+	return p.parseInline(line.text, line.indent, line.num)
+}
+
+func (p *configYAML) parseMap(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for {
+		line := p.next()
+		if line == nil || line.indent < indent {
+			return m, nil
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf(ErrFormatConfigParseYAML, line.num, "bad indentation of a mapping entry")
+		}
+		if yamlIsSeq(line.text) {
+			return m, nil
+		}
+		key, val, ok := yamlSplitKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf(ErrFormatConfigParseYAML, line.num, "can not read a block mapping entry "+line.text)
+		}
+		if _, _, ok := yamlSplitKey(val); ok {
+			return nil, fmt.Errorf(ErrFormatConfigParseYAML, line.num, "mapping values are not allowed in this context")
+		}
+		p.pos++
+		v, err := p.parseValue(val, indent, line.num, true)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+func (p *configYAML) parseSeq(indent int) (interface{}, error) {
+	var list []interface{}
+	for {
+		line := p.next()
+		if line == nil || line.indent < indent || !yamlIsSeq(line.text) {
+			return list, nil
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf(ErrFormatConfigParseYAML, line.num, "bad indentation of a sequence entry")
+		}
+		rest := strings.TrimLeft(line.text[1:], " ")
+		_, _, iskey := yamlSplitKey(rest)
+		if rest != "" && (yamlIsSeq(rest) || (iskey && rest[0] != '[' && rest[0] != '{')) {
+			// "- key: val"和"- - val"作为缩进更深的子节点继续解析
+			line.indent += len(line.text) - len(rest)
+			line.text = rest
+			v, err := p.parseNode(line.indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			continue
+		}
+		p.pos++
+		v, err := p.parseValue(rest, indent, line.num, false)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+// parseValue 方法解析键或数组元素的值，值为空时解析下一级节点。
+func (p *configYAML) parseValue(val string, indent, num int, inmap bool) (interface{}, error) {
+	if val == "" {
+		line := p.next()
+		if line == nil {
+			return nil, nil
+		}
+		if line.indent > indent {
+			return p.parseNode(line.indent)
+		}
+		if inmap && line.indent == indent && yamlIsSeq(line.text) {
+			return p.parseSeq(indent)
+		}
+		return nil, nil
+	}
+	if val[0] == '|' || val[0] == '>' {
+		return p.parseBlock(val, indent, num)
+	}
+	return p.parseInline(val, indent, num)
+}
+
+// parseInline 方法解析单行值，未闭合的流格式或引号会合并后续行。
+func (p *configYAML) parseInline(val string, indent, num int) (interface{}, error) {
+	if yamlIsProperty(val) {
+		return nil, fmt.Errorf(ErrFormatConfigParseYAML, num, "anchors, aliases and tags are not supported "+val)
+	}
+	for !yamlIsClosed(val) {
+		if p.pos >= len(p.lines) {
+			return nil, fmt.Errorf(ErrFormatConfigParseYAML, num, "unclosed value "+val)
+		}
+		if p.lines[p.pos].text != "" {
+			val += " " + p.lines[p.pos].text
+		}
+		p.pos++
+	}
+	if val[0] != '[' && val[0] != '{' && val[0] != '"' && val[0] != '\'' {
+		// 纯量允许多行，使用空格连接
+		for line := p.next(); line != nil && line.indent > indent; line = p.next() {
+			if _, _, ok := yamlSplitKey(line.text); ok || yamlIsSeq(line.text) {
+				break
+			}
+			val += " " + line.text
+			p.pos++
+		}
+		return yamlScalar(val), nil
+	}
+	s := &configYAMLFlow{data: val}
+	v, err := s.parse()
+	if err == nil {
+		s.skip()
+		if s.pos < len(s.data) {
+			err = fmt.Errorf("unexpected content %s", s.data[s.pos:])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf(ErrFormatConfigParseYAML, num, err.Error())
+	}
+	return v, nil
+}
+
+// parseBlock 方法解析'|'和'>'开始的多行字符串。
+func (p *configYAML) parseBlock(header string, indent, num int) (interface{}, error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	for _, c := range []byte(header[1:]) {
+		switch {
+		case c == '-' || c == '+':
+			chomp = c
+		case c >= '1' && c <= '9', c == ' ':
+		default:
+			return nil, fmt.Errorf(ErrFormatConfigParseYAML, num, "invalid block scalar header "+header)
+		}
+	}
+
+	var lines []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := p.lines[p.pos].raw
+		text := strings.TrimLeft(raw, " ")
+		if text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		ind := len(raw) - len(text)
+		if ind <= indent || (blockIndent != -1 && ind < blockIndent) {
+			break
+		}
+		if blockIndent == -1 {
+			blockIndent = ind
+		}
+		lines = append(lines, raw[blockIndent:])
+	}
+
+	// 去除尾部空行
+	n := len(lines)
+	for n > 0 && lines[n-1] == "" {
+		n--
+	}
+	tail := lines[n:]
+	lines = lines[:n]
+	var str string
+	if folded {
+		var b bytes.Buffer
+		for i, line := range lines {
+			switch {
+			case i == 0:
+			case line == "" || strings.HasPrefix(line, " "):
+				b.WriteByte('\n')
+			case lines[i-1] == "" || strings.HasPrefix(lines[i-1], " "):
+				if lines[i-1] != "" {
+					b.WriteByte('\n')
+				}
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(line)
+		}
+		str = b.String()
+	} else {
+		str = strings.Join(lines, "\n")
+	}
+	switch chomp {
+	case '-':
+	case '+':
+		str += "\n" + strings.Repeat("\n", len(tail))
+	default:
+		if len(lines) > 0 {
+			str += "\n"
+		}
+	}
+	return str, nil
+}
+
+// yamlIsMarker 函数检查行是否是文档开始标记"---"或结束标记"..."。
+func yamlIsMarker(raw, marker string) bool {
+	return strings.HasPrefix(raw, marker) && (len(raw) == 3 || raw[3] == ' ' || raw[3] == '\t')
+}
+
+// yamlIsProperty 函数检查值是否以锚点'&'、别名'*'或标签'!'开始，这些特性不被支持。
+func yamlIsProperty(val string) bool {
+	return val != "" && strings.IndexByte("&*!", val[0]) != -1
+}
+
+func yamlIsSeq(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// yamlSplitKey 函数分割"key: value"，忽略引号和流格式内的冒号。
+func yamlSplitKey(text string) (string, string, bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' || yamlIsSeq(text) {
+		return "", "", false
+	}
+	var quote byte
+	start := 0
+	if text[0] == '"' || text[0] == '\'' {
+		quote = text[0]
+		start = 1
+	}
+	for i := start; i < len(text); i++ {
+		switch {
+		case quote != 0:
+			if text[i] == '\\' && quote == '"' {
+				i++
+			} else if text[i] == quote {
+				if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+					i++
+					continue
+				}
+				quote = 0
+			}
+		case text[i] == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			key := strings.TrimSpace(text[:i])
+			if start == 1 {
+				key = GetString(yamlScalar(key))
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// yamlStripComment 函数删除行尾注释，'#'需要在行首或者空白之后。
+func yamlStripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" :-[{,", text[i-1]) != -1 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// yamlIsClosed 函数检查流格式括号和引号是否闭合。
+func yamlIsClosed(text string) bool {
+	if text == "" || strings.IndexByte("[{\"'", text[0]) == -1 {
+		return true
+	}
+	var quote byte
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return quote == 0 && depth <= 0
+}
+
+// yamlScalar 函数将纯量字符串转换成nil、bool、int、float64或string。
+func yamlScalar(s string) interface{} {
+	s = strings.TrimSpace(s)
+	if len(s) > 1 {
+		switch {
+		case s[0] == '"' && s[len(s)-1] == '"':
+			str, err := strconv.Unquote(s)
+			if err == nil {
+				return str
+			}
+			return s[1 : len(s)-1]
+		case s[0] == '\'' && s[len(s)-1] == '\'':
+			return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+		}
+	}
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	if s[0] == '+' || s[0] == '-' || s[0] == '.' || (s[0] >= '0' && s[0] <= '9') {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return int(n)
+		}
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o") {
+			if n, err := strconv.ParseInt(s[2:], map[byte]int{'x': 16, 'o': 8}[s[1]], 64); err == nil {
+				return int(n)
+			}
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	}
+	return s
+}
+
+// configYAMLFlow 定义yaml流格式解析，解析"[a, b]"和"{a: 1}"。
+type configYAMLFlow struct {
+	data string
+	pos  int
+}
+
+func (s *configYAMLFlow) skip() {
+	for s.pos < len(s.data) && (s.data[s.pos] == ' ' || s.data[s.pos] == '\t') {
+		s.pos++
+	}
+}
+
+func (s *configYAMLFlow) parse() (interface{}, error) {
+	s.skip()
+	if s.pos >= len(s.data) {
+		return nil, nil
+	}
+	switch s.data[s.pos] {
+	case '[':
+		s.pos++
+		var list []interface{}
+		for {
+			s.skip()
+			if s.pos < len(s.data) && s.data[s.pos] == ']' {
+				s.pos++
+				return list, nil
+			}
+			v, err := s.parse()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			if err = s.sep(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		s.pos++
+		m := make(map[string]interface{})
+		for {
+			s.skip()
+			if s.pos < len(s.data) && s.data[s.pos] == '}' {
+				s.pos++
+				return m, nil
+			}
+			key, err := s.parse()
+			if err != nil {
+				return nil, err
+			}
+			s.skip()
+			if s.pos >= len(s.data) || s.data[s.pos] != ':' {
+				return nil, fmt.Errorf("missed ':' in flow mapping")
+			}
+			s.pos++
+			val, err := s.parse()
+			if err != nil {
+				return nil, err
+			}
+			m[GetString(key)] = val
+			if err = s.sep('}'); err != nil {
+				return nil, err
+			}
+		}
+	case '"', '\'':
+		quote := s.data[s.pos]
+		for i := s.pos + 1; i < len(s.data); i++ {
+			if s.data[i] == '\\' && quote == '"' {
+				i++
+			} else if s.data[i] == quote {
+				if quote == '\'' && i+1 < len(s.data) && s.data[i+1] == '\'' {
+					i++
+					continue
+				}
+				str := s.data[s.pos : i+1]
+				s.pos = i + 1
+				return yamlScalar(str), nil
+			}
+		}
+		return nil, fmt.Errorf("unclosed quoted scalar")
+	default:
+		if yamlIsProperty(s.data[s.pos:]) {
+			return nil, fmt.Errorf("anchors, aliases and tags are not supported")
+		}
+		start := s.pos
+		for s.pos < len(s.data) && strings.IndexByte(",]}", s.data[s.pos]) == -1 &&
+			!(s.data[s.pos] == ':' && (s.pos+1 == len(s.data) || s.data[s.pos+1] == ' ')) {
+			s.pos++
+		}
+		return yamlScalar(s.data[start:s.pos]), nil
+	}
+}
+
+// sep 方法读取流格式的分隔符','或结束符。
+func (s *configYAMLFlow) sep(end byte) error {
+	s.skip()
+	if s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ',':
+			s.pos++
+			return nil
+		case end:
+			return nil
+		}
+	}
+	return fmt.Errorf("missed ',' or '%c' in flow collection", end)
+}
+
+// configTOML 定义toml解析状态，tables记录表的定义方式，用于检查重复定义的表。
+type configTOML struct {
+	data    string
+	pos     int
+	line    int
+	root    map[string]interface{}
+	current string
+	tables  map[string]int
+}
+
+// 定义toml表的定义方式，表头定义的表和点分割键定义的表不允许再次使用表头定义，内联表不允许扩展。
+const (
+	tomlTableImplicit = iota
+	tomlTableHeader
+	tomlTableDotted
+	tomlTableInline
+)
+
+func decodeTOML(data string) (map[string]interface{}, error) {
+	p := &configTOML{
+		data:   strings.Replace(data, "\r\n", "\n", -1),
+		line:   1,
+		root:   make(map[string]interface{}),
+		tables: make(map[string]int),
+	}
+	err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf(ErrFormatConfigParseTOML, p.line, err.Error())
+	}
+	return p.root, nil
+}
+
+func (p *configTOML) parse() error {
+	current := p.root
+	for {
+		p.skipBlank(true)
+		if p.pos >= len(p.data) {
+			return nil
+		}
+		if p.data[p.pos] == '[' {
+			array := strings.HasPrefix(p.data[p.pos:], "[[")
+			p.pos++
+			if array {
+				p.pos++
+			}
+			keys, err := p.parseKeys()
+			if err != nil {
+				return err
+			}
+			end := "]"
+			if array {
+				end = "]]"
+			}
+			if !strings.HasPrefix(p.data[p.pos:], end) {
+				return fmt.Errorf("missed '%s' in table header", end)
+			}
+			p.pos += len(end)
+			current, err = p.openTable(keys, array)
+			if err != nil {
+				return err
+			}
+			p.current = strings.Join(keys, ".")
+		} else {
+			keys, err := p.parseKeys()
+			if err != nil {
+				return err
+			}
+			if p.pos >= len(p.data) || p.data[p.pos] != '=' {
+				return fmt.Errorf("missed '=' after key %s", strings.Join(keys, "."))
+			}
+			p.pos++
+			p.skipBlank(false)
+			val, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			if err = p.defineKeys(keys, val); err != nil {
+				return err
+			}
+			if err = tomlSetValue(current, keys, val); err != nil {
+				return err
+			}
+		}
+		p.skipBlank(false)
+		if p.pos < len(p.data) && p.data[p.pos] != '\n' {
+			return fmt.Errorf("unexpected content %q", p.data[p.pos:p.lineEnd()])
+		}
+	}
+}
+
+// openTable 方法创建或打开一个表，数组表会追加一个新表。
+func (p *configTOML) openTable(keys []string, array bool) (map[string]interface{}, error) {
+	m := p.root
+	for i, key := range keys {
+		last := i == len(keys)-1
+		name := strings.Join(keys[:i+1], ".")
+		if p.tables[name] == tomlTableInline {
+			return nil, fmt.Errorf("inline table %s can not be extended", name)
+		}
+		switch val := m[key].(type) {
+		case nil:
+			if last && array {
+				table := make(map[string]interface{})
+				m[key] = []interface{}{table}
+				return table, nil
+			}
+			table := make(map[string]interface{})
+			m[key] = table
+			m = table
+		case map[string]interface{}:
+			if last && (array || p.tables[name] != tomlTableImplicit) {
+				return nil, fmt.Errorf("duplicate table %s", name)
+			}
+			m = val
+		case []interface{}:
+			table, ok := val[len(val)-1].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("key %s not is array of tables", key)
+			}
+			if last {
+				if !array {
+					return nil, fmt.Errorf("duplicate table %s", strings.Join(keys, "."))
+				}
+				table = make(map[string]interface{})
+				m[key] = append(val, table)
+				// 新的数组元素重新记录子表
+				for sub := range p.tables {
+					if strings.HasPrefix(sub, name+".") {
+						delete(p.tables, sub)
+					}
+				}
+			}
+			m = table
+		default:
+			return nil, fmt.Errorf("key %s already defined as value", key)
+		}
+	}
+	p.tables[strings.Join(keys, ".")] = tomlTableHeader
+	return m, nil
+}
+
+// defineKeys 方法记录点分割键隐式定义的表和内联表，点分割键不允许扩展表头定义的表和内联表。
+func (p *configTOML) defineKeys(keys []string, val interface{}) error {
+	name := p.current
+	for i, key := range keys {
+		if name != "" {
+			name += "."
+		}
+		name += key
+		if i == len(keys)-1 {
+			break
+		}
+		switch p.tables[name] {
+		case tomlTableHeader, tomlTableInline:
+			return fmt.Errorf("table %s already defined", name)
+		}
+		p.tables[name] = tomlTableDotted
+	}
+	if _, ok := val.(map[string]interface{}); ok {
+		p.tables[name] = tomlTableInline
+	}
+	return nil
+}
+
+func tomlSetValue(m map[string]interface{}, keys []string, val interface{}) error {
+	for _, key := range keys[:len(keys)-1] {
+		switch v := m[key].(type) {
+		case nil:
+			table := make(map[string]interface{})
+			m[key] = table
+			m = table
+		case map[string]interface{}:
+			m = v
+		default:
+			return fmt.Errorf("key %s already defined as value", key)
+		}
+	}
+	key := keys[len(keys)-1]
+	if _, ok := m[key]; ok {
+		return fmt.Errorf("duplicate key %s", key)
+	}
+	m[key] = val
+	return nil
+}
+
+func (p *configTOML) lineEnd() int {
+	end := strings.IndexByte(p.data[p.pos:], '\n')
+	if end == -1 {
+		return len(p.data)
+	}
+	return p.pos + end
+}
+
+// skipBlank 方法跳过空白和注释，newline为true时同时跳过换行。
+func (p *configTOML) skipBlank(newline bool) {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\r':
+		case '\n':
+			if !newline {
+				return
+			}
+			p.line++
+		case '#':
+			p.pos = p.lineEnd()
+			continue
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+// parseKeys 方法解析点分割的键，支持裸键和引号键。
+func (p *configTOML) parseKeys() ([]string, error) {
+	var keys []string
+	for {
+		p.skipBlank(false)
+		if p.pos >= len(p.data) {
+			return nil, fmt.Errorf("unexpected end of key")
+		}
+		var key string
+		switch p.data[p.pos] {
+		case '"', '\'':
+			val, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			key = val
+		default:
+			start := p.pos
+			for p.pos < len(p.data) && tomlIsBareKey(p.data[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, fmt.Errorf("invalid key character %q", p.data[p.pos])
+			}
+			key = p.data[start:p.pos]
+		}
+		keys = append(keys, key)
+		p.skipBlank(false)
+		if p.pos < len(p.data) && p.data[p.pos] == '.' {
+			p.pos++
+			continue
+		}
+		return keys, nil
+	}
+}
+
+func tomlIsBareKey(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-'
+}
+
+func (p *configTOML) parseValue() (interface{}, error) {
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("missed value")
+	}
+	switch p.data[p.pos] {
+	case '"', '\'':
+		return p.parseString()
+	case '[':
+		p.pos++
+		list := []interface{}{}
+		for {
+			p.skipBlank(true)
+			if p.pos < len(p.data) && p.data[p.pos] == ']' {
+				p.pos++
+				return list, nil
+			}
+			val, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+			p.skipBlank(true)
+			if p.pos < len(p.data) && p.data[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.data) || p.data[p.pos] != ']' {
+				return nil, fmt.Errorf("missed ',' or ']' in array")
+			}
+		}
+	case '{':
+		p.pos++
+		m := make(map[string]interface{})
+		p.skipBlank(false)
+		if p.pos < len(p.data) && p.data[p.pos] == '}' {
+			p.pos++
+			return m, nil
+		}
+		for {
+			keys, err := p.parseKeys()
+			if err != nil {
+				return nil, err
+			}
+			if p.pos >= len(p.data) || p.data[p.pos] != '=' {
+				return nil, fmt.Errorf("missed '=' in inline table")
+			}
+			p.pos++
+			p.skipBlank(false)
+			val, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			if err = tomlSetValue(m, keys, val); err != nil {
+				return nil, err
+			}
+			p.skipBlank(false)
+			if p.pos < len(p.data) && p.data[p.pos] == '}' {
+				p.pos++
+				return m, nil
+			}
+			if p.pos >= len(p.data) || p.data[p.pos] != ',' {
+				return nil, fmt.Errorf("missed ',' or '}' in inline table")
+			}
+			p.pos++
+		}
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && strings.IndexByte(" \t\r\n,]}#", p.data[p.pos]) == -1 {
+		p.pos++
+	}
+	// 日期和时间使用空格分隔
+	if p.pos-start == 10 && p.pos+1 < len(p.data) && p.data[p.pos] == ' ' && p.data[p.pos+1] >= '0' && p.data[p.pos+1] <= '9' {
+		p.pos++
+		for p.pos < len(p.data) && strings.IndexByte(" \t\r\n,]}#", p.data[p.pos]) == -1 {
+			p.pos++
+		}
+	}
+	return tomlScalar(p.data[start:p.pos])
+}
+
+// tomlScalar 函数解析bool、数字和时间，本地日期时间返回字符串。
+func tomlScalar(s string) (interface{}, error) {
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	case "":
+		return nil, fmt.Errorf("missed value")
+	}
+	if len(s) >= 8 && (s[4] == '-' || s[2] == ':') {
+		str := strings.Replace(s, " ", "T", 1)
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02", "15:04:05.999999999"} {
+			if t, err := time.Parse(layout, str); err == nil {
+				if layout == time.RFC3339Nano {
+					return t, nil
+				}
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid datetime %s", s)
+	}
+	num := strings.Replace(s, "_", "", -1)
+	if len(num) > 2 && num[0] == '0' {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[num[1]]
+		if base != 0 {
+			n, err := strconv.ParseInt(num[2:], base, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer %s", s)
+			}
+			return int(n), nil
+		}
+	}
+	if n, err := strconv.ParseInt(num, 10, 64); err == nil {
+		return int(n), nil
+	}
+	if n, err := strconv.ParseFloat(num, 64); err == nil {
+		return n, nil
+	}
+	return nil, fmt.Errorf("invalid value %s", s)
+}
+
+// parseString 方法解析基本、字面量和多行字符串。
+func (p *configTOML) parseString() (string, error) {
+	quote := p.data[p.pos]
+	multi := strings.HasPrefix(p.data[p.pos:], strings.Repeat(string(quote), 3))
+	if multi {
+		p.pos += 3
+		// 忽略开始引号后的换行
+		if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+			p.pos++
+			p.line++
+		}
+	} else {
+		p.pos++
+	}
+
+	var b bytes.Buffer
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case c == quote:
+			if !multi {
+				p.pos++
+				return b.String(), nil
+			}
+			if strings.HasPrefix(p.data[p.pos:], strings.Repeat(string(quote), 3)) {
+				// 多行字符串结束前最多允许两个引号
+				for i := 0; i < 2 && strings.HasPrefix(p.data[p.pos+1:], strings.Repeat(string(quote), 3)); i++ {
+					b.WriteByte(quote)
+					p.pos++
+				}
+				p.pos += 3
+				return b.String(), nil
+			}
+			b.WriteByte(c)
+			p.pos++
+		case c == '\n':
+			if !multi {
+				return "", fmt.Errorf("unclosed string")
+			}
+			p.line++
+			b.WriteByte(c)
+			p.pos++
+		case c == '\\' && quote == '"':
+			if err := p.parseEscape(&b, multi); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unclosed string")
+}
+
+func (p *configTOML) parseEscape(b *bytes.Buffer, multi bool) error {
+	p.pos++
+	if p.pos >= len(p.data) {
+		return fmt.Errorf("unclosed string")
+	}
+	c := p.data[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.data) {
+			return fmt.Errorf("invalid unicode escape")
+		}
+		n, err := strconv.ParseUint(p.data[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(n)) {
+			return fmt.Errorf("invalid unicode escape %s", p.data[p.pos:p.pos+size])
+		}
+		b.WriteRune(rune(n))
+		p.pos += size
+	case ' ', '\t', '\r', '\n':
+		// 多行字符串行尾反斜杠删除后续空白
+		if !multi {
+			return fmt.Errorf("invalid escape '\\%c'", c)
+		}
+		p.pos--
+		for p.pos < len(p.data) && strings.IndexByte(" \t\r\n", p.data[p.pos]) != -1 {
+			if p.data[p.pos] == '\n' {
+				p.line++
+			}
+			p.pos++
+		}
+	default:
+		return fmt.Errorf("invalid escape '\\%c'", c)
+	}
+	return nil
+}
@@ -0,0 +1,384 @@
+package eudore
+
+// configwatch 实现配置热加载和配置变化订阅。
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ConfigWatcher 定义配置热加载对象，在配置文件修改或收到SIGHUP信号时重新执行Config的配置解析函数。
+//
+// 解析使用配置数据的副本，解析成功后写回配置并按键前缀通知订阅者，解析失败保留之前的配置。
+//
+// 支持ConfigMap和ConfigEudore使用副本解析，其他Config实现会直接解析，失败后使用Set("", data)恢复数据。
+//
+// ConfigMap解析成功后整体替换数据；ConfigEudore持有配置写锁将数据写回原始对象，
+// 需要使用Config.Get或订阅者读取配置，直接读取原始结构体需要结构体实现RLock/RUnlock并持有读锁，否则会产生数据竞争。
+type ConfigWatcher struct {
+	Config      Config        `alias:"config"`
+	Interval    time.Duration `alias:"interval"`
+	mu          sync.Mutex
+	subscribers []configSubscriber
+	modtimes    map[string]time.Time
+}
+
+type configSubscriber struct {
+	prefix string
+	fn     func(string, interface{}, interface{})
+}
+
+// NewConfigWatcher 函数创建一个配置热加载对象，默认检测文件间隔为DefaultConfigWatchInterval。
+func NewConfigWatcher(c Config) *ConfigWatcher {
+	return &ConfigWatcher{
+		Config:   c,
+		Interval: DefaultConfigWatchInterval,
+		modtimes: make(map[string]time.Time),
+	}
+}
+
+// Subscribe 方法订阅一个键前缀的配置变化，前缀为空订阅全部配置，每个变化的键调用一次fn。
+//
+// 前缀"logger"匹配键"logger"和"logger.level"，不匹配"loggers"。
+func (w *ConfigWatcher) Subscribe(prefix string, fn func(key string, oldval, newval interface{})) {
+	w.mu.Lock()
+	w.subscribers = append(w.subscribers, configSubscriber{prefix, fn})
+	w.mu.Unlock()
+}
+
+// Reload 方法重新执行配置解析函数，成功后对比新旧配置通知订阅者。
+func (w *ConfigWatcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	olds, news, err := configParseSnapshot(w.Config)
+	if err != nil {
+		configPrint(w.Config, "config reload error, keep previous config: ", err)
+		return err
+	}
+	configPrint(w.Config, "config reload success")
+	for _, key := range configDiffKeys(olds, news) {
+		for _, sub := range w.subscribers {
+			if sub.prefix == "" || key == sub.prefix || strings.HasPrefix(key, sub.prefix+".") {
+				sub.fn(key, olds[key], news[key])
+			}
+		}
+	}
+	return nil
+}
+
+// Watch 方法阻塞检测'config'配置的文件修改时间和SIGHUP信号，发生变化时调用Reload，直到ctx结束。
+func (w *ConfigWatcher) Watch(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultConfigWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	w.checkModtime()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			configPrint(w.Config, "config receive SIGHUP signal")
+			w.Reload()
+		case <-ticker.C:
+			if w.checkModtime() {
+				w.Reload()
+			}
+		}
+	}
+}
+
+// checkModtime 方法检查配置文件修改时间，返回是否有文件变化。
+func (w *ConfigWatcher) checkModtime() bool {
+	changed := false
+	for _, path := range GetStrings(w.Config.Get("config")) {
+		var modtime time.Time
+		stat, err := os.Stat(path)
+		if err == nil {
+			modtime = stat.ModTime()
+		}
+		last, ok := w.modtimes[path]
+		if ok && !last.Equal(modtime) {
+			configPrint(w.Config, "config file changed: ", path)
+			changed = true
+		}
+		w.modtimes[path] = modtime
+	}
+	return changed
+}
+
+// configParseSnapshot 函数使用配置数据的副本执行解析，成功后写回数据，返回新旧数据的展开键值。
+func configParseSnapshot(c Config) (map[string]interface{}, map[string]interface{}, error) {
+	switch conf := c.(type) {
+	case *configMap:
+		conf.Locker.RLock()
+		keys := configClone(reflect.ValueOf(conf.Keys)).Interface().(map[string]interface{})
+		olds := configFlatten(conf.Keys)
+		tmp := &configMap{
+			Keys: keys, Print: conf.Print, funcs: conf.funcs,
+			sources: configCloneSources(conf.sources), secrets: append([]string(nil), conf.secrets...),
+		}
+		conf.Locker.RUnlock()
+		if err := tmp.Parse(); err != nil {
+			return nil, nil, err
+		}
+		conf.Locker.Lock()
+		conf.Keys = tmp.Keys
+		conf.sources = tmp.sources
+		conf.secrets = tmp.secrets
+		news := configFlatten(conf.Keys)
+		conf.Locker.Unlock()
+		return olds, news, nil
+	case *configEudore:
+		conf.RLock()
+		keys := configClone(reflect.ValueOf(conf.Keys))
+		olds := configFlatten(conf.Keys)
+		tmp := &configEudore{
+			Print: conf.Print, funcs: conf.funcs, sources: configCloneSources(conf.sources),
+			secrets: append([]string(nil), conf.secrets...), configRLocker: new(sync.RWMutex),
+		}
+		if keys.IsValid() {
+			tmp.Keys = keys.Interface()
+		}
+		conf.RUnlock()
+		if err := tmp.Parse(); err != nil {
+			return nil, nil, err
+		}
+		conf.Lock()
+		if !configCopyBack(reflect.ValueOf(conf.Keys), reflect.ValueOf(tmp.Keys)) {
+			conf.Keys = tmp.Keys
+		}
+		conf.sources = tmp.sources
+		conf.secrets = tmp.secrets
+		news := configFlatten(conf.Keys)
+		conf.Unlock()
+		return olds, news, nil
+	default:
+		data := c.Get("")
+		olds := configFlatten(data)
+		backup := configClone(reflect.ValueOf(data))
+		if err := c.Parse(); err != nil {
+			if backup.IsValid() {
+				c.Set("", backup.Interface())
+			}
+			return nil, nil, err
+		}
+		return olds, configFlatten(c.Get("")), nil
+	}
+}
+
+func configCloneSources(sources map[string]string) map[string]string {
+	if sources == nil {
+		return nil
+	}
+	data := make(map[string]string, len(sources))
+	for k, v := range sources {
+		data[k] = v
+	}
+	return data
+}
+
+// configDiffKeys 函数返回新旧数据中值不同的键。
+func configDiffKeys(olds, news map[string]interface{}) []string {
+	var keys []string
+	for key, val := range olds {
+		newval, ok := news[key]
+		if !ok || !reflect.DeepEqual(val, newval) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range news {
+		if _, ok := olds[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+var typeLocker = reflect.TypeOf((*sync.Locker)(nil)).Elem()
+
+func configIsLocker(iType reflect.Type) bool {
+	return iType.Implements(typeLocker) || reflect.PtrTo(iType).Implements(typeLocker)
+}
+
+// configClone 函数深拷贝map、slice、指针和结构体导出属性，结构体中的锁会重置为零值。
+func configClone(iValue reflect.Value) reflect.Value {
+	if !iValue.IsValid() {
+		return iValue
+	}
+	switch iValue.Kind() {
+	case reflect.Ptr:
+		if iValue.IsNil() || iValue.Type().Implements(typeLocker) {
+			return iValue
+		}
+		newValue := reflect.New(iValue.Type().Elem())
+		newValue.Elem().Set(configClone(iValue.Elem()))
+		return newValue
+	case reflect.Interface:
+		if iValue.IsNil() {
+			return iValue
+		}
+		newValue := reflect.New(iValue.Type()).Elem()
+		newValue.Set(configClone(iValue.Elem()))
+		return newValue
+	case reflect.Map:
+		if iValue.IsNil() {
+			return iValue
+		}
+		newValue := reflect.MakeMap(iValue.Type())
+		for _, key := range iValue.MapKeys() {
+			newValue.SetMapIndex(key, configClone(iValue.MapIndex(key)))
+		}
+		return newValue
+	case reflect.Slice:
+		if iValue.IsNil() {
+			return iValue
+		}
+		newValue := reflect.MakeSlice(iValue.Type(), iValue.Len(), iValue.Len())
+		for i := 0; i < iValue.Len(); i++ {
+			newValue.Index(i).Set(configClone(iValue.Index(i)))
+		}
+		return newValue
+	case reflect.Struct:
+		newValue := reflect.New(iValue.Type()).Elem()
+		newValue.Set(iValue)
+		for i := 0; i < iValue.NumField(); i++ {
+			field := newValue.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if configIsLocker(field.Type()) && field.Kind() != reflect.Ptr {
+				field.Set(reflect.Zero(field.Type()))
+			} else {
+				field.Set(configClone(iValue.Field(i)))
+			}
+		}
+		return newValue
+	default:
+		return iValue
+	}
+}
+
+// configCopyBack 函数将解析后的数据写回原始对象，保留原始指针和结构体中的锁，无法写回返回false。
+func configCopyBack(dst, src reflect.Value) bool {
+	if !dst.IsValid() || !src.IsValid() || dst.Type() != src.Type() {
+		return false
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() || src.IsNil() || dst.Elem().Kind() != reflect.Struct {
+			return false
+		}
+		dst, src = dst.Elem(), src.Elem()
+		for i := 0; i < dst.NumField(); i++ {
+			field := dst.Field(i)
+			if field.CanSet() && !configIsLocker(field.Type()) {
+				field.Set(src.Field(i))
+			}
+		}
+		return true
+	case reflect.Map:
+		if dst.IsNil() {
+			return false
+		}
+		for _, key := range dst.MapKeys() {
+			dst.SetMapIndex(key, reflect.Value{})
+		}
+		for _, key := range src.MapKeys() {
+			dst.SetMapIndex(key, src.MapIndex(key))
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// configFlatten 函数将配置数据展开为'.'连接的键和值，结构体使用alias tag作为键名。
+func configFlatten(data interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+	configFlattenValue(values, "", reflect.ValueOf(data))
+	return values
+}
+
+func configFlattenValue(values map[string]interface{}, prefix string, iValue reflect.Value) {
+	switch iValue.Kind() {
+	case reflect.Invalid:
+		if prefix != "" {
+			values[prefix] = nil
+		}
+	case reflect.Ptr, reflect.Interface:
+		if iValue.IsNil() {
+			values[prefix] = nil
+			return
+		}
+		configFlattenValue(values, prefix, iValue.Elem())
+	case reflect.Map:
+		if iValue.Type().Key().Kind() != reflect.String {
+			values[prefix] = iValue.Interface()
+			return
+		}
+		for _, key := range iValue.MapKeys() {
+			configFlattenValue(values, configJoinKey(prefix, key.String()), iValue.MapIndex(key))
+		}
+	case reflect.Struct:
+		iType := iValue.Type()
+		exported := false
+		for i := 0; i < iType.NumField(); i++ {
+			field := iType.Field(i)
+			if field.PkgPath != "" || configIsLocker(field.Type) {
+				continue
+			}
+			exported = true
+			kind := field.Type.Kind()
+			name := field.Tag.Get(DefaultGetSetTags[0])
+			if name == "-" || kind == reflect.Func || kind == reflect.Chan {
+				continue
+			}
+			if name == "" && !field.Anonymous {
+				name = field.Name
+			}
+			configFlattenValue(values, configJoinKey(prefix, name), iValue.Field(i))
+		}
+		// time.Time等没有导出属性的结构体作为值
+		if !exported && iValue.CanInterface() {
+			values[prefix] = iValue.Interface()
+		}
+	case reflect.Func, reflect.Chan:
+	default:
+		if iValue.CanInterface() {
+			values[prefix] = iValue.Interface()
+		}
+	}
+}
+
+// configFlattenKeys 函数返回配置数据展开后的全部键。
+func configFlattenKeys(data interface{}) []string {
+	if data == nil {
+		return nil
+	}
+	values := configFlatten(data)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func configJoinKey(prefix, key string) string {
+	if prefix == "" || key == "" {
+		return prefix + key
+	}
+	return prefix + "." + key
+}
@@ -15,8 +15,16 @@ type contextKey struct {
 var (
 	// AppContextKey 定义从context.Value中获取app实例对象的key，如果app支持的话。
 	AppContextKey = &contextKey{"app"}
+	// TraceContextKey 定义从context.Value中获取请求*TraceSpan的key。
+	TraceContextKey = &contextKey{"trace"}
 	// DefaultBodyMaxMemory 默认Body解析占用内存。
 	DefaultBodyMaxMemory int64 = 32 << 20 // 32 MB
+	// DefaultBinderRegistry 定义BindDefault使用的Binder注册表。
+	DefaultBinderRegistry = newBinderRegistryDefault()
+	// DefaultBindStreamMaxBytes 定义BindStream默认允许的body最大长度。
+	DefaultBindStreamMaxBytes int64 = 1 << 30 // 1 GB
+	// DefaultBindStreamMaxItems 定义BindStream默认允许的最大元素数量。
+	DefaultBindStreamMaxItems = 1000000
 	// DefaultGetSetTags 定义Get/Set函数使用的默认tag。
 	DefaultGetSetTags = []string{"alias"}
 	// DefaultConvertTags 定义默认转换使用的结构体tags。
@@ -25,8 +33,35 @@ var (
 	DefaultConvertFormTags = []string{"form", "alias"}
 	// DefaultConvertURLTags 定义bind url使用tags。
 	DefaultConvertURLTags = []string{"url", "alias"}
+	// DefaultProtobufMarshal 定义RenderProtobuf使用的protobuf编码函数，默认使用消息的Marshal方法。
+	//
+	// google.golang.org/protobuf生成的消息没有Marshal方法，默认函数会返回错误，
+	// 需要设置为func(i interface{}) ([]byte, error) { return proto.Marshal(i.(proto.Message)) }。
+	DefaultProtobufMarshal = protobufMarshal
+	// DefaultProtobufUnmarshal 定义BindProtobuf使用的protobuf解码函数，默认使用消息的Unmarshal方法。
+	//
+	// google.golang.org/protobuf生成的消息没有Unmarshal方法，需要设置为proto.Unmarshal的封装。
+	DefaultProtobufUnmarshal = protobufUnmarshal
+	// DefaultRendererRegistry 定义RenderDefault使用的Renderer注册表。
+	DefaultRendererRegistry = newRendererRegistryDefault()
 	// DefaultRecoverDepth 定义GetPanicStack函数默认显示栈最大层数。
 	DefaultRecoverDepth = 20
+	// DefaultConfigWatchInterval 定义ConfigWatcher检测配置文件修改的默认间隔。
+	DefaultConfigWatchInterval = 3 * time.Second
+	// DefaultAppHookTimeout 定义App生命周期钩子默认的超时时间。
+	DefaultAppHookTimeout = 10 * time.Second
+	// DefaultAppShutdownTimeout 定义App结束时关闭Server的超时时间。
+	DefaultAppShutdownTimeout = 30 * time.Second
+	// DefaultLoggerLevels 定义LoggerStd默认使用的日志级别注册表。
+	DefaultLoggerLevels = NewLoggerLevels()
+	// DefaultLoggerLevelsMaxCells 定义日志级别注册表最多保存的Logger名称数量，超过后新名称的Logger不会获得运行时修改的级别。
+	DefaultLoggerLevelsMaxCells = 1024
+	// DefaultLoggerWriterAsyncSize 定义异步日志写入流默认缓冲的条目数量。
+	DefaultLoggerWriterAsyncSize = 4096
+	// DefaultWebsocketReadLimit 定义websocket读取一个消息的默认最大长度。
+	DefaultWebsocketReadLimit int64 = 32 << 20 // 32 MB
+	// DefaultWebsocketUpgrader 定义默认的websocket升级对象，使用DefaultWebsocketUpgrader.Upgrade(ctx)升级请求。
+	DefaultWebsocketUpgrader = NewWebsocketUpgrader()
 	// LogLevelString 定义日志级别输出字符串。
 	LogLevelString = [5]string{"DEBUG", "INFO", "WARNING", "ERROR", "FATAL"}
 	// RouterAllMethod 定义路由器使用的全部方法。
@@ -34,7 +69,7 @@ var (
 	// RouterAnyMethod 定义Any方法的注册使用的方法。
 	RouterAnyMethod = []string{MethodGet, MethodPost, MethodPut, MethodDelete, MethodHead, MethodPatch}
 	// ConfigAllParseFunc 定义ConfigMap和ConfigEudore默认使用的解析函数。
-	ConfigAllParseFunc = []ConfigParseFunc{ConfigParseJSON, ConfigParseArgs, ConfigParseEnvs, ConfigParseMods, ConfigParseWorkdir, ConfigParseHelp}
+	ConfigAllParseFunc = []ConfigParseFunc{ConfigParseFile, ConfigParseArgs, ConfigParseEnvs, ConfigParseMods, ConfigParseWorkdir, ConfigParseHelp, ConfigParseValidate}
 	// DefaultHandlerExtend 为默认的函数扩展处理者，是RouterStd使用的最顶级的函数扩展处理者。
 	DefaultHandlerExtend = NewHandlerExtendBase()
 	// DefaultValidater 定义默认的验证器
@@ -103,29 +138,86 @@ var (
 	loggerpart3  = []byte(`,"message":"`)
 	loggerpart4  = []byte("\"}\n")
 	loggerpart5  = []byte("}\n")
+	// loggerConsoleLevels 定义console格式日志级别的颜色和对齐。
+	loggerConsoleLevels = [][]byte{
+		[]byte("\x1b[37mDEBUG  \x1b[0m"), []byte("\x1b[34mINFO   \x1b[0m"), []byte("\x1b[33mWARNING\x1b[0m"),
+		[]byte("\x1b[31mERROR  \x1b[0m"), []byte("\x1b[35mFATAL  \x1b[0m"),
+	}
 )
 
 // 定义默认错误
 var (
 	// ErrApplicationStop 在app正常退出时返回。
 	ErrApplicationStop = errors.New("stop application success")
+	// ErrBindStreamFuncInvalid BindStream的处理函数类型必须是func(*T) error或func(T) error。
+	ErrBindStreamFuncInvalid = errors.New("BindStream func type must be func(*T) error or func(T) error")
+	// ErrBindStreamTooLarge BindStream请求body超过最大长度。
+	ErrBindStreamTooLarge = errors.New("request body too large")
+	// ErrBindStreamTooManyItems BindStream请求元素数量超过最大数量。
+	ErrBindStreamTooManyItems = errors.New("request body too many items")
+	// ErrCBORDataInvalid 解析cbor数据格式错误。
+	ErrCBORDataInvalid = errors.New("cbor data is invalid")
+	// ErrCodecDataDepth 解析msgpack或cbor数据嵌套层数超过限制。
+	ErrCodecDataDepth = errors.New("codec data exceeded max depth")
+	// ErrConfigSecretInvalid 配置加密值长度无效。
+	ErrConfigSecretInvalid = errors.New("config secret value is invalid")
+	// ErrConfigSecretKeyEmpty 解密配置值时环境变量EnvEudoreConfigKey为空。
+	ErrConfigSecretKeyEmpty = errors.New("config secret key env " + EnvEudoreConfigKey + " is empty")
 	// ErrConverterInputDataNil 在Converter方法时，输出参数是空。
 	ErrConverterInputDataNil = errors.New("Converter input value is nil")
 	// ErrConverterInputDataNotPtr 在Converter方法时，输出参数是空。
 	ErrConverterInputDataNotPtr = errors.New("Converter input value not is ptr")
 	// ErrConverterTargetDataNil 在Converter方法时，目标参数是空。
 	ErrConverterTargetDataNil = errors.New("Converter target data is nil")
+	// ErrLoggerWriterClosed 异步日志写入流已经关闭。
+	ErrLoggerWriterClosed = errors.New("logger writer is closed")
 	// ErrLoggerLevelUnmarshalText 日志级别解码错误，请检查输出的[]byte是否有效。
 	ErrLoggerLevelUnmarshalText = errors.New("logger level UnmarshalText error")
+	// ErrMsgpackDataInvalid 解析msgpack数据格式错误。
+	ErrMsgpackDataInvalid = errors.New("msgpack data is invalid")
 	// ErrRegisterNewHandlerParamNotFunc 调用RegisterHandlerExtend函数时，参数必须是一个函数。
 	ErrRegisterNewHandlerParamNotFunc = errors.New("The parameter type of RegisterNewHandler must be a function")
+	// ErrRendererNotAcceptable RendererRegistry没有Accept Header可接受的Renderer。
+	ErrRendererNotAcceptable = errors.New("renderer not acceptable")
 	// ErrResponseWriterHTTPNotHijacker ResponseWriterHTTP对象没有实现http.Hijacker接口。
 	ErrResponseWriterHTTPNotHijacker = errors.New("http.Hijacker interface is not supported")
 	// ErrSeterNotSupportField Seter对象不支持设置当前属性。
 	ErrSeterNotSupportField = errors.New("Converter seter not support set field")
+	// ErrTraceparentInvalid traceparent Header格式无效。
+	ErrTraceparentInvalid = errors.New("traceparent header is invalid")
+	// ErrWebsocketClosed websocket连接已经发送关闭帧，不能继续写入消息。
+	ErrWebsocketClosed = errors.New("websocket close frame has been sent")
+	// ErrWebsocketControlTooLarge websocket控制帧数据超过125字节。
+	ErrWebsocketControlTooLarge = errors.New("websocket control frame payload too large")
+	// ErrWebsocketInvalidUTF8 websocket文本消息或关闭原因不是有效的UTF-8。
+	ErrWebsocketInvalidUTF8 = errors.New("websocket invalid utf-8 payload")
+	// ErrWebsocketReadLimit websocket消息长度超过ReadLimit。
+	ErrWebsocketReadLimit = errors.New("websocket message exceeds read limit")
 
+	// ErrFormatAppHookDependCycle App生命周期钩子存在循环依赖。
+	ErrFormatAppHookDependCycle = "eudore app hook '%s' depend cycle"
+	// ErrFormatAppHookDependNotFound App生命周期钩子依赖的钩子不存在。
+	ErrFormatAppHookDependNotFound = "eudore app hook '%s' depend '%s' not found"
+	// ErrFormatAppHookInvalidName App生命周期钩子名称为空或者重复。
+	ErrFormatAppHookInvalidName = "eudore app hook name '%s' is empty or duplicate"
+	// ErrFormatAppHookStart App生命周期钩子执行Start返回错误。
+	ErrFormatAppHookStart = "eudore app hook '%s' start error: %v"
+	// ErrFormatAppHookStop App生命周期钩子执行Stop返回错误。
+	ErrFormatAppHookStop = "eudore app hook '%s' stop error: %v"
 	// ErrFormatBindDefaultNotSupportContentType BindDefault函数不支持当前的Content-Type Header。
 	ErrFormatBindDefaultNotSupportContentType = "BindDefault not support content type header: %s"
+	// ErrFormatCodecAssign msgpack或cbor解码数据类型无法赋值给对象类型。
+	ErrFormatCodecAssign = "codec cannot assign %s to type %s"
+	// ErrFormatCodecUnsupportedType msgpack或cbor编码不支持的类型。
+	ErrFormatCodecUnsupportedType = "codec unsupported type: %s"
+	// ErrFormatConfigParseTOML 解析toml配置文件错误。
+	ErrFormatConfigParseTOML = "toml parse error at line %d: %s"
+	// ErrFormatConfigParseYAML 解析yaml配置文件错误。
+	ErrFormatConfigParseYAML = "yaml parse error at line %d: %s"
+	// ErrFormatConfigSecret 配置secret引用解析失败。
+	ErrFormatConfigSecret = "config key '%s' resolve secret error: %v"
+	// ErrFormatConfigValidate 配置验证失败，描述无效配置的路径。
+	ErrFormatConfigValidate = "config key '%s' invalid: %v"
 	// ErrFormatControllerBind 执行控制器方法bind时返回错误
 	ErrFormatControllerBind = "Controller bind error: %v"
 	// ErrFormatConverterGetWithTags 在Get方法时，无法或到值，返回错误描述。
@@ -144,6 +236,12 @@ var (
 	ErrFormatConverterSetTypeError = "The type of the set value is %s, which is not configurable, key: %v, val: %s"
 	// ErrFormatConverterSetWithValue setWithValue函数中类型无法赋值。
 	ErrFormatConverterSetWithValue = "The setWithValue method type %s cannot be assigned to type %s"
+	// ErrFormatLoggerWriterAsyncPolicy 异步日志写入流的缓冲满处理策略无效。
+	ErrFormatLoggerWriterAsyncPolicy = "logger writer async invalid policy '%s', policy must be block, droplow or dropall"
+	// ErrFormatProtobufMethodNotFound protobuf消息没有默认编解码函数使用的Marshal或Unmarshal方法。
+	ErrFormatProtobufMethodNotFound = "protobuf type %T not has %[2]s method, set eudore.DefaultProtobuf%[2]s to use proto.%[2]s"
+	// ErrFormatProtobufNotMessage BindProtobuf或RenderProtobuf的对象没有实现proto.Message接口或编解码方法。
+	ErrFormatProtobufNotMessage = "protobuf type %T not is proto.Message"
 	// ErrFormatRegisterHandlerExtendInputParamError RegisterHandlerExtend函数注册的函数参数错误。
 	ErrFormatRegisterHandlerExtendInputParamError = "The '%s' input parameter is illegal and should be one"
 	// ErrFormatRegisterHandlerExtendOutputParamError RegisterHandlerExtend函数注册的函数返回值错误。
@@ -154,10 +252,26 @@ var (
 	ErrFormatRouterStdAddHandlerExtend = "The RouterStd.AddHandlerExtend path is '%s' RegisterHandlerExtend error: %v"
 	// ErrFormatRouterStdRegisterHandlersMethodInvalid RouterStd.registerHandlers 的添加的是无效的，全部有效方法为RouterAnyMethod。
 	ErrFormatRouterStdRegisterHandlersMethodInvalid = "The RouterStd.registerHandlers arg method '%s' is invalid, complete method: '%s', add fullpath: '%s'"
+	// ErrFormatRouterStdRegisterHandlersNameExists RouterStd.registerHandlers 的路由名称已经注册其他路由路径。
+	ErrFormatRouterStdRegisterHandlersNameExists = "The RouterStd.registerHandlers route name '%s' already registered path '%s', add fullpath: '%s'"
 	// ErrFormatRouterStdRegisterHandlersRecover RouterStd出现panic。
 	ErrFormatRouterStdRegisterHandlersRecover = "The RouterStd.registerHandlers arg method is '%s' and path is '%s', recover error: %v"
 	// ErrFormatRouterStdNewHandlerFuncsUnregisterType RouterStd添加处理对象或中间件的第n个参数类型未注册，需要先使用RegisterHandlerExtend或AddHandlerExtend注册该函数类型。
 	ErrFormatRouterStdNewHandlerFuncsUnregisterType = "The RouterStd.newHandlerFuncs path is '%s', %dth handler parameter type is '%s', this is the unregistered handler type"
+	// ErrFormatRouterStdURLArgsInvalid RouterStd.URL的参数必须是字符串键值对或url.Values。
+	ErrFormatRouterStdURLArgsInvalid = "The RouterStd.URL route name '%s' %dth arg '%v' is invalid, args must be key-value pairs or url.Values"
+	// ErrFormatRouterStdURLNotFound RouterStd.URL的路由名称未注册。
+	ErrFormatRouterStdURLNotFound = "The RouterStd.URL route name '%s' not found"
+	// ErrFormatRouterStdURLParamInvalid RouterStd.URL的路由参数未通过校验函数检查。
+	ErrFormatRouterStdURLParamInvalid = "The RouterStd.URL route name '%s' param '%s' value '%s' is invalid"
+	// ErrFormatRouterStdURLParamMissing RouterStd.URL缺少路由参数。
+	ErrFormatRouterStdURLParamMissing = "The RouterStd.URL route name '%s' param '%s' is missing"
+	// ErrFormatWebsocketHandshake websocket握手请求无效。
+	ErrFormatWebsocketHandshake = "websocket handshake error: %s"
+	// ErrFormatWebsocketMessageType websocket写入无效的消息类型。
+	ErrFormatWebsocketMessageType = "websocket invalid message type %d"
+	// ErrFormatWebsocketProtocol websocket帧违反协议。
+	ErrFormatWebsocketProtocol = "websocket protocol error: %s"
 )
 
 // 定义eudore定义各种常量。
@@ -168,6 +282,12 @@ const (
 	EnvEudoreIsDaemon = "EUDORE_IS_DEAMON"
 	// EnvEudoreIsNotify 表示使用使用了Notify组件。
 	EnvEudoreIsNotify = "EUDORE_IS_NOTIFY"
+	// EnvEudoreConfigKey 定义ConfigParseSecrets解密配置使用的AES密钥，使用"base64:"前缀表示base64编码。
+	EnvEudoreConfigKey = "EUDORE_CONFIG_KEY"
+	// EnvEudoreListeners 用于热重启时向子进程传递继承的监听，值为使用','连接的network://addr，顺序对应fd 3开始。
+	EnvEudoreListeners = "EUDORE_LISTENERS"
+	// EnvEudoreReadyFD 用于热重启时子进程通知父进程启动完成，值为管道写入端的fd。
+	EnvEudoreReadyFD = "EUDORE_READY_FD"
 	// EnvEudoreDisablePidfile 用于Command组件不写入pidfile，Notify组件启动的子程序不写入pidfile。
 	EnvEudoreDisablePidfile = "EUDORE_DISABLE_PIDFILE"
 
@@ -289,6 +409,7 @@ const (
 	HeaderIfUnmodifiedSince               = "If-Unmodified-Since"
 	HeaderIndex                           = "Index"
 	HeaderKeepAlive                       = "Keep-Alive"
+	HeaderLastEventID                     = "Last-Event-Id"
 	HeaderLastModified                    = "Last-Modified"
 	HeaderLocation                        = "Location"
 	HeaderOrigin                          = "Origin"
@@ -302,6 +423,10 @@ const (
 	HeaderReferrerPolicy                  = "Referrer-Policy"
 	HeaderRetryAfter                      = "Retry-After"
 	HeaderSecWebSocketAccept              = "Sec-WebSocket-Accept"
+	HeaderSecWebSocketExtensions          = "Sec-Websocket-Extensions"
+	HeaderSecWebSocketKey                 = "Sec-Websocket-Key"
+	HeaderSecWebSocketProtocol            = "Sec-Websocket-Protocol"
+	HeaderSecWebSocketVersion             = "Sec-Websocket-Version"
 	HeaderServer                          = "Server"
 	HeaderServerTiming                    = "Server-Timing"
 	HeaderSetCookie                       = "Set-Cookie"
@@ -310,6 +435,8 @@ const (
 	HeaderTE                              = "Te"
 	HeaderTimingAllowOrigin               = "Timing-Allow-Origin"
 	HeaderTk                              = "Tk"
+	HeaderTraceparent                     = "Traceparent"
+	HeaderTracestate                      = "Tracestate"
 	HeaderTrailer                         = "Trailer"
 	HeaderTransferEncoding                = "Transfer-Encoding"
 	HeaderUpgrade                         = "Upgrade"
@@ -320,6 +447,7 @@ const (
 	HeaderWWWAuthenticate                 = "Www-Authenticate"
 	HeaderWarning                         = "Warning"
 	HeaderXContentTypeOptions             = "X-Content-Type-Options"
+	HeaderXAccelBuffering                 = "X-Accel-Buffering"
 	HeaderXCSRFToken                      = "X-Csrf-Token"
 	HeaderXDNSPrefetchControl             = "X-Dns-Prefetch-Control"
 	HeaderXForwardedFor                   = "X-Forwarded-For"
@@ -357,6 +485,7 @@ const (
 	MimeTextJavascriptUtf8         = MimeTextJavascript + "; " + MimeCharsetUtf8
 	MimeTextMarkdown               = "text/markdown"
 	MimeTextMarkdownUtf8           = MimeTextMarkdown + "; " + MimeCharsetUtf8
+	MimeTextEventStream            = "text/event-stream"
 	MimeTextXML                    = "text/xml"
 	MimeTextXMLCharsetUtf8         = MimeTextXML + "; " + MimeCharsetUtf8
 	MimeApplicationJSON            = "application/json"
@@ -365,6 +494,9 @@ const (
 	MimeApplicationxmlCharsetUtf8  = MimeApplicationXML + "; " + MimeCharsetUtf8
 	MimeApplicationForm            = "application/x-www-form-urlencoded"
 	MimeApplicationFormCharsetUtf8 = MimeApplicationForm + "; " + MimeCharsetUtf8
+	MimeApplicationMsgpack         = "application/msgpack"
+	MimeApplicationProtobuf        = "application/protobuf"
+	MimeApplicationCBOR            = "application/cbor"
 	MimeMultipartForm              = "multipart/form-data"
 
 	// Param
@@ -374,6 +506,8 @@ const (
 	ParamCaller          = "caller"
 	ParamControllerGroup = "controllergroup"
 	ParamRAM             = "ram"
+	ParamName            = "name"
+	ParamOpenAPI         = "openapi"
 	ParamRegister        = "register"
 	ParamTemplate        = "template"
 	ParamRoute           = "route"
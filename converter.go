@@ -18,6 +18,7 @@ func ConvertToWithTags(source interface{}, target interface{}, tags []string) er
 */
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -839,6 +840,18 @@ func getWithValueString(t reflect.Type, v reflect.Value) string {
 // 将一个字符串赋值给对象
 func setWithString(iValue reflect.Value, val string) error {
 	val = strings.TrimSpace(val)
+	err := setWithStringKind(iValue, val)
+	// 按类型无法解析时使用encoding.TextUnmarshaler接口，例如LoggerLevel的"debug"。
+	if err != nil && iValue.CanAddr() && iValue.Kind() != reflect.Ptr {
+		unmarshaler, ok := iValue.Addr().Interface().(encoding.TextUnmarshaler)
+		if ok {
+			return unmarshaler.UnmarshalText([]byte(val))
+		}
+	}
+	return err
+}
+
+func setWithStringKind(iValue reflect.Value, val string) error {
 	switch iValue.Kind() {
 	case reflect.Int:
 		return setIntField(val, 0, iValue)
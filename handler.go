@@ -52,9 +52,10 @@ type handlerHTTP interface {
 
 var (
 	// contextFuncName key类型一定为HandlerFunc类型，保存函数可能正确的名称。
-	contextFuncName    = make(map[uintptr]string)   // 最终名称
-	contextSaveName    = make(map[uintptr]string)   // 函数名称
-	contextAliasName   = make(map[uintptr][]string) // 对象名称
+	contextFuncName    = make(map[uintptr]string)          // 最终名称
+	contextSaveName    = make(map[uintptr]string)          // 函数名称
+	contextAliasName   = make(map[uintptr][]string)        // 对象名称
+	contextRPCTypes    = make(map[uintptr][2]reflect.Type) // RPC请求和响应类型
 	fineLineFieldsKeys = []string{"file", "line"}
 )
 
@@ -708,7 +709,7 @@ func NewExtendHandlerRPC(fn interface{}) HandlerFunc {
 		return nil
 	}
 	fineLineFieldsVals := getFileLineFieldsVals(iValue)
-	return func(ctx Context) {
+	h := func(ctx Context) {
 		// 创建请求参数并初始化
 		var req reflect.Value
 		if kindIn == reflect.Ptr {
@@ -742,6 +743,19 @@ func NewExtendHandlerRPC(fn interface{}) HandlerFunc {
 			ctx.Fatal(err)
 		}
 	}
+	contextRPCTypes[getFuncPointer(reflect.ValueOf(h))] = [2]reflect.Type{typeIn, iType.Out(0)}
+	return h
+}
+
+// getHandlerRPCTypes 函数返回处理函数中最后一个RPC处理函数的请求和响应类型，不存在返回nil。
+func getHandlerRPCTypes(hs HandlerFuncs) (reflect.Type, reflect.Type) {
+	for i := len(hs) - 1; i > -1; i-- {
+		types, ok := contextRPCTypes[getFuncPointer(reflect.ValueOf(hs[i]))]
+		if ok {
+			return types[0], types[1]
+		}
+	}
+	return nil, nil
 }
 
 // NewExtendHandlerStringer 函数处理fmt.Stringer接口类型转换成HandlerFunc。
@@ -35,6 +35,23 @@ type responseWriterHTTP struct {
 	size int
 }
 
+// responseWriterHead 定义HEAD请求使用GET处理时的响应，丢弃写入的body并记录长度。
+type responseWriterHead struct {
+	ResponseWriter
+	size int
+}
+
+// Write 方法丢弃写入的数据。
+func (w *responseWriterHead) Write(data []byte) (int, error) {
+	w.size += len(data)
+	return len(data), nil
+}
+
+// Size 方法获得丢弃的数据长度。
+func (w *responseWriterHead) Size() int {
+	return w.size
+}
+
 // SetCookie 定义响应返回的set-cookie header的数据生成
 type SetCookie = http.Cookie
 
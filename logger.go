@@ -2,6 +2,7 @@ package eudore
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
 	"encoding"
 	"encoding/json"
@@ -11,12 +12,13 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
-	"unsafe"
 )
 
 /*
@@ -69,17 +71,63 @@ type loggerInitHandler interface {
 //
 // TimeFormat 日志输出时间格式化格式。
 //
-// FileLine 是否输出调用日志输出的函数和文件位置
+// FileLine 是否输出调用日志输出的函数和文件位置。
+//
+// Encoder 设置日志条目编码函数，如果为空会使用Format和Key创建一个LoggerEncoderFunc。
+//
+// Format 指定日志输出格式，可以为json、logfmt、console、syslog、journald，默认为json。
+//
+// TimeKey LevelKey MessageKey 指定json和logfmt格式的时间、级别、消息的键名。
+//
+// CallerKey 如果非空，调用位置使用一个键输出"file:line"，否则使用name、file、line三个键输出。
+//
+// Rotate MaxAge MaxBackups MaxTotalSize Compress 指定文件按时间切割和切割文件保留策略，参考LoggerWriterRotateConfig说明。
+//
+// AsyncSize 如果大于0使用NewLoggerWriterAsync异步写入日志，指定缓冲的日志条目数量。
+//
+// AsyncPolicy 指定异步缓冲满时的处理策略，可以为block、droplow、dropall，默认为block，其他值会panic；
+// 需要使用Dropped方法获取丢弃数量时，使用NewLoggerWriterAsync创建写入流设置为Writer。
+//
+// Levels 指定日志级别注册表，使用WithField("logger", name)设置名称的日志级别，默认为DefaultLoggerLevels。
+//
+// SamplingInitial 如果大于0开启日志采样，每秒内相同级别和消息的日志输出前SamplingInitial条。
+//
+// SamplingThereafter 每秒超过SamplingInitial条后每SamplingThereafter条输出一条，为0时全部丢弃，
+// 丢弃的日志数量在Sync时输出一条Warning日志。
 type LoggerStdConfig struct {
-	Writer     LoggerWriter `json:"-" alias:"writer" description:"Logger output writer."`
-	Std        bool         `json:"std" alias:"std" description:"Is output to os.Stdout."`
-	Path       string       `json:"path" alias:"path" description:"Output logger file path."`
-	MaxSize    uint64       `json:"maxsize" alias:"maxsize" description:"Output file max size, 'Path' must contain 'index'."`
-	Link       string       `json:"link" alias:"link" description:"Output file link to path."`
-	Level      LoggerLevel  `json:"level" alias:"level" description:"Logger Output level."`
-	TimeFormat string       `json:"timeformat" alias:"timeformat" description:"Logger output timeFormat, default '2006-01-02 15:04:05'"`
-	FileLine   bool         `json:"fileline" alias:"fileline" description:"Is output file and line."`
-}
+	Writer     LoggerWriter      `json:"-" alias:"writer" description:"Logger output writer."`
+	Std        bool              `json:"std" alias:"std" description:"Is output to os.Stdout."`
+	Path       string            `json:"path" alias:"path" description:"Output logger file path."`
+	MaxSize    uint64            `json:"maxsize" alias:"maxsize" description:"Output file max size, 'Path' must contain 'index'."`
+	Link       string            `json:"link" alias:"link" description:"Output file link to path."`
+	Level      LoggerLevel       `json:"level" alias:"level" description:"Logger Output level."`
+	TimeFormat string            `json:"timeformat" alias:"timeformat" description:"Logger output timeFormat, default '2006-01-02 15:04:05'"`
+	FileLine   bool              `json:"fileline" alias:"fileline" description:"Is output file and line."`
+	Encoder    LoggerEncoderFunc `json:"-" alias:"encoder" description:"Logger entry encoder."`
+	Format     string            `json:"format" alias:"format" description:"Logger output format: json, logfmt, console, syslog or journald, default json."`
+	TimeKey    string            `json:"timekey" alias:"timekey" description:"Logger output time key, default 'time'."`
+	LevelKey   string            `json:"levelkey" alias:"levelkey" description:"Logger output level key, default 'level'."`
+	MessageKey string            `json:"messagekey" alias:"messagekey" description:"Logger output message key, default 'message'."`
+	CallerKey  string            `json:"callerkey" alias:"callerkey" description:"Logger output caller key, default use 'name' 'file' 'line'."`
+	// 日志切割
+	Rotate       string        `json:"rotate" alias:"rotate" description:"Output file rotate by time: hour or day."`
+	MaxAge       time.Duration `json:"maxage" alias:"maxage" description:"Rotated file max age."`
+	MaxBackups   int           `json:"maxbackups" alias:"maxbackups" description:"Rotated file max backups number."`
+	MaxTotalSize uint64        `json:"maxtotalsize" alias:"maxtotalsize" description:"Rotated file max total size."`
+	Compress     bool          `json:"compress" alias:"compress" description:"Is compress rotated file use gzip."`
+	Levels       *LoggerLevels `json:"-" alias:"levels" description:"Logger level registry."`
+	// 异步写入
+	AsyncSize   int    `json:"asyncsize" alias:"asyncsize" description:"Logger async writer buffer entries size."`
+	AsyncPolicy string `json:"asyncpolicy" alias:"asyncpolicy" description:"Logger async writer overflow policy: block, droplow or dropall."`
+	// 日志采样
+	SamplingInitial    int `json:"samplinginitial" alias:"samplinginitial" description:"Logger sampling output first n entries per message per second."`
+	SamplingThereafter int `json:"samplingthereafter" alias:"samplingthereafter" description:"Logger sampling output every mth entries after first n entries."`
+}
+
+// LoggerEncoderFunc 定义日志条目编码函数，将条目的时间、级别、属性和消息编码追加到entry.Buffer。
+//
+// 属性值使用loggerFormatWriteReflect写入，全部编码的属性值格式相同。
+type LoggerEncoderFunc func(*LoggerStd)
 
 // LoggerStd 定义日志默认实现条目信息。
 type LoggerStd struct {
@@ -95,6 +143,8 @@ type LoggerStd struct {
 	Buffer     []byte
 	Timeformat string
 	Depth      int
+	// 日志级别注册表覆盖的日志级别，小于0时使用Level。
+	override *int32
 }
 
 // LoggerStdData 定义loggerStd的数据存储
@@ -178,13 +228,33 @@ func NewLoggerStdDataJSON(arg interface{}) LoggerStdData {
 	var config LoggerStdConfig
 	config.TimeFormat = "2006-01-02 15:04:05"
 	ConvertTo(arg, &config)
+	if config.Encoder == nil {
+		config.Encoder = newLoggerEncoder(&config)
+	}
 	logdepath := 3
 	if !config.FileLine {
 		logdepath = 3 - 0x7f
 	}
 	if config.Writer == nil {
 		var err error
-		config.Writer, err = NewLoggerWriterRotate(strings.TrimSpace(config.Path), config.Std, config.MaxSize, newLoggerLinkName(config.Link))
+		config.Writer, err = NewLoggerWriterRotateWithConfig(&LoggerWriterRotateConfig{
+			Path:         strings.TrimSpace(config.Path),
+			Std:          config.Std,
+			MaxSize:      config.MaxSize,
+			Rotate:       config.Rotate,
+			MaxAge:       config.MaxAge,
+			MaxBackups:   config.MaxBackups,
+			MaxTotalSize: config.MaxTotalSize,
+			Compress:     config.Compress,
+			Link:         config.Link,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+	if config.AsyncSize > 0 {
+		var err error
+		config.Writer, err = NewLoggerWriterAsync(config.Writer, config.AsyncSize, config.AsyncPolicy)
 		if err != nil {
 			panic(err)
 		}
@@ -192,11 +262,23 @@ func NewLoggerStdDataJSON(arg interface{}) LoggerStdData {
 
 	data := &loggerStdDataJSON{
 		LoggerWriter: config.Writer,
+		Encoder:      config.Encoder,
+		CallerKey:    config.CallerKey,
 	}
+	if config.SamplingInitial > 0 {
+		data.Sampler = newLoggerSampler(config.SamplingInitial, config.SamplingThereafter)
+	}
+	data.LevelWriter, _ = config.Writer.(loggerWriterLevel)
+	// ConvertTo会创建新的结构体指针，直接获取原始的日志级别注册表。
+	config.Levels, _ = Get(arg, "levels").(*LoggerLevels)
+	if config.Levels == nil {
+		config.Levels = DefaultLoggerLevels
+	}
+	data.Levels = config.Levels
 	data.Pool.New = func() interface{} {
 		return &LoggerStd{
 			LoggerStdData: data,
-			Timeformat:    "2006-01-02 15:04:05",
+			Timeformat:    config.TimeFormat,
 			Buffer:        make([]byte, 0, 2048),
 			Keys:          make([]string, 0, 4),
 			Vals:          make([]interface{}, 0, 4),
@@ -210,6 +292,12 @@ type loggerStdDataJSON struct {
 	sync.Mutex
 	sync.Pool
 	LoggerWriter
+	Encoder   LoggerEncoderFunc
+	CallerKey string
+	Sampler   *loggerSampler
+	// LevelWriter 如果LoggerWriter实现WriteLevel方法，写入时同时传递日志级别。
+	LevelWriter loggerWriterLevel
+	Levels      *LoggerLevels
 }
 
 func (data *loggerStdDataJSON) GetLogger() *LoggerStd {
@@ -217,28 +305,125 @@ func (data *loggerStdDataJSON) GetLogger() *LoggerStd {
 }
 
 func (data *loggerStdDataJSON) PutLogger(entry *LoggerStd) {
-	if len(entry.Message) > 0 || len(entry.Keys) > 0 {
+	if (len(entry.Message) > 0 || len(entry.Keys) > 0) && (data.Sampler == nil || data.Sampler.Check(entry)) {
 		if entry.Depth > 0 {
 			name, file, line := logFormatNameFileLine(entry.Depth)
-			entry.Keys = append(entry.Keys, "name", "file", "line")
-			entry.Vals = append(entry.Vals, name, file, line)
+			if data.CallerKey == "" {
+				entry.Keys = append(entry.Keys, "name", "file", "line")
+				entry.Vals = append(entry.Vals, name, file, line)
+			} else {
+				entry.Keys = append(entry.Keys, data.CallerKey)
+				entry.Vals = append(entry.Vals, file+":"+strconv.Itoa(line))
+			}
 		}
 		if len(entry.Keys) > len(entry.Vals) {
 			entry.Keys = entry.Keys[0:len(entry.Vals)]
 			entry.WithField("loggererr", "LoggerStd.loggerStdDataJSON: The number of field keys and values are not equal")
 		}
-		loggerEntryStdFormat(entry)
-		data.Mutex.Lock()
-		data.LoggerWriter.Write(entry.Buffer)
-		data.Mutex.Unlock()
-		entry.Message = ""
-		entry.Keys = entry.Keys[0:0]
-		entry.Vals = entry.Vals[0:0]
-		entry.Buffer = entry.Buffer[0:0]
+		data.writeEntry(entry)
 	}
+	entry.override = nil
+	entry.Message = ""
+	entry.Keys = entry.Keys[0:0]
+	entry.Vals = entry.Vals[0:0]
+	entry.Buffer = entry.Buffer[0:0]
 	data.Put(entry)
 }
 
+func (data *loggerStdDataJSON) writeEntry(entry *LoggerStd) {
+	data.Encoder(entry)
+	data.Mutex.Lock()
+	if data.LevelWriter != nil {
+		data.LevelWriter.WriteLevel(entry.Level, entry.Buffer)
+	} else {
+		data.LoggerWriter.Write(entry.Buffer)
+	}
+	data.Mutex.Unlock()
+}
+
+// Sync 方法输出采样丢弃的日志数量，然后同步输出流。
+func (data *loggerStdDataJSON) Sync() error {
+	if data.Sampler != nil {
+		dropped := atomic.SwapUint64(&data.Sampler.dropped, 0)
+		if dropped > 0 {
+			entry := data.GetLogger()
+			entry.Time = time.Now()
+			entry.Level = LogWarning
+			entry.Message = "logger sampling dropped entries"
+			entry.Keys = append(entry.Keys, "dropped")
+			entry.Vals = append(entry.Vals, dropped)
+			data.writeEntry(entry)
+			entry.Message = ""
+			entry.Keys = entry.Keys[0:0]
+			entry.Vals = entry.Vals[0:0]
+			entry.Buffer = entry.Buffer[0:0]
+			data.Put(entry)
+		}
+	}
+	return data.LoggerWriter.Sync()
+}
+
+// loggerSampler 定义日志采样器，使用固定数量的计数器按级别和消息的hash计数，计数器每秒重置。
+//
+// 计数使用原子操作，不同消息hash冲突时共享一个计数器。
+type loggerSampler struct {
+	counters   [loggerSamplerSize]loggerSamplerCounter
+	initial    uint64
+	thereafter uint64
+	dropped    uint64
+}
+
+type loggerSamplerCounter struct {
+	resetAt int64
+	count   uint64
+}
+
+const loggerSamplerSize = 4096
+
+func newLoggerSampler(initial, thereafter int) *loggerSampler {
+	if thereafter < 0 {
+		thereafter = 0
+	}
+	return &loggerSampler{
+		initial:    uint64(initial),
+		thereafter: uint64(thereafter),
+	}
+}
+
+// Check 方法检查条目是否输出，Fatal级别日志总是输出。
+func (s *loggerSampler) Check(entry *LoggerStd) bool {
+	if entry.Level >= LogFatal {
+		return true
+	}
+	// fnv-1a hash
+	hash := uint32(2166136261) ^ uint32(entry.Level)
+	hash *= 16777619
+	for i := 0; i < len(entry.Message); i++ {
+		hash ^= uint32(entry.Message[i])
+		hash *= 16777619
+	}
+	n := s.counters[hash%loggerSamplerSize].Inc(entry.Time.UnixNano())
+	if n <= s.initial || (s.thereafter > 0 && (n-s.initial)%s.thereafter == 0) {
+		return true
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	return false
+}
+
+// Inc 方法增加计数，如果超过重置时间重新开始计数。
+func (c *loggerSamplerCounter) Inc(now int64) uint64 {
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > now {
+		return atomic.AddUint64(&c.count, 1)
+	}
+	if atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+int64(time.Second)) {
+		atomic.StoreUint64(&c.count, 1)
+		return 1
+	}
+	// 其他goroutine已经重置计数
+	return atomic.AddUint64(&c.count, 1)
+}
+
 type loggerStdDataInit struct {
 	sync.Mutex
 	Data []*LoggerStd
@@ -264,6 +449,7 @@ func (entry *LoggerStd) getEntry() *LoggerStd {
 	newentry.Time = time.Now()
 	newentry.Level = entry.Level
 	newentry.Depth = entry.Depth
+	newentry.override = entry.override
 	if len(entry.Keys) != 0 {
 		newentry.Keys = append(newentry.Keys, entry.Keys...)
 		newentry.Vals = append(newentry.Vals, entry.Vals...)
@@ -271,7 +457,18 @@ func (entry *LoggerStd) getEntry() *LoggerStd {
 	return newentry
 }
 
-// SetLevel 方法设置日志输出级别。
+// getLevel 方法返回生效的日志级别，日志级别注册表的设置优先。
+func (entry *LoggerStd) getLevel() LoggerLevel {
+	if entry.override != nil {
+		level := atomic.LoadInt32(entry.override)
+		if level >= 0 {
+			return LoggerLevel(level)
+		}
+	}
+	return entry.Level
+}
+
+// SetLevel 方法设置日志输出级别，如果日志级别注册表设置了Logger名称的级别，注册表的级别优先。
 func (entry *LoggerStd) SetLevel(level LoggerLevel) {
 	entry.Level = level
 }
@@ -286,7 +483,7 @@ func (entry *LoggerStd) Debug(args ...interface{}) {
 	if entry.Logger {
 		entry = entry.getEntry()
 	}
-	if entry.Level < 1 {
+	if entry.getLevel() < 1 {
 		entry.Level = 0
 		entry.Message = fmt.Sprintln(args...)
 		entry.Message = entry.Message[:len(entry.Message)-1]
@@ -302,7 +499,7 @@ func (entry *LoggerStd) Info(args ...interface{}) {
 	if entry.Logger {
 		entry = entry.getEntry()
 	}
-	if entry.Level < 2 {
+	if entry.getLevel() < 2 {
 		entry.Level = 1
 		entry.Message = fmt.Sprintln(args...)
 		entry.Message = entry.Message[:len(entry.Message)-1]
@@ -318,7 +515,7 @@ func (entry *LoggerStd) Warning(args ...interface{}) {
 	if entry.Logger {
 		entry = entry.getEntry()
 	}
-	if entry.Level < 3 {
+	if entry.getLevel() < 3 {
 		entry.Level = 2
 		entry.Message = fmt.Sprintln(args...)
 		entry.Message = entry.Message[:len(entry.Message)-1]
@@ -334,7 +531,7 @@ func (entry *LoggerStd) Error(args ...interface{}) {
 	if entry.Logger {
 		entry = entry.getEntry()
 	}
-	if entry.Level < 4 {
+	if entry.getLevel() < 4 {
 		entry.Level = 3
 		entry.Message = fmt.Sprintln(args...)
 		entry.Message = entry.Message[:len(entry.Message)-1]
@@ -361,7 +558,7 @@ func (entry *LoggerStd) Debugf(format string, args ...interface{}) {
 	if entry.Logger {
 		entry = entry.getEntry()
 	}
-	if entry.Level < 1 {
+	if entry.getLevel() < 1 {
 		entry.Level = 0
 		entry.Message = fmt.Sprintf(format, args...)
 	} else {
@@ -376,7 +573,7 @@ func (entry *LoggerStd) Infof(format string, args ...interface{}) {
 	if entry.Logger {
 		entry = entry.getEntry()
 	}
-	if entry.Level < 2 {
+	if entry.getLevel() < 2 {
 		entry.Level = 1
 		entry.Message = fmt.Sprintf(format, args...)
 	} else {
@@ -391,7 +588,7 @@ func (entry *LoggerStd) Warningf(format string, args ...interface{}) {
 	if entry.Logger {
 		entry = entry.getEntry()
 	}
-	if entry.Level < 3 {
+	if entry.getLevel() < 3 {
 		entry.Level = 2
 		entry.Message = fmt.Sprintf(format, args...)
 	} else {
@@ -406,7 +603,7 @@ func (entry *LoggerStd) Errorf(format string, args ...interface{}) {
 	if entry.Logger {
 		entry = entry.getEntry()
 	}
-	if entry.Level < 4 {
+	if entry.getLevel() < 4 {
 		entry.Level = 3
 		entry.Message = fmt.Sprintf(format, args...)
 	} else {
@@ -454,11 +651,19 @@ func (entry *LoggerStd) WithFields(key []string, value []interface{}) Logger {
 // 如果key为"depth"值类型为string值"enable"或"disable",启用或关闭日志调用位置输出。
 //
 // 如果key为"time"值类型为time.time，设置日志输出的时间属性。
+//
+// 如果key为"logger"值类型为string，设置Logger名称，使用日志级别注册表中名称对应的日志级别。
 func (entry *LoggerStd) WithField(key string, value interface{}) Logger {
 	if entry.Logger {
 		entry = entry.getEntry()
 	}
 	switch key {
+	case "logger":
+		data, ok := entry.LoggerStdData.(*loggerStdDataJSON)
+		name, ok2 := value.(string)
+		if ok && ok2 && data.Levels != nil {
+			entry.override = data.Levels.lookup(name)
+		}
 	case "context":
 		val, ok := value.(context.Context)
 		if ok {
@@ -496,11 +701,43 @@ func (entry *LoggerStd) WithField(key string, value interface{}) Logger {
 	return entry
 }
 
+// newLoggerEncoder 函数根据配置的Format创建日志编码函数。
+func newLoggerEncoder(config *LoggerStdConfig) LoggerEncoderFunc {
+	switch strings.ToLower(config.Format) {
+	case "logfmt":
+		return NewLoggerEncoderLogfmt(config.TimeKey, config.LevelKey, config.MessageKey)
+	case "console":
+		return NewLoggerEncoderConsole()
+	case "syslog":
+		return NewLoggerEncoderSyslog(1, "")
+	case "journald":
+		return NewLoggerEncoderJournald("")
+	default:
+		if config.TimeKey == "" && config.LevelKey == "" && config.MessageKey == "" {
+			return loggerEntryStdFormat
+		}
+		return NewLoggerEncoderJSON(config.TimeKey, config.LevelKey, config.MessageKey)
+	}
+}
+
+// NewLoggerEncoderJSON 函数创建json格式的日志编码函数，每行一个json对象，参数指定时间、级别、消息的键名，为空使用默认键名。
+func NewLoggerEncoderJSON(timekey, levelkey, messagekey string) LoggerEncoderFunc {
+	part1 := []byte(`{"` + GetString(timekey, "time") + `":"`)
+	part2 := []byte(`","` + GetString(levelkey, "level") + `":"`)
+	part3 := []byte(`,"` + GetString(messagekey, "message") + `":"`)
+	return func(entry *LoggerStd) {
+		loggerEncodeJSON(entry, part1, part2, part3)
+	}
+}
+
 func loggerEntryStdFormat(entry *LoggerStd) {
-	timestr := time.Now().Format(entry.Timeformat)
-	entry.Buffer = append(entry.Buffer, loggerpart1...)
-	entry.Buffer = append(entry.Buffer, *(*[]byte)(unsafe.Pointer(&timestr))...)
-	entry.Buffer = append(entry.Buffer, loggerpart2...)
+	loggerEncodeJSON(entry, loggerpart1, loggerpart2, loggerpart3)
+}
+
+func loggerEncodeJSON(entry *LoggerStd, part1, part2, part3 []byte) {
+	entry.Buffer = append(entry.Buffer, part1...)
+	entry.Buffer = entry.Time.AppendFormat(entry.Buffer, entry.Timeformat)
+	entry.Buffer = append(entry.Buffer, part2...)
 	entry.Buffer = append(entry.Buffer, loggerlevels[entry.Level]...)
 	entry.Buffer = append(entry.Buffer, '"')
 
@@ -513,7 +750,7 @@ func loggerEntryStdFormat(entry *LoggerStd) {
 	}
 
 	if len(entry.Message) > 0 {
-		entry.Buffer = append(entry.Buffer, loggerpart3...)
+		entry.Buffer = append(entry.Buffer, part3...)
 		loggerFormatWriteString(entry, entry.Message)
 		entry.Buffer = append(entry.Buffer, loggerpart4...)
 	} else {
@@ -521,6 +758,73 @@ func loggerEntryStdFormat(entry *LoggerStd) {
 	}
 }
 
+// NewLoggerEncoderLogfmt 函数创建logfmt格式的日志编码函数，参数指定时间、级别、消息的键名，为空使用默认键名。
+//
+// 输出格式: time="2006-01-02 15:04:05" level=INFO key=value message="hello eudore"
+func NewLoggerEncoderLogfmt(timekey, levelkey, messagekey string) LoggerEncoderFunc {
+	timekey = GetString(timekey, "time")
+	levelkey = GetString(levelkey, "level") + "="
+	messagekey = GetString(messagekey, "message")
+	return func(entry *LoggerStd) {
+		entry.Buffer = append(entry.Buffer, timekey...)
+		entry.Buffer = append(entry.Buffer, '=')
+		loggerFormatWriteLogfmt(entry, reflect.ValueOf(entry.Time.Format(entry.Timeformat)))
+		entry.Buffer = append(entry.Buffer, ' ')
+		entry.Buffer = append(entry.Buffer, levelkey...)
+		entry.Buffer = append(entry.Buffer, LogLevelString[entry.Level]...)
+		for i := range entry.Keys {
+			entry.Buffer = append(entry.Buffer, ' ')
+			entry.Buffer = append(entry.Buffer, entry.Keys[i]...)
+			entry.Buffer = append(entry.Buffer, '=')
+			loggerFormatWriteLogfmt(entry, reflect.ValueOf(entry.Vals[i]))
+		}
+		if len(entry.Message) > 0 {
+			entry.Buffer = append(entry.Buffer, ' ')
+			entry.Buffer = append(entry.Buffer, messagekey...)
+			entry.Buffer = append(entry.Buffer, '=')
+			loggerFormatWriteLogfmt(entry, reflect.ValueOf(entry.Message))
+		}
+		entry.Buffer = append(entry.Buffer, '\n')
+	}
+}
+
+// NewLoggerEncoderConsole 函数创建一个用于开发环境的彩色终端日志编码函数。
+//
+// 输出格式: 2006-01-02 15:04:05 INFO    hello eudore key=value
+func NewLoggerEncoderConsole() LoggerEncoderFunc {
+	return func(entry *LoggerStd) {
+		entry.Buffer = entry.Time.AppendFormat(entry.Buffer, entry.Timeformat)
+		entry.Buffer = append(entry.Buffer, ' ')
+		entry.Buffer = append(entry.Buffer, loggerConsoleLevels[entry.Level]...)
+		entry.Buffer = append(entry.Buffer, ' ')
+		loggerFormatWriteString(entry, entry.Message)
+		for i := range entry.Keys {
+			entry.Buffer = append(entry.Buffer, ' ', '\x1b', '[', '3', '6', 'm')
+			entry.Buffer = append(entry.Buffer, entry.Keys[i]...)
+			entry.Buffer = append(entry.Buffer, '=', '\x1b', '[', '0', 'm')
+			loggerFormatWriteLogfmt(entry, reflect.ValueOf(entry.Vals[i]))
+		}
+		entry.Buffer = append(entry.Buffer, '\n')
+	}
+}
+
+// loggerFormatWriteLogfmt 函数使用loggerFormatWriteReflect写入值，字符串不包含空格、'='和需要转义的字符时删除引号。
+func loggerFormatWriteLogfmt(entry *LoggerStd, iValue reflect.Value) {
+	pos := len(entry.Buffer)
+	loggerFormatWriteReflect(entry, iValue)
+	value := entry.Buffer[pos:]
+	if len(value) < 3 || value[0] != '"' || value[len(value)-1] != '"' {
+		return
+	}
+	for _, b := range value[1 : len(value)-1] {
+		if b == ' ' || b == '=' || b == '\\' || b == '"' {
+			return
+		}
+	}
+	copy(value, value[1:len(value)-1])
+	entry.Buffer = entry.Buffer[:len(entry.Buffer)-2]
+}
+
 // String 方法实现ftm.Stringer接口，格式化输出日志级别。
 func (l LoggerLevel) String() string {
 	return LogLevelString[l]
@@ -548,6 +852,115 @@ func (l *LoggerLevel) UnmarshalText(text []byte) error {
 	return ErrLoggerLevelUnmarshalText
 }
 
+// LoggerLevels 定义日志级别注册表，按Logger名称或路由前缀覆盖日志级别，可以在运行时修改。
+//
+// 名称以'*'结尾时为前缀匹配，例如"/api/orders/*"匹配"/api/orders/:id"，多个名称匹配时使用最长的名称。
+//
+// WithField("logger", name)使用过的名称最多保存DefaultLoggerLevelsMaxCells个，
+// 超过后新名称的Logger使用创建时匹配的级别，不会获得运行时修改的级别，Logger名称不应该使用请求数据。
+type LoggerLevels struct {
+	sync.RWMutex
+	levels map[string]LoggerLevel
+	// 每个查找过的名称保存一个生效级别，修改注册表时更新，-1表示没有设置。
+	cells map[string]*int32
+}
+
+// NewLoggerLevels 函数创建一个日志级别注册表。
+func NewLoggerLevels() *LoggerLevels {
+	return &LoggerLevels{
+		levels: make(map[string]LoggerLevel),
+		cells:  make(map[string]*int32),
+	}
+}
+
+// Set 方法设置一个名称或前缀的日志级别。
+func (l *LoggerLevels) Set(name string, level LoggerLevel) {
+	l.Lock()
+	if l.levels == nil {
+		l.levels = make(map[string]LoggerLevel)
+	}
+	l.levels[name] = level
+	l.update()
+	l.Unlock()
+}
+
+// Delete 方法删除一个名称或前缀的日志级别。
+func (l *LoggerLevels) Delete(name string) {
+	l.Lock()
+	delete(l.levels, name)
+	l.update()
+	l.Unlock()
+}
+
+// List 方法返回全部设置的日志级别。
+func (l *LoggerLevels) List() map[string]LoggerLevel {
+	l.RLock()
+	defer l.RUnlock()
+	levels := make(map[string]LoggerLevel, len(l.levels))
+	for k, v := range l.levels {
+		levels[k] = v
+	}
+	return levels
+}
+
+// Match 方法返回名称匹配的日志级别。
+func (l *LoggerLevels) Match(name string) (LoggerLevel, bool) {
+	l.RLock()
+	level := l.match(name)
+	l.RUnlock()
+	if level < 0 {
+		return 0, false
+	}
+	return LoggerLevel(level), true
+}
+
+func (l *LoggerLevels) match(name string) int32 {
+	level, ok := l.levels[name]
+	if ok {
+		return int32(level)
+	}
+	var pattern string
+	var result int32 = -1
+	for k, v := range l.levels {
+		if len(k) > len(pattern) && k[len(k)-1] == '*' && strings.HasPrefix(name, k[:len(k)-1]) {
+			pattern = k
+			result = int32(v)
+		}
+	}
+	return result
+}
+
+// lookup 方法返回名称的生效级别，Logger保存该值用于获得运行时修改的级别。
+func (l *LoggerLevels) lookup(name string) *int32 {
+	l.RLock()
+	cell, ok := l.cells[name]
+	l.RUnlock()
+	if ok {
+		return cell
+	}
+	l.Lock()
+	defer l.Unlock()
+	cell, ok = l.cells[name]
+	if !ok {
+		if l.cells == nil {
+			l.cells = make(map[string]*int32)
+		}
+		cell = new(int32)
+		*cell = l.match(name)
+		// 限制保存的名称数量，超过后不再更新新名称的级别。
+		if len(l.cells) < DefaultLoggerLevelsMaxCells {
+			l.cells[name] = cell
+		}
+	}
+	return cell
+}
+
+func (l *LoggerLevels) update() {
+	for name, cell := range l.cells {
+		atomic.StoreInt32(cell, l.match(name))
+	}
+}
+
 // NewPrintFunc 函数使用Logger创建一个输出函数。
 //
 // 如果第一个参数Fields类型，则调用WithFields方法。
@@ -827,6 +1240,169 @@ type LoggerWriter interface {
 	io.Writer
 }
 
+// loggerWriterLevel 定义LoggerWriter可选的带日志级别写入方法。
+type loggerWriterLevel interface {
+	WriteLevel(LoggerLevel, []byte) (int, error)
+}
+
+// LoggerWriterAsync 定义异步日志写入流，使用固定大小的环形缓冲保存日志条目，后台goroutine写入到下级LoggerWriter。
+//
+// 缓冲满时按照策略处理：
+//	block	等待缓冲有空闲位置，不丢弃日志。
+//	droplow	Debug和Info日志在缓冲使用超过3/4时丢弃，其他级别日志在缓冲满时丢弃。
+//	dropall	缓冲满时丢弃全部级别日志。
+//
+// Sync方法会等待缓冲日志全部写入后同步下级LoggerWriter，App.Run结束时调用Logger.Sync保证日志全部写入。
+type LoggerWriterAsync struct {
+	sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	drained  *sync.Cond
+	writer   LoggerWriter
+	wmu      sync.Mutex
+	policy   string
+	buffers  [][]byte
+	levels   []LoggerLevel
+	head     int
+	count    int
+	writing  bool
+	closed   bool
+	dropped  [5]uint64
+	done     chan struct{}
+}
+
+// NewLoggerWriterAsync 函数创建一个异步日志写入流，size指定缓冲条目数量，policy指定缓冲满时的处理策略，为空使用block。
+//
+// policy不是block、droplow、dropall时返回错误。
+func NewLoggerWriterAsync(w LoggerWriter, size int, policy string) (*LoggerWriterAsync, error) {
+	policy = strings.ToLower(policy)
+	switch policy {
+	case "":
+		policy = "block"
+	case "block", "droplow", "dropall":
+	default:
+		return nil, fmt.Errorf(ErrFormatLoggerWriterAsyncPolicy, policy)
+	}
+	if size <= 0 {
+		size = DefaultLoggerWriterAsyncSize
+	}
+	aw := &LoggerWriterAsync{
+		writer:  w,
+		policy:  policy,
+		buffers: make([][]byte, size),
+		levels:  make([]LoggerLevel, size),
+		done:    make(chan struct{}),
+	}
+	aw.notEmpty = sync.NewCond(&aw.Mutex)
+	aw.notFull = sync.NewCond(&aw.Mutex)
+	aw.drained = sync.NewCond(&aw.Mutex)
+	go aw.run()
+	return aw, nil
+}
+
+// Write 方法写入Info级别的日志数据。
+func (w *LoggerWriterAsync) Write(p []byte) (int, error) {
+	return w.WriteLevel(LogInfo, p)
+}
+
+// WriteLevel 方法将日志数据复制到缓冲中，缓冲满时按照策略阻塞或丢弃。
+func (w *LoggerWriterAsync) WriteLevel(level LoggerLevel, p []byte) (int, error) {
+	w.Lock()
+	for {
+		if w.closed {
+			w.Unlock()
+			return 0, ErrLoggerWriterClosed
+		}
+		size := len(w.buffers)
+		if w.policy == "droplow" && level < LogWarning && w.count >= size-size/4 ||
+			w.policy == "droplow" && w.count == size || w.policy == "dropall" && w.count == size {
+			w.Unlock()
+			atomic.AddUint64(&w.dropped[level], 1)
+			return len(p), nil
+		}
+		if w.count < size {
+			break
+		}
+		w.notFull.Wait()
+	}
+	index := (w.head + w.count) % len(w.buffers)
+	w.buffers[index] = append(w.buffers[index][0:0], p...)
+	w.levels[index] = level
+	w.count++
+	w.notEmpty.Signal()
+	w.Unlock()
+	return len(p), nil
+}
+
+// run 方法后台写入缓冲的日志数据，每次写入当前缓冲的全部条目。
+func (w *LoggerWriterAsync) run() {
+	defer close(w.done)
+	w.Lock()
+	for {
+		for w.count == 0 && !w.closed {
+			w.notEmpty.Wait()
+		}
+		if w.count == 0 && w.closed {
+			w.drained.Broadcast()
+			w.Unlock()
+			return
+		}
+		head, count := w.head, w.count
+		w.writing = true
+		w.Unlock()
+
+		// 写入期间条目不会被覆盖，生产者只能写入空闲位置。
+		w.wmu.Lock()
+		for i := 0; i < count; i++ {
+			w.writer.Write(w.buffers[(head+i)%len(w.buffers)])
+		}
+		w.wmu.Unlock()
+
+		w.Lock()
+		w.head = (head + count) % len(w.buffers)
+		w.count -= count
+		w.writing = false
+		w.notFull.Broadcast()
+		w.drained.Broadcast()
+	}
+}
+
+// Sync 方法等待缓冲日志全部写入，然后同步下级LoggerWriter。
+func (w *LoggerWriterAsync) Sync() error {
+	w.Lock()
+	for w.count > 0 || w.writing {
+		w.drained.Wait()
+	}
+	w.Unlock()
+	w.wmu.Lock()
+	defer w.wmu.Unlock()
+	return w.writer.Sync()
+}
+
+// Close 方法关闭异步写入，写入缓冲的全部日志后同步下级LoggerWriter。
+func (w *LoggerWriterAsync) Close() error {
+	w.Lock()
+	if w.closed {
+		w.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.notEmpty.Broadcast()
+	w.notFull.Broadcast()
+	w.Unlock()
+	<-w.done
+	return w.writer.Sync()
+}
+
+// Dropped 方法返回每个日志级别丢弃的日志数量，数组索引为LoggerLevel。
+func (w *LoggerWriterAsync) Dropped() [5]uint64 {
+	var dropped [5]uint64
+	for i := range dropped {
+		dropped[i] = atomic.LoadUint64(&w.dropped[i])
+	}
+	return dropped
+}
+
 type syncWriterFile struct {
 	*bufio.Writer
 	file *os.File
@@ -836,12 +1412,52 @@ type syncWriterRotate struct {
 	name      string
 	std       bool
 	MaxSize   uint64
+	Rotate    string
 	nextindex int
 	nexttime  time.Time
 	nbytes    uint64
 	*bufio.Writer
-	file  *os.File
-	newfn []func(string)
+	file    *os.File
+	newfn   []func(string)
+	cleaner *loggerRotateCleaner
+}
+
+// LoggerWriterRotateConfig 定义日志切割写入流的配置。
+//
+// Path 指定文件路径，yyyy、yy、MM、dd、HH会替换为时间，index会替换为切割文件索引。
+//
+// MaxSize 指定文件切割大小，需要Path中存在index字符串。
+//
+// Rotate 指定按时间切割周期，可以为hour或day，如果Path中没有时间字符串会在文件扩展名前添加时间；
+// 为空时每小时检查Path中的时间是否变化。
+//
+// MaxAge MaxBackups MaxTotalSize 指定切割文件保留的时间、数量和总大小，不包含正在写入的文件。
+//
+// Compress 是否使用gzip压缩切割后的文件。
+//
+// Link 如果非空会作为软连接的目标路径。
+type LoggerWriterRotateConfig struct {
+	Path         string        `json:"path" alias:"path"`
+	Std          bool          `json:"std" alias:"std"`
+	MaxSize      uint64        `json:"maxsize" alias:"maxsize"`
+	Rotate       string        `json:"rotate" alias:"rotate"`
+	MaxAge       time.Duration `json:"maxage" alias:"maxage"`
+	MaxBackups   int           `json:"maxbackups" alias:"maxbackups"`
+	MaxTotalSize uint64        `json:"maxtotalsize" alias:"maxtotalsize"`
+	Compress     bool          `json:"compress" alias:"compress"`
+	Link         string        `json:"link" alias:"link"`
+}
+
+// loggerRotateCleaner 定义切割文件的压缩和保留策略，在文件切割后使用后台goroutine执行。
+type loggerRotateCleaner struct {
+	sync.Mutex
+	current      atomic.Value
+	pattern      string
+	link         string
+	MaxAge       time.Duration
+	MaxBackups   int
+	MaxTotalSize uint64
+	Compress     bool
 }
 
 // NewLoggerWriterStd 函数返回一个标准输出流的日志写入流。
@@ -873,23 +1489,60 @@ func (w syncWriterFile) Sync() error {
 
 // NewLoggerWriterRotate 函数创建一个支持文件切割的的日志写入流。
 func NewLoggerWriterRotate(name string, std bool, maxsize uint64, fn ...func(string)) (LoggerWriter, error) {
+	return NewLoggerWriterRotateWithConfig(&LoggerWriterRotateConfig{
+		Path:    name,
+		Std:     std,
+		MaxSize: maxsize,
+	}, fn...)
+}
+
+// NewLoggerWriterRotateWithConfig 函数使用配置创建一个支持文件切割、保留策略和压缩的日志写入流。
+//
+// 如果不需要切割和清理，会使用NewLoggerWriterFile创建文件写入流。
+func NewLoggerWriterRotateWithConfig(config *LoggerWriterRotateConfig, fn ...func(string)) (LoggerWriter, error) {
+	name := config.Path
+	if config.Link != "" {
+		fn = append([]func(string){newLoggerLinkName(config.Link)}, fn...)
+	}
+	rotate := strings.ToLower(config.Rotate)
+	if rotate != "" && name == formatDateName(name) && name != "" {
+		// 文件名称添加时间
+		ext := filepath.Ext(name)
+		if rotate == "day" {
+			name = name[:len(name)-len(ext)] + "-yyyy-MM-dd" + ext
+		} else {
+			name = name[:len(name)-len(ext)] + "-yyyy-MM-dd-HH" + ext
+		}
+	}
+	maxsize := config.MaxSize
 	if strings.Index(name, "index") == -1 {
 		maxsize = 0
 	}
 	if maxsize <= 0 {
 		// 如果同时文件名称不包含日期，那么就具有index和date日志滚动条件。
 		if name == formatDateName(name) {
-			return NewLoggerWriterFile(name, std)
+			return NewLoggerWriterFile(name, config.Std)
 		}
 		maxsize = 0xffffffffff
 	}
 	lw := &syncWriterRotate{
 		name:     name,
-		std:      std,
+		std:      config.Std,
 		MaxSize:  maxsize,
-		nexttime: getNextHour(),
+		Rotate:   rotate,
+		nexttime: getNextRotateTime(rotate),
 		newfn:    fn,
 	}
+	if config.MaxAge > 0 || config.MaxBackups > 0 || config.MaxTotalSize > 0 || config.Compress {
+		lw.cleaner = &loggerRotateCleaner{
+			pattern:      getRotatePattern(name),
+			link:         filepath.Clean(config.Link),
+			MaxAge:       config.MaxAge,
+			MaxBackups:   config.MaxBackups,
+			MaxTotalSize: config.MaxTotalSize,
+			Compress:     config.Compress,
+		}
+	}
 	return lw, lw.rotateFile()
 }
 
@@ -909,7 +1562,7 @@ func (w *syncWriterRotate) Write(p []byte) (n int, err error) {
 		w.rotateFile()
 	}
 	if time.Now().After(w.nexttime) {
-		w.nexttime = getNextHour()
+		w.nexttime = getNextRotateTime(w.Rotate)
 		// 检查时间变化
 		if strings.Replace(formatDateName(w.name), "index", fmt.Sprint(w.nextindex-1), -1) != w.file.Name() {
 			w.nextindex = 0
@@ -928,6 +1581,10 @@ func (w *syncWriterRotate) rotateFile() error {
 	name := formatDateName(w.name)
 	for {
 		name := strings.Replace(name, "index", fmt.Sprint(w.nextindex), -1)
+		if w.cleaner != nil && w.file != nil {
+			// 切换文件期间新旧文件都不能清理
+			w.cleaner.current.Store([]string{name, w.file.Name()})
+		}
 		os.MkdirAll(filepath.Dir(name), 0644)
 		file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 		if err != nil {
@@ -945,6 +1602,10 @@ func (w *syncWriterRotate) rotateFile() error {
 			for _, fn := range w.newfn {
 				fn(name)
 			}
+			if w.cleaner != nil {
+				w.cleaner.current.Store([]string{name})
+				go w.cleaner.Clean()
+			}
 			return nil
 		}
 		file.Close()
@@ -966,6 +1627,114 @@ func getNextHour() time.Time {
 	return time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 0, 0, now.Location())
 }
 
+func getNextRotateTime(rotate string) time.Time {
+	if rotate == "day" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	}
+	return getNextHour()
+}
+
+// getRotatePattern 函数将文件名称中的时间和索引替换为通配符，用于匹配全部切割文件。
+func getRotatePattern(name string) string {
+	name = strings.Replace(name, "yyyy", "????", 1)
+	name = strings.Replace(name, "yy", "??", 1)
+	name = strings.Replace(name, "MM", "??", 1)
+	name = strings.Replace(name, "dd", "??", 1)
+	name = strings.Replace(name, "HH", "??", 1)
+	return strings.Replace(name, "index", "*", -1)
+}
+
+// Clean 方法压缩切割文件，然后按照保留时间、数量和总大小删除旧的切割文件，不处理正在写入的文件和Link软连接。
+func (c *loggerRotateCleaner) Clean() {
+	c.Lock()
+	defer c.Unlock()
+	names, _ := filepath.Glob(c.pattern)
+	gznames, _ := filepath.Glob(c.pattern + ".gz")
+	var files []os.FileInfo
+	var paths []string
+	for _, name := range append(names, gznames...) {
+		// 执行清理时可能已经再次切割文件，每次检查最新写入的文件。
+		current, _ := c.current.Load().([]string)
+		if filepath.Clean(name) == c.link || sliceContains(current, name) || sliceContains(paths, name) {
+			continue
+		}
+		if c.Compress && !strings.HasSuffix(name, ".gz") {
+			if compressLoggerFile(name) == nil {
+				name += ".gz"
+			}
+		}
+		stat, err := os.Stat(name)
+		if err == nil && !stat.IsDir() {
+			files = append(files, stat)
+			paths = append(paths, name)
+		}
+	}
+
+	// 按修改时间从新到旧排序
+	sort.Sort(loggerRotateFiles{files, paths})
+	var total uint64
+	for i := range files {
+		total += uint64(files[i].Size())
+		if (c.MaxAge > 0 && time.Since(files[i].ModTime()) > c.MaxAge) ||
+			(c.MaxBackups > 0 && i >= c.MaxBackups) ||
+			(c.MaxTotalSize > 0 && total > c.MaxTotalSize) {
+			os.Remove(paths[i])
+		}
+	}
+}
+
+type loggerRotateFiles struct {
+	files []os.FileInfo
+	paths []string
+}
+
+func (f loggerRotateFiles) Len() int {
+	return len(f.files)
+}
+
+func (f loggerRotateFiles) Less(i, j int) bool {
+	return f.files[i].ModTime().After(f.files[j].ModTime())
+}
+
+func (f loggerRotateFiles) Swap(i, j int) {
+	f.files[i], f.files[j] = f.files[j], f.files[i]
+	f.paths[i], f.paths[j] = f.paths[j], f.paths[i]
+}
+
+// compressLoggerFile 函数使用gzip压缩文件，压缩后删除原文件并保留修改时间。
+func compressLoggerFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, stat.Mode())
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(dst)
+	_, err = io.Copy(gw, src)
+	if err == nil {
+		err = gw.Close()
+	}
+	if err == nil {
+		err = dst.Close()
+	} else {
+		dst.Close()
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	os.Chtimes(name+".gz", stat.ModTime(), stat.ModTime())
+	return os.Remove(name)
+}
+
 func newLoggerLinkName(link string) func(string) {
 	os.MkdirAll(filepath.Dir(link), 0644)
 	return func(name string) {
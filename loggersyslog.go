@@ -0,0 +1,252 @@
+package eudore
+
+// loggersyslog 实现syslog和journald的日志编码和写入流。
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 日志级别对应的syslog severity，Fatal使用crit。
+var loggerSyslogSeverity = [5]int{7, 6, 4, 3, 2}
+
+// NewLoggerEncoderSyslog 函数创建RFC 5424格式的日志编码函数，facility为syslog设施编号，appname为空使用程序名称。
+//
+// 日志属性写入structured data "fields@32473"，级别转换为syslog severity。
+//
+// 输出格式: <14>1 2006-01-02T15:04:05.000000+08:00 hostname appname 1234 - [fields@32473 key="value"] message
+func NewLoggerEncoderSyslog(facility int, appname string) LoggerEncoderFunc {
+	hostname, _ := os.Hostname()
+	header := " " + GetString(hostname, "-") + " " + GetString(appname, filepath.Base(os.Args[0])) +
+		" " + strconv.Itoa(os.Getpid()) + " - "
+	return func(entry *LoggerStd) {
+		entry.Buffer = append(entry.Buffer, '<')
+		entry.Buffer = strconv.AppendInt(entry.Buffer, int64(facility*8+loggerSyslogSeverity[entry.Level]), 10)
+		entry.Buffer = append(entry.Buffer, '>', '1', ' ')
+		entry.Buffer = entry.Time.AppendFormat(entry.Buffer, "2006-01-02T15:04:05.000000Z07:00")
+		entry.Buffer = append(entry.Buffer, header...)
+		if len(entry.Keys) == 0 {
+			entry.Buffer = append(entry.Buffer, '-')
+		} else {
+			entry.Buffer = append(entry.Buffer, "[fields@32473"...)
+			for i := range entry.Keys {
+				entry.Buffer = append(entry.Buffer, ' ')
+				entry.Buffer = appendSyslogName(entry.Buffer, entry.Keys[i])
+				entry.Buffer = append(entry.Buffer, '=', '"')
+				loggerFormatWriteSyslog(entry, reflect.ValueOf(entry.Vals[i]))
+				entry.Buffer = append(entry.Buffer, '"')
+			}
+			entry.Buffer = append(entry.Buffer, ']')
+		}
+		if len(entry.Message) > 0 {
+			entry.Buffer = append(entry.Buffer, ' ')
+			entry.Buffer = append(entry.Buffer, entry.Message...)
+		}
+	}
+}
+
+// appendSyslogName 函数写入SD-NAME，最长32个字符，删除'=' ' ' ']' '"'和非打印字符。
+func appendSyslogName(buf []byte, name string) []byte {
+	n := 0
+	for i := 0; i < len(name) && n < 32; i++ {
+		b := name[i]
+		if b > 32 && b < 127 && b != '=' && b != ']' && b != '"' {
+			buf = append(buf, b)
+			n++
+		}
+	}
+	if n == 0 {
+		buf = append(buf, '_')
+	}
+	return buf
+}
+
+// loggerFormatWriteSyslog 函数使用loggerFormatWriteReflect写入PARAM-VALUE，删除字符串引号并转义']'。
+func loggerFormatWriteSyslog(entry *LoggerStd, iValue reflect.Value) {
+	pos := loggerFormatWriteUnquote(entry, iValue)
+	if bytes.IndexByte(entry.Buffer[pos:], ']') == -1 {
+		return
+	}
+	value := append([]byte(nil), entry.Buffer[pos:]...)
+	entry.Buffer = entry.Buffer[:pos]
+	for _, b := range value {
+		if b == ']' {
+			entry.Buffer = append(entry.Buffer, '\\')
+		}
+		entry.Buffer = append(entry.Buffer, b)
+	}
+}
+
+// loggerFormatWriteUnquote 函数使用loggerFormatWriteReflect写入值，删除字符串的引号，返回值写入的起始位置。
+func loggerFormatWriteUnquote(entry *LoggerStd, iValue reflect.Value) int {
+	pos := len(entry.Buffer)
+	loggerFormatWriteReflect(entry, iValue)
+	value := entry.Buffer[pos:]
+	if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+		copy(value, value[1:len(value)-1])
+		entry.Buffer = entry.Buffer[:len(entry.Buffer)-2]
+	}
+	return pos
+}
+
+// NewLoggerEncoderJournald 函数创建journald原生协议的日志编码函数，identifier为空使用程序名称。
+//
+// 日志级别写入PRIORITY，调用位置name、file、line写入CODE_FUNC、CODE_FILE、CODE_LINE，
+// 其他属性键转换为大写字母、数字和'_'组成的字段名称。
+func NewLoggerEncoderJournald(identifier string) LoggerEncoderFunc {
+	identifier = GetString(identifier, filepath.Base(os.Args[0]))
+	return func(entry *LoggerStd) {
+		entry.Buffer = append(entry.Buffer, "PRIORITY="...)
+		entry.Buffer = strconv.AppendInt(entry.Buffer, int64(loggerSyslogSeverity[entry.Level]), 10)
+		entry.Buffer = append(entry.Buffer, "\nSYSLOG_IDENTIFIER="...)
+		entry.Buffer = append(entry.Buffer, identifier...)
+		entry.Buffer = append(entry.Buffer, "\nSYSLOG_TIMESTAMP="...)
+		entry.Buffer = entry.Time.AppendFormat(entry.Buffer, time.RFC3339Nano)
+		entry.Buffer = append(entry.Buffer, '\n')
+		for i := range entry.Keys {
+			switch entry.Keys[i] {
+			case "name":
+				entry.Buffer = append(entry.Buffer, "CODE_FUNC"...)
+			case "file":
+				entry.Buffer = append(entry.Buffer, "CODE_FILE"...)
+			case "line":
+				entry.Buffer = append(entry.Buffer, "CODE_LINE"...)
+			default:
+				entry.Buffer = appendJournaldName(entry.Buffer, entry.Keys[i])
+			}
+			entry.Buffer = append(entry.Buffer, '=')
+			loggerFormatWriteUnquote(entry, reflect.ValueOf(entry.Vals[i]))
+			entry.Buffer = append(entry.Buffer, '\n')
+		}
+		appendJournaldField(entry, "MESSAGE", entry.Message)
+	}
+}
+
+// appendJournaldName 函数写入journald字段名称，小写字母转换为大写，其他字符转换为'_'，不能以'_'和数字开头。
+func appendJournaldName(buf []byte, name string) []byte {
+	if name == "" || name[0] == '_' || ('0' <= name[0] && name[0] <= '9') {
+		buf = append(buf, "FIELD_"...)
+	}
+	for i := 0; i < len(name); i++ {
+		b := name[i]
+		switch {
+		case 'a' <= b && b <= 'z':
+			buf = append(buf, b-'a'+'A')
+		case 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+			buf = append(buf, b)
+		default:
+			buf = append(buf, '_')
+		}
+	}
+	return buf
+}
+
+// appendJournaldField 函数写入一个journald字段，值包含换行时使用二进制长度格式。
+func appendJournaldField(entry *LoggerStd, name, value string) {
+	entry.Buffer = append(entry.Buffer, name...)
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\n' {
+			var size [8]byte
+			binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+			entry.Buffer = append(entry.Buffer, '\n')
+			entry.Buffer = append(entry.Buffer, size[:]...)
+			entry.Buffer = append(entry.Buffer, value...)
+			entry.Buffer = append(entry.Buffer, '\n')
+			return
+		}
+	}
+	entry.Buffer = append(entry.Buffer, '=')
+	entry.Buffer = append(entry.Buffer, value...)
+	entry.Buffer = append(entry.Buffer, '\n')
+}
+
+// loggerWriterSocket 定义每次写入发送一个消息的日志写入流，写入失败时重新连接一次。
+type loggerWriterSocket struct {
+	sync.Mutex
+	network string
+	address string
+	conn    net.Conn
+	// 流式连接使用RFC 6587 octet counting分帧
+	framing bool
+	buffer  []byte
+}
+
+// NewLoggerWriterSyslog 函数创建syslog日志写入流，需要配合NewLoggerEncoderSyslog使用。
+//
+// network可以为unixgram、unix、udp、tcp，address为空时使用unixgram连接/dev/log，
+// unix和tcp流式连接使用RFC 6587 octet counting分帧。
+func NewLoggerWriterSyslog(network, address string) (LoggerWriter, error) {
+	if address == "" {
+		network, address = "unixgram", "/dev/log"
+	}
+	w := &loggerWriterSocket{
+		network: network,
+		address: address,
+		framing: network == "unix" || network == "tcp" || network == "tcp4" || network == "tcp6",
+	}
+	return w, w.connect()
+}
+
+// NewLoggerWriterJournald 函数创建journald日志写入流，需要配合NewLoggerEncoderJournald使用。
+//
+// address为空使用"/run/systemd/journal/socket"，不支持超过socket数据报大小限制的日志。
+func NewLoggerWriterJournald(address string) (LoggerWriter, error) {
+	w := &loggerWriterSocket{
+		network: "unixgram",
+		address: GetString(address, "/run/systemd/journal/socket"),
+	}
+	return w, w.connect()
+}
+
+func (w *loggerWriterSocket) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	conn, err := net.Dial(w.network, w.address)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write 方法发送一条日志消息。
+func (w *loggerWriterSocket) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	data := p
+	if w.framing {
+		w.buffer = strconv.AppendInt(w.buffer[0:0], int64(len(p)), 10)
+		w.buffer = append(w.buffer, ' ')
+		w.buffer = append(w.buffer, p...)
+		data = w.buffer
+	}
+	if w.conn != nil {
+		_, err := w.conn.Write(data)
+		if err == nil {
+			return len(p), nil
+		}
+	}
+	err := w.connect()
+	if err != nil {
+		return 0, err
+	}
+	_, err = w.conn.Write(data)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync 方法实现LoggerWriter接口，消息已经直接发送。
+func (w *loggerWriterSocket) Sync() error {
+	return nil
+}
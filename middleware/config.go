@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/eudore/eudore"
+)
+
+// NewConfigFunc 函数创建一个配置查看处理函数，显示生效的配置、每个键的来源，secret值显示为"******"。
+//
+// 参数prefix指定显示的键前缀，format=text使用文本格式输出，默认输出json格式的配置树。
+func NewConfigFunc(c eudore.Config) eudore.HandlerFunc {
+	return func(ctx eudore.Context) {
+		prefix := eudore.GetString(ctx.GetQuery("prefix"), strings.Replace(ctx.GetParam("*"), "/", ".", -1))
+		var values []eudore.ConfigValue
+		for _, value := range eudore.GetConfigValues(c) {
+			if prefix == "" || value.Key == prefix || strings.HasPrefix(value.Key, prefix+".") {
+				values = append(values, value)
+			}
+		}
+
+		ctx.SetHeader("X-Eudore-Admin", "config")
+		if ctx.GetQuery("format") == "text" {
+			ctx.SetHeader(eudore.HeaderContentType, eudore.MimeTextPlainCharsetUtf8)
+			for _, value := range values {
+				fmt.Fprintf(ctx, "%s = %v # %s\n", value.Key, value.Value, value.Source)
+			}
+			return
+		}
+
+		ctx.SetHeader(eudore.HeaderContentType, eudore.MimeApplicationJSONUtf8)
+		encoder := json.NewEncoder(ctx)
+		encoder.SetIndent("", "\t")
+		encoder.Encode(newConfigTree(values))
+	}
+}
+
+// newConfigTree 函数将展开的配置键值按'.'还原成树结构，叶子节点为值和来源。
+func newConfigTree(values []eudore.ConfigValue) map[string]interface{} {
+	tree := make(map[string]interface{})
+	for _, value := range values {
+		node := tree
+		keys := strings.Split(value.Key, ".")
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := node[key].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				node[key] = next
+			}
+			node = next
+		}
+		node[keys[len(keys)-1]] = map[string]interface{}{
+			"value":  value.Value,
+			"source": value.Source,
+		}
+	}
+	return tree
+}
@@ -15,6 +15,7 @@ import (
 // 如果Access-Control-Allow-Methods header为空，设置为*。
 //
 // Cors中间件注册不是全局中间件时，需要最后注册一次Options /*或404方法，否则Options请求匹配了默认404没有经过Cors中间件处理。
+// 路由器自动响应Options请求时，需要在注册Cors中间件后注册一次Allow方法，例如app.AddHandler("Allow", "", eudore.HandlerRouterOptions)，否则自动响应没有经过Cors中间件处理。
 func NewCorsFunc(origins []string, headers map[string]string) eudore.HandlerFunc {
 	if len(origins) == 0 {
 		origins = []string{"*"}
@@ -61,6 +61,15 @@ Cache
 example:
 	app.AddMiddleware(middleware.NewCacheFunc(time.Second*10, app.Context))
 
+Config
+
+创建配置查看处理函数，显示生效的配置树、每个键的来源，secret值显示为"******"。
+
+参数:
+	eudore.Config    查看的配置对象
+example:
+	app.AnyFunc("/eudore/debug/config/*", middleware.NewConfigFunc(app.Config))
+
 ContextWarp
 
 使中间件之后的处理函数使用的eudore.Context对象为新的Context
@@ -90,6 +99,7 @@ example:
 	}))
 
 Cors中间件注册不是全局中间件时，需要最后注册一次Options /*或404方法，否则Options请求匹配了默认404没有经过Cors中间件处理。
+路由器自动响应Options请求时，需要在注册Cors中间件后注册一次Allow方法，例如app.AddHandler("Allow", "", eudore.HandlerRouterOptions)，否则自动响应没有经过Cors中间件处理。
 
 Csrf
 
@@ -121,7 +131,7 @@ example:
 
 Gzip
 
-对请求响应body使用gzip压缩
+对请求响应body使用gzip压缩，Upgrade请求和text/event-stream响应不会压缩
 
 参数:
 	int    gzip压缩等级，非法值设置为5
@@ -138,6 +148,27 @@ Logger
 example:
 	app.AddMiddleware(middleware.NewLoggerFunc(app, "route"))
 
+LoggerLevels
+
+使用日志级别注册表按路由前缀设置请求的日志级别，并注入运行时修改日志级别的管理接口
+
+参数:
+	*eudore.LoggerLevels    日志级别注册表，为空使用eudore.DefaultLoggerLevels。
+	eudore.Router           为注入日志级别管理路由的路由器，需要添加认证中间件。
+example:
+	admin := app.Group("/eudore/debug")
+	admin.AddMiddleware(middleware.NewBasicAuthFunc(map[string]string{"root": "111"}))
+	app.AddMiddleware(middleware.NewLoggerLevelsFunc(nil, admin))
+
+Metrics
+
+按路由和方法记录请求数量、处理中请求数量、请求耗时和响应大小，使用Prometheus文本格式输出指标
+
+参数:
+	eudore.Router    为注入GET /metrics指标获取路由的路由器。
+example:
+	app.AddMiddleware(middleware.NewMetricsFunc(app.Group("/eudore/debug")))
+
 Rate
 
 实现请求令牌桶限流
@@ -247,6 +278,15 @@ Timeout
 
 实现难点：写入中超时状态码异常、panic栈无法捕捉信息异常、http.Header并发读写、sync.Pool回收了Context、Context数据竟态检测
 
+Tracer
+
+解析W3C traceparent和tracestate Header创建请求Span，给请求日志添加trace-id和span-id属性，请求结束时导出Span
+
+参数:
+	eudore.TraceExporter    Span导出对象，测试可以使用eudore.NewTraceExporterMemory()
+example:
+	app.AddMiddleware(middleware.NewTracerFunc(eudore.NewTraceExporterMemory()))
+
 */
 package middleware // import "github.com/eudore/eudore/middleware"
 
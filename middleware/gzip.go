@@ -39,34 +39,66 @@ func NewGzipFunc(level int) eudore.HandlerFunc {
 		ctx.SetHeader(eudore.HeaderVary, eudore.HeaderAcceptEncoding)
 		ctx.Next()
 
-		w.Writer.Close()
+		if !w.passthrough {
+			w.Writer.Close()
+		}
 		pool.Put(w.Writer)
 	}
 
 }
 
 // gzipResponse 定义Gzip响应，实现ResponseWriter接口
+//
+// 第一次写入时如果响应Content-Type是text/event-stream，不压缩直接写入响应，避免缓冲事件流。
 type gzipResponse struct {
 	eudore.ResponseWriter
-	Writer *gzip.Writer
+	Writer      *gzip.Writer
+	checked     bool
+	passthrough bool
+}
+
+// WriteHeader 实现ResponseWriter中的WriteHeader方法。
+func (w *gzipResponse) WriteHeader(code int) {
+	w.check()
+	w.ResponseWriter.WriteHeader(code)
 }
 
 // Write 实现ResponseWriter中的Write方法。
-func (w gzipResponse) Write(data []byte) (int, error) {
+func (w *gzipResponse) Write(data []byte) (int, error) {
+	w.check()
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
 	return w.Writer.Write(data)
 }
 
 // Flush 实现ResponseWriter中的Flush方法。
-func (w gzipResponse) Flush() {
-	w.Writer.Flush()
+func (w *gzipResponse) Flush() {
+	w.check()
+	if !w.passthrough {
+		w.Writer.Flush()
+	}
 	w.ResponseWriter.Flush()
 }
 
+func (w *gzipResponse) check() {
+	if w.checked {
+		return
+	}
+	w.checked = true
+	h := w.ResponseWriter.Header()
+	if strings.HasPrefix(h.Get(eudore.HeaderContentType), eudore.MimeTextEventStream) {
+		w.passthrough = true
+		h.Del(eudore.HeaderContentEncoding)
+	}
+}
+
 func shouldCompress(ctx eudore.Context) bool {
 	h := ctx.Request().Header
 	if !strings.Contains(h.Get(eudore.HeaderAcceptEncoding), "gzip") ||
 		strings.Contains(h.Get(eudore.HeaderConnection), "Upgrade") ||
-		strings.Contains(h.Get(eudore.HeaderContentType), "text/event-stream") {
+		strings.Contains(h.Get(eudore.HeaderAccept), eudore.MimeTextEventStream) ||
+		strings.Contains(h.Get(eudore.HeaderContentType), eudore.MimeTextEventStream) {
 
 		return false
 	}
@@ -26,3 +26,62 @@ func NewLoggerLevelFunc(fn func(ctx eudore.Context) int) eudore.HandlerFunc {
 		}
 	}
 }
+
+// NewLoggerLevelsFunc 函数创建一个按路由设置请求日志级别的中间件，使用请求的route参数匹配日志级别注册表，如果没有route参数使用请求路径。
+//
+// 如果router非空，注入日志级别管理接口：
+//	GET /logger/levels	获取全部设置的日志级别
+//	PUT /logger/levels?name=/api/orders/*&level=debug	设置名称或前缀的日志级别
+//	DELETE /logger/levels?name=/api/orders/*	删除名称或前缀的日志级别
+//
+// 管理接口可以修改全部日志级别，router需要添加认证中间件，例如NewBasicAuthFunc。
+func NewLoggerLevelsFunc(levels *eudore.LoggerLevels, router eudore.Router) eudore.HandlerFunc {
+	if levels == nil {
+		levels = eudore.DefaultLoggerLevels
+	}
+	if router != nil {
+		l := loggerLevels{levels}
+		router.GetFunc("/logger/levels", l.list)
+		router.PutFunc("/logger/levels", l.put)
+		router.DeleteFunc("/logger/levels", l.delete)
+	}
+	return func(ctx eudore.Context) {
+		level, ok := levels.Match(eudore.GetString(ctx.GetParam("route"), ctx.Path()))
+		if ok {
+			log := ctx.Logger().WithFields(nil, nil)
+			log.SetLevel(level)
+			ctx.SetLogger(log)
+		}
+	}
+}
+
+type loggerLevels struct {
+	*eudore.LoggerLevels
+}
+
+func (l loggerLevels) list(ctx eudore.Context) interface{} {
+	ctx.SetHeader("X-Eudore-Admin", "logger")
+	levels := make(map[string]string)
+	for name, level := range l.List() {
+		levels[name] = level.String()
+	}
+	return levels
+}
+
+func (l loggerLevels) put(ctx eudore.Context) error {
+	var level eudore.LoggerLevel
+	err := level.UnmarshalText([]byte(ctx.GetQuery("level")))
+	if err != nil {
+		return err
+	}
+	name := ctx.GetQuery("name")
+	ctx.Infof("%s set logger level %s: %s", ctx.RealIP(), name, level)
+	l.Set(name, level)
+	return nil
+}
+
+func (l loggerLevels) delete(ctx eudore.Context) {
+	name := ctx.GetQuery("name")
+	ctx.Infof("%s delete logger level %s", ctx.RealIP(), name)
+	l.Delete(name)
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eudore/eudore"
+)
+
+// DefaultMetricsDurationBuckets 定义请求耗时直方图默认的桶，单位秒。
+var DefaultMetricsDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultMetricsSizeBuckets 定义响应大小直方图默认的桶，单位字节。
+var DefaultMetricsSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// Metrics 定义请求指标数据，按路由和方法记录请求数量、处理中请求数量、请求耗时和响应大小。
+//
+// 路由使用eudore.ParamRoute参数，eudore.RouterAllMethod以外的方法使用"OTHER"记录，避免任意请求方法创建指标数据；
+// 路由和方法第一次出现时创建指标数据，之后记录只使用原子操作不分配内存。
+type Metrics struct {
+	sync.RWMutex
+	DurationBuckets []float64
+	SizeBuckets     []float64
+	routes          map[string]map[string]*metricsData
+}
+
+// metricsData 定义一个路由方法的指标数据，状态码按1xx-5xx分类计数。
+type metricsData struct {
+	codes     [6]uint64
+	durations metricsHistogram
+	sizes     metricsHistogram
+	inflight  int64
+}
+
+// metricsHistogram 定义直方图数据，counts最后一个值为超出全部桶的数量。
+type metricsHistogram struct {
+	sum    uint64
+	counts []uint64
+}
+
+// NewMetricsFunc 函数创建一个请求指标记录处理函数，如果router非空注入GET /metrics指标获取路由。
+func NewMetricsFunc(router eudore.Router) eudore.HandlerFunc {
+	return NewMetrics().NewMetricsFunc(router)
+}
+
+// NewMetrics 函数创建请求指标对象，使用默认的直方图桶。
+func NewMetrics() *Metrics {
+	return &Metrics{
+		DurationBuckets: DefaultMetricsDurationBuckets,
+		SizeBuckets:     DefaultMetricsSizeBuckets,
+		routes:          make(map[string]map[string]*metricsData),
+	}
+}
+
+// NewMetricsFunc 方法创建请求指标记录处理函数，如果router非空注入GET /metrics指标获取路由。
+func (m *Metrics) NewMetricsFunc(router eudore.Router) eudore.HandlerFunc {
+	if router != nil {
+		router.GetFunc("/metrics", m.HandleMetrics)
+	}
+	return func(ctx eudore.Context) {
+		data := m.getData(ctx.GetParam(eudore.ParamRoute), getMetricsMethod(ctx.Method()))
+		atomic.AddInt64(&data.inflight, 1)
+		now := time.Now()
+		ctx.Next()
+		duration := time.Since(now)
+		atomic.AddInt64(&data.inflight, -1)
+
+		status := ctx.Response().Status() / 100
+		if status < 1 || status > 5 {
+			status = 0
+		}
+		size := ctx.Response().Size()
+		atomic.AddUint64(&data.codes[status], 1)
+		data.durations.observe(m.DurationBuckets, duration.Seconds(), uint64(duration))
+		data.sizes.observe(m.SizeBuckets, float64(size), uint64(size))
+	}
+}
+
+// getMetricsMethod 函数返回指标记录的方法标签，非标准方法返回"OTHER"。
+func getMetricsMethod(method string) string {
+	for _, i := range eudore.RouterAllMethod {
+		if i == method {
+			return method
+		}
+	}
+	return "OTHER"
+}
+
+func (m *Metrics) getData(route, method string) *metricsData {
+	m.RLock()
+	data := m.routes[route][method]
+	m.RUnlock()
+	if data != nil {
+		return data
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	methods := m.routes[route]
+	if methods == nil {
+		methods = make(map[string]*metricsData)
+		m.routes[route] = methods
+	}
+	data = methods[method]
+	if data == nil {
+		data = &metricsData{
+			durations: metricsHistogram{counts: make([]uint64, len(m.DurationBuckets)+1)},
+			sizes:     metricsHistogram{counts: make([]uint64, len(m.SizeBuckets)+1)},
+		}
+		methods[method] = data
+	}
+	return data
+}
+
+func (h *metricsHistogram) observe(buckets []float64, val float64, sum uint64) {
+	i := sort.SearchFloat64s(buckets, val)
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.sum, sum)
+}
+
+// HandleMetrics 方法使用Prometheus文本格式输出全部请求指标。
+func (m *Metrics) HandleMetrics(ctx eudore.Context) {
+	type metricsItem struct {
+		labels string
+		data   *metricsData
+	}
+	m.RLock()
+	items := make([]metricsItem, 0, len(m.routes))
+	for route, methods := range m.routes {
+		for method, data := range methods {
+			labels := "method=\"" + metricsEscape(method) + "\",route=\"" + metricsEscape(route) + "\""
+			items = append(items, metricsItem{labels, data})
+		}
+	}
+	m.RUnlock()
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].labels < items[j].labels
+	})
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("# HELP eudore_http_requests_total Total number of HTTP requests.\n")
+	buf.WriteString("# TYPE eudore_http_requests_total counter\n")
+	for _, item := range items {
+		for i := 1; i < len(item.data.codes); i++ {
+			count := atomic.LoadUint64(&item.data.codes[i])
+			if count != 0 {
+				metricsWriteValue(buf, "eudore_http_requests_total", item.labels+",code=\""+strconv.Itoa(i)+"xx\"", float64(count))
+			}
+		}
+	}
+	buf.WriteString("# HELP eudore_http_requests_in_flight Number of HTTP requests currently being served.\n")
+	buf.WriteString("# TYPE eudore_http_requests_in_flight gauge\n")
+	for _, item := range items {
+		metricsWriteValue(buf, "eudore_http_requests_in_flight", item.labels, float64(atomic.LoadInt64(&item.data.inflight)))
+	}
+	buf.WriteString("# HELP eudore_http_request_duration_seconds HTTP request latencies in seconds.\n")
+	buf.WriteString("# TYPE eudore_http_request_duration_seconds histogram\n")
+	for _, item := range items {
+		item.data.durations.write(buf, "eudore_http_request_duration_seconds", item.labels, m.DurationBuckets, 1e-9)
+	}
+	buf.WriteString("# HELP eudore_http_response_size_bytes HTTP response sizes in bytes.\n")
+	buf.WriteString("# TYPE eudore_http_response_size_bytes histogram\n")
+	for _, item := range items {
+		item.data.sizes.write(buf, "eudore_http_response_size_bytes", item.labels, m.SizeBuckets, 1)
+	}
+
+	ctx.SetHeader("X-Eudore-Admin", "metrics")
+	ctx.SetHeader(eudore.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	ctx.Write(buf.Bytes())
+}
+
+// write 方法输出直方图的累计桶、总和和数量，scale用于转换总和的单位。
+func (h *metricsHistogram) write(buf *bytes.Buffer, name, labels string, buckets []float64, scale float64) {
+	var count uint64
+	for i, bucket := range buckets {
+		count += atomic.LoadUint64(&h.counts[i])
+		metricsWriteValue(buf, name+"_bucket", labels+",le=\""+strconv.FormatFloat(bucket, 'g', -1, 64)+"\"", float64(count))
+	}
+	count += atomic.LoadUint64(&h.counts[len(buckets)])
+	metricsWriteValue(buf, name+"_bucket", labels+",le=\"+Inf\"", float64(count))
+	metricsWriteValue(buf, name+"_sum", labels, float64(atomic.LoadUint64(&h.sum))*scale)
+	metricsWriteValue(buf, name+"_count", labels, float64(count))
+}
+
+func metricsWriteValue(buf *bytes.Buffer, name, labels string, val float64) {
+	buf.WriteString(name)
+	buf.WriteByte('{')
+	buf.WriteString(labels)
+	buf.WriteString("} ")
+	buf.WriteString(strconv.FormatFloat(val, 'g', -1, 64))
+	buf.WriteByte('\n')
+}
+
+var metricsEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func metricsEscape(s string) string {
+	return metricsEscaper.Replace(s)
+}
@@ -11,6 +11,8 @@ import (
 // NewRouterFunc 函数创建一个路由器中间件，将根据路由路径匹配执行对应的多个处理函数。
 //
 // 如果key为"router"，val类型为eudore.Router，则使用改路由器处理请求。
+//
+// 默认路由器的404、405和自动响应OPTIONS的Allow处理都为空，未匹配的请求继续执行后续处理函数。
 func NewRouterFunc(data map[string]interface{}) eudore.HandlerFunc {
 	router, ok := data["router"].(eudore.Router)
 	delete(data, "router")
@@ -18,6 +20,7 @@ func NewRouterFunc(data map[string]interface{}) eudore.HandlerFunc {
 		router = eudore.NewRouterStd(nil)
 		router.AddHandler("404", "", eudore.HandlerEmpty)
 		router.AddHandler("405", "", eudore.HandlerEmpty)
+		router.AddHandler("Allow", "", eudore.HandlerEmpty)
 	}
 
 	for k, v := range data {
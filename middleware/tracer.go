@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/eudore/eudore"
+)
+
+// NewTracerFunc 函数创建一个链路追踪中间件，解析请求的traceparent和tracestate Header创建server Span。
+//
+// 请求traceparent无效时创建新的Trace，Span保存到请求context.Context中，使用eudore.GetTraceSpan(ctx.GetContext())获取，
+// 请求日志添加trace-id和span-id属性，响应设置X-Trace-Id Header，请求结束时Span导出到exporter。
+//
+// 使用eudore.NewTraceClient创建的http.Client发送请求时，需要使用req.WithContext(ctx.GetContext())传播Span。
+func NewTracerFunc(exporter eudore.TraceExporter) eudore.HandlerFunc {
+	return func(ctx eudore.Context) {
+		parent, err := eudore.ParseTraceparent(ctx.GetHeader(eudore.HeaderTraceparent))
+		if err == nil {
+			parent.TraceState = ctx.GetHeader(eudore.HeaderTracestate)
+		}
+		span := eudore.NewTraceSpan(parent, ctx.Method()+" "+eudore.GetString(ctx.GetParam("route"), ctx.Path()), exporter)
+		span.Kind = "server"
+		span.SetAttribute("http.method", ctx.Method())
+		span.SetAttribute("http.path", ctx.Path())
+		span.SetAttribute("http.host", ctx.Host())
+
+		ctx.WithContext(eudore.ContextWithTraceSpan(ctx.GetContext(), span))
+		ctx.SetHeader(eudore.HeaderXTraceID, span.TraceID)
+		ctx.SetLogger(ctx.Logger().WithFields([]string{"trace-id", "span-id"}, []interface{}{span.TraceID, span.SpanID}).WithFields(nil, nil))
+		ctx.Next()
+
+		status := ctx.Response().Status()
+		span.SetAttribute("http.status_code", status)
+		if status >= 500 {
+			err := ctx.Err()
+			if err == nil {
+				err = errors.New(http.StatusText(status))
+			}
+			span.SetError(err)
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,494 @@
+package eudore
+
+// openapi 根据注册的路由生成OpenAPI 3.0文档，文档格式参考https://spec.openapis.org/oas/v3.0.3。
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OpenAPI 定义OpenAPI 3.0文档，使用NewRouterCoreOpenAPI函数包装路由器核心在注册路由时生成文档。
+//
+// 路由参数name或action作为operationId，controllergroup作为tags，openapi=off的路由不会生成文档。
+type OpenAPI struct {
+	sync.RWMutex `json:"-"`
+	OpenAPI      string                                  `json:"openapi"`
+	Info         OpenAPIInfo                             `json:"info"`
+	Servers      []OpenAPIServer                         `json:"servers,omitempty"`
+	Paths        map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components   OpenAPIComponents                       `json:"components"`
+	types        map[reflect.Type]string
+}
+
+// OpenAPIInfo 定义文档的基本信息。
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIServer 定义文档的服务器地址。
+type OpenAPIServer struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIOperation 定义一个路由方法的文档。
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter 定义路径或query参数。
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody 定义请求body。
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse 定义一个响应。
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType 定义一种mime类型的数据格式。
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema,omitempty"`
+}
+
+// OpenAPIComponents 定义文档中复用的结构体schema。
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas,omitempty"`
+}
+
+// OpenAPISchema 定义数据的schema。
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Minimum              *int64                    `json:"minimum,omitempty"`
+	Maximum              *int64                    `json:"maximum,omitempty"`
+	MinLength            *int64                    `json:"minLength,omitempty"`
+	MaxLength            *int64                    `json:"maxLength,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+// routerCoreOpenAPI 在注册路由时生成OpenAPI文档。
+type routerCoreOpenAPI struct {
+	RouterCore
+	doc *OpenAPI
+}
+
+// routerCoreWalker 定义路由器核心遍历已注册路由的方法，routerCoreStd实现该方法。
+type routerCoreWalker interface {
+	walkRoutes(func(method, path string, hs HandlerFuncs))
+}
+
+// NewOpenAPI 函数创建一个OpenAPI 3.0文档。
+func NewOpenAPI(title, version string) *OpenAPI {
+	return &OpenAPI{
+		OpenAPI:    "3.0.3",
+		Info:       OpenAPIInfo{Title: title, Version: version},
+		Paths:      make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{Schemas: make(map[string]*OpenAPISchema)},
+		types:      make(map[reflect.Type]string),
+	}
+}
+
+// NewRouterCoreOpenAPI 函数创建一个生成OpenAPI文档的路由器核心，默认使用eudore.RouterCoreStd为核心。
+//
+// 注册路由时调用OpenAPI.AddOperation方法添加文档，core中已经注册的路由需要core实现routerCoreWalker才会添加，
+// RouterCoreStd和包装它的Redirect、Lock核心实现该方法。
+//
+// 包装后的核心没有lookPathFold方法，NewRouterCoreRedirect包装OpenAPI核心时无法忽略大小写匹配，
+// 需要使用NewRouterCoreOpenAPI(NewRouterCoreRedirect(nil), doc)的顺序。
+func NewRouterCoreOpenAPI(core RouterCore, doc *OpenAPI) RouterCore {
+	if core == nil {
+		core = NewRouterCoreStd()
+	}
+	walker, ok := core.(routerCoreWalker)
+	if ok {
+		walker.walkRoutes(doc.AddOperation)
+	}
+	return &routerCoreOpenAPI{RouterCore: core, doc: doc}
+}
+
+// HandleFunc 方法注册路由并添加OpenAPI文档。
+func (r *routerCoreOpenAPI) HandleFunc(method, path string, hs HandlerFuncs) {
+	r.RouterCore.HandleFunc(method, path, hs)
+	r.doc.AddOperation(method, path, hs)
+}
+
+// HandleHTTP 方法返回OpenAPI json文档。
+func (doc *OpenAPI) HandleHTTP(ctx Context) {
+	doc.RLock()
+	defer doc.RUnlock()
+	ctx.WriteJSON(doc)
+}
+
+// AddOperation 方法使用路由方法、路径和处理函数添加文档，register=off时删除文档。
+//
+// 路径参数和通配符参数的校验规则转换成参数schema，RPC处理函数的请求和响应类型转换成schema，
+// GET和HEAD方法的请求类型转换成query参数，其他方法的请求类型转换成json请求body。
+func (doc *OpenAPI) AddOperation(method, path string, hs HandlerFuncs) {
+	var methods []string
+	switch method {
+	case MethodAny:
+		methods = RouterAnyMethod
+	case MethodGet, MethodPost, MethodPut, MethodDelete, MethodHead, MethodPatch, MethodOptions, MethodTrace:
+		methods = []string{method}
+	default:
+		return
+	}
+	params := NewParamsRoute(path)
+	if params.Get(ParamOpenAPI) == "off" {
+		return
+	}
+
+	doc.Lock()
+	defer doc.Unlock()
+	route, parameters := doc.getPathParameters(params.Get(ParamRoute))
+	if params.Get(ParamRegister) == "off" || hs == nil {
+		for _, method := range methods {
+			delete(doc.Paths[route], strings.ToLower(method))
+		}
+		if len(doc.Paths[route]) == 0 {
+			delete(doc.Paths, route)
+		}
+		return
+	}
+
+	typeIn, typeOut := getHandlerRPCTypes(hs)
+	for _, method := range methods {
+		op := &OpenAPIOperation{
+			OperationID: params.Get(ParamName),
+			Parameters:  append([]*OpenAPIParameter(nil), parameters...),
+			Responses:   map[string]*OpenAPIResponse{"200": {Description: "OK"}},
+		}
+		if op.OperationID == "" {
+			op.OperationID = params.Get(ParamAction)
+		}
+		if group := params.Get(ParamControllerGroup); group != "" {
+			op.Tags = []string{group}
+		}
+		if typeIn != nil {
+			if method == MethodGet || method == MethodHead {
+				op.Parameters = append(op.Parameters, doc.getQueryParameters(typeIn)...)
+			} else {
+				op.RequestBody = &OpenAPIRequestBody{
+					Required: true,
+					Content:  map[string]*OpenAPIMediaType{MimeApplicationJSON: {Schema: doc.getSchema(typeIn)}},
+				}
+			}
+		}
+		if typeOut != nil && typeOut != typeInterface {
+			op.Responses["200"].Content = map[string]*OpenAPIMediaType{MimeApplicationJSON: {Schema: doc.getSchema(typeOut)}}
+		}
+
+		if doc.Paths[route] == nil {
+			doc.Paths[route] = make(map[string]*OpenAPIOperation)
+		}
+		doc.Paths[route][strings.ToLower(method)] = op
+	}
+}
+
+// getPathParameters 方法将路由路径转换成OpenAPI路径模板和路径参数。
+func (doc *OpenAPI) getPathParameters(route string) (string, []*OpenAPIParameter) {
+	var paths []string
+	var parameters []*OpenAPIParameter
+	add := func(name, check string) {
+		paths = append(paths, "{"+name+"}")
+		parameters = append(parameters, &OpenAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   newOpenAPISchemaCheck(check),
+		})
+	}
+	for _, path := range getSplitPath(route) {
+		switch path[0] {
+		case ':':
+			add(split2byte(path[1:], '|'))
+		case '*':
+			name, check := split2byte(path[1:], '|')
+			if name == "" {
+				name = "*"
+			}
+			add(name, check)
+			parameters[len(parameters)-1].Description = "wildcard"
+		case '{':
+			for key := path; key != ""; {
+				if key[0] != '{' {
+					pos := strings.IndexByte(key, '{')
+					if pos == -1 {
+						pos = len(key)
+					}
+					paths = append(paths, key[:pos])
+					key = key[pos:]
+					continue
+				}
+				var block string
+				block, key = getSplitPathBlock(key)
+				add(split2byte(block, '|'))
+			}
+		default:
+			paths = append(paths, path)
+		}
+	}
+	return strings.Join(paths, ""), parameters
+}
+
+// getQueryParameters 方法将结构体字段转换成query参数，名称使用DefaultConvertFormTags。
+func (doc *OpenAPI) getQueryParameters(iType reflect.Type) []*OpenAPIParameter {
+	for iType.Kind() == reflect.Ptr {
+		iType = iType.Elem()
+	}
+	if iType.Kind() != reflect.Struct {
+		return nil
+	}
+	var parameters []*OpenAPIParameter
+	for i := 0; i < iType.NumField(); i++ {
+		field := iType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		for _, tag := range DefaultConvertFormTags {
+			if val := field.Tag.Get(tag); val != "" {
+				name = val
+				break
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		schema, required := doc.getFieldSchema(field)
+		parameters = append(parameters, &OpenAPIParameter{
+			Name:     name,
+			In:       "query",
+			Required: required,
+			Schema:   schema,
+		})
+	}
+	return parameters
+}
+
+// getSchema 方法将类型转换成schema，命名结构体保存到components并返回引用。
+func (doc *OpenAPI) getSchema(iType reflect.Type) *OpenAPISchema {
+	for iType.Kind() == reflect.Ptr {
+		iType = iType.Elem()
+	}
+	if iType == typeTimeTime {
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	}
+	switch iType.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64, reflect.Uintptr:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if iType.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: doc.getSchema(iType.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: doc.getSchema(iType.Elem())}
+	case reflect.Struct:
+		if iType.Name() == "" {
+			return doc.getStructSchema(iType)
+		}
+		name, ok := doc.types[iType]
+		if !ok {
+			name = iType.Name()
+			for i := 2; doc.Components.Schemas[name] != nil; i++ {
+				name = iType.Name() + strconv.Itoa(i)
+			}
+			doc.types[iType] = name
+			// 先保存占位schema，避免递归类型无限解析。
+			schema := &OpenAPISchema{}
+			doc.Components.Schemas[name] = schema
+			*schema = *doc.getStructSchema(iType)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	}
+	return &OpenAPISchema{}
+}
+
+// getStructSchema 方法将结构体转换成object schema，字段名称使用json tag，匿名结构体字段会展开。
+func (doc *OpenAPI) getStructSchema(iType reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	for i := 0; i < iType.NumField(); i++ {
+		field := iType.Field(i)
+		name, _ := split2byte(field.Tag.Get("json"), ',')
+		if name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			embed := doc.getStructSchema(fieldType)
+			for key, val := range embed.Properties {
+				schema.Properties[key] = val
+			}
+			schema.Required = append(schema.Required, embed.Required...)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fieldSchema, required := doc.getFieldSchema(field)
+		schema.Properties[name] = fieldSchema
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// getFieldSchema 方法返回结构体字段的schema，validate tag转换成schema约束，nozero规则表示字段必须存在。
+func (doc *OpenAPI) getFieldSchema(field reflect.StructField) (*OpenAPISchema, bool) {
+	schema := doc.getSchema(field.Type)
+	tags := field.Tag.Get("validate")
+	if tags == "" || schema.Ref != "" {
+		return schema, tags != "" && strings.Contains(","+tags+",", ",nozero,")
+	}
+	var required bool
+	for _, tag := range strings.Split(tags, ",") {
+		if tag == "nozero" {
+			required = true
+		}
+		setOpenAPISchemaCheck(schema, tag)
+	}
+	return schema, required
+}
+
+// newOpenAPISchemaCheck 函数将路由参数的校验规则转换成schema，isnum、min和max规则的参数类型为integer。
+func newOpenAPISchemaCheck(check string) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "string"}
+	if check == "" {
+		return schema
+	}
+	if check[0] == '^' && check[len(check)-1] == '$' {
+		check = "regexp:" + check
+	}
+	switch name, _ := split2byte(check, ':'); name {
+	case "isnum", "min", "max":
+		schema.Type, schema.Format = "integer", "int64"
+	}
+	setOpenAPISchemaCheck(schema, check)
+	return schema
+}
+
+// setOpenAPISchemaCheck 函数将一个校验规则设置为schema约束，未知规则会被忽略。
+func setOpenAPISchemaCheck(schema *OpenAPISchema, check string) {
+	name, args := split2byte(check, ':')
+	switch name {
+	case "nozero":
+		if schema.Type == "string" {
+			schema.MinLength = newOpenAPIInt(1)
+		}
+	case "isnum":
+		if schema.Type == "string" {
+			schema.Pattern = "^-?[0-9]+$"
+		}
+	case "min", "max":
+		// minimum和maximum只用于数值类型
+		num, err := strconv.ParseInt(args, 10, 64)
+		if err != nil || (schema.Type != "integer" && schema.Type != "number") {
+			return
+		}
+		if name == "min" {
+			schema.Minimum = newOpenAPIInt(num)
+		} else {
+			schema.Maximum = newOpenAPIInt(num)
+		}
+	case "len":
+		var flag string
+		if args != "" && strings.IndexByte("><=", args[0]) != -1 {
+			flag, args = args[:1], args[1:]
+		}
+		num, err := strconv.ParseInt(args, 10, 64)
+		if err != nil {
+			return
+		}
+		switch flag {
+		case ">":
+			schema.MinLength = newOpenAPIInt(num + 1)
+		case "<":
+			schema.MaxLength = newOpenAPIInt(num - 1)
+		default:
+			schema.MinLength, schema.MaxLength = newOpenAPIInt(num), newOpenAPIInt(num)
+		}
+	case "regexp":
+		schema.Pattern = args
+	}
+}
+
+func newOpenAPIInt(num int64) *int64 {
+	return &num
+}
+
+// NewOpenAPIUIHandler 函数创建一个Swagger UI页面处理函数，页面加载url指定的OpenAPI文档。
+//
+// assets为swagger-ui-dist静态资源目录地址，页面会加载其中的swagger-ui.css和swagger-ui-bundle.js，
+// 需要使用固定版本或者自托管的地址，例如https://unpkg.com/swagger-ui-dist@5.17.14。
+func NewOpenAPIUIHandler(url, assets string) HandlerFunc {
+	assets = strings.TrimSuffix(assets, "/")
+	page := fmt.Sprintf(openapiUIPage, assets, assets, strconv.Quote(url))
+	return func(ctx Context) {
+		ctx.SetHeader(HeaderContentType, MimeTextHTMLCharsetUtf8)
+		ctx.WriteString(page)
+	}
+}
+
+const openapiUIPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>OpenAPI</title>
+<link rel="stylesheet" href="%s/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="%s/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({url: %s, dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>
+`
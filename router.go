@@ -3,7 +3,10 @@ package eudore
 // Router对象用于定义请求的路由器
 
 import (
+	"bytes"
 	"fmt"
+	"net/url"
+	"path"
 	"reflect"
 	"runtime"
 	"strings"
@@ -37,6 +40,8 @@ RouterCore has four router cores to implement the following functions:
     Get all registered routing rule information (RouterCoreBebug implementation)
     Routing rule matching based on Host (implemented by RouterCoreHost)
     Allows dynamic addition and deletion of router rules at runtime (RouterCoreStd implementation, the outer layer requires RouterCoreLock packaging layer)
+    Automatically redirect trailing slash, cleaned and case-insensitive paths when not matched (implemented by RouterCoreRedirect)
+    Automatically respond OPTIONS requests and HEAD requests use GET handlers (RouterCoreStd implementation)
 
 Router 接口分为RouterCore和RouterMethod，RouterCore实现路由器匹配算法和逻辑，RouterMethod实现路由规则注册的封装。
 
@@ -64,6 +69,8 @@ RouterCore拥有四种路由器核心实现下列功能：
     获取注册的全部路由规则信息(RouterCoreBebug实现)
     基于Host进行路由规则匹配(RouterCoreHost实现)
     允许运行时进行动态增删路由器规则(RouterCoreStd实现，外层需要RouterCoreLock包装一层)
+    未匹配时自动重定向结尾斜杠、清理后和忽略大小写的路径(RouterCoreRedirect实现)
+    自动响应OPTIONS请求，HEAD请求使用GET处理(RouterCoreStd实现)
 */
 type Router interface {
 	RouterCore
@@ -81,6 +88,7 @@ type Router interface {
 	DeleteFunc(string, ...interface{})
 	HeadFunc(string, ...interface{})
 	PatchFunc(string, ...interface{})
+	URL(string, ...interface{}) (string, error)
 }
 
 // The RouterCore interface performs registration of the route and matches a request and returns the handler.
@@ -105,6 +113,13 @@ type RouterStd struct {
 	Middlewares     *middlewareTree      `alias:"middlewares"`
 	Print           func(...interface{}) `alias:"print"`
 	params          *Params              `alias:"params"`
+	names           *routerNames         `alias:"names"`
+}
+
+// routerNames 定义RouterStd和全部Group路由器共享的命名路由。
+type routerNames struct {
+	sync.RWMutex
+	routes map[string]string
 }
 
 // HandlerRouter405 函数定义默认405处理
@@ -116,6 +131,33 @@ func HandlerRouter405(ctx Context) {
 	ctx.WriteString(page405)
 }
 
+// HandlerRouterOptions 函数定义默认自动响应OPTIONS请求的处理，返回路由允许的方法。
+func HandlerRouterOptions(ctx Context) {
+	ctx.SetHeader(HeaderAllow, ctx.GetParam(ParamAllow))
+	ctx.WriteHeader(204)
+}
+
+// HandlerRouterHead 函数定义HEAD请求使用GET处理时丢弃响应body。
+func HandlerRouterHead(ctx Context) {
+	ctx.SetResponse(&responseWriterHead{ResponseWriter: ctx.Response()})
+}
+
+// HandlerRouterRedirect 函数定义默认的路径修正重定向处理，重定向到route参数的路径并保留请求query。
+//
+// GET和HEAD请求响应301，其他方法响应308，Location Header中路径的每一段都会使用url.PathEscape转义。
+func HandlerRouterRedirect(ctx Context) {
+	location := getRedirectLocation(ctx.GetParam(ParamRoute))
+	if ctx.Request().URL.RawQuery != "" {
+		location = location + "?" + ctx.Request().URL.RawQuery
+	}
+	switch ctx.Method() {
+	case MethodGet, MethodHead:
+		ctx.Redirect(301, location)
+	default:
+		ctx.Redirect(308, location)
+	}
+}
+
 // HandlerRouter404 函数定义默认404处理
 func HandlerRouter404(ctx Context) {
 	const page404 string = "404 page not found\n"
@@ -139,6 +181,7 @@ func NewRouterStd(core RouterCore) Router {
 		HandlerExtender: NewHandlerExtendWarp(NewHandlerExtendTree(), DefaultHandlerExtend),
 		Middlewares:     newMiddlewareTree(),
 		Print:           printEmpty,
+		names:           &routerNames{routes: make(map[string]string)},
 	}
 }
 
@@ -169,6 +212,7 @@ func (m *RouterStd) Group(path string) Router {
 		HandlerExtender: NewHandlerExtendWarp(NewHandlerExtendTree(), m.HandlerExtender),
 		Middlewares:     m.Middlewares.clone(),
 		Print:           m.Print,
+		names:           m.names,
 	}
 }
 
@@ -249,7 +293,7 @@ func getRouteParam(path, key string) string {
 // AddHandler method adds a new route, allowing multiple request methods to be added separately using','.
 //
 // You can register 9 methods defined by http (three of the Router interfaces do not provide direct registration),
-// or you can register the method as: ANY TEST 404 405 NotFound MethodNotAllowed, register Any, TEST, 404, 405 routing rules.
+// or you can register the method as: ANY TEST 404 405 NotFound MethodNotAllowed ALLOW REDIRECT, register Any, TEST, 404, 405, automatic OPTIONS, path redirect routing rules.
 // the registration method is ANY to register all methods, the ANY method route will be covered by the same path non-ANY method,
 // and vice versa; the registration method is TEST will output the debug information related to the route registration,
 // but the registration behavior will not be performed;
@@ -263,7 +307,7 @@ func getRouteParam(path, key string) string {
 //
 // AddHandler 方法添加一条新路由, 允许添加多个请求方法使用','分开。
 //
-// 可以注册http定义的9种方法(其中三种Router接口未提供直接注册),也可以注册方法为：ANY TEST 404 405 NotFound MethodNotAllowed，注册Any、TEST、404、405路由规则。注册方法为ANY注册全部方法，ANY方法路由会被同路径非ANY方法覆盖，反之不行；注册方法为TEST会输出路由注册相关debug信息，但不执行注册行为;
+// 可以注册http定义的9种方法(其中三种Router接口未提供直接注册),也可以注册方法为：ANY TEST 404 405 NotFound MethodNotAllowed ALLOW REDIRECT，注册Any、TEST、404、405、自动OPTIONS、路径修正重定向路由规则。注册方法为ANY注册全部方法，ANY方法路由会被同路径非ANY方法覆盖，反之不行；注册方法为TEST会输出路由注册相关debug信息，但不执行注册行为;
 //
 // handler参数使用当前RouterStd的HandlerExtender.NewHandlerFuncs()方法处理，生成对应的HandlerFuncs。
 //
@@ -285,6 +329,7 @@ func (m *RouterStd) registerHandlers(method, path string, hs ...interface{}) (er
 		}
 	}()
 
+	origin := path
 	params := m.paramsCombine(path)
 	path = params.Get("route")
 	fullpath := params.String()
@@ -319,6 +364,20 @@ func (m *RouterStd) registerHandlers(method, path string, hs ...interface{}) (er
 			m.printError(1, err)
 		}
 	}
+	// 命名路由只使用当前注册路径的name参数，不继承Group的name参数，名称重复时保留第一次注册的路径。
+	if name := getRouteParam(origin, ParamName); name != "" && errs.GetError() == nil {
+		m.names.Lock()
+		route, ok := m.names.routes[name]
+		if !ok {
+			m.names.routes[name] = path
+		}
+		m.names.Unlock()
+		if ok && route != path {
+			err := fmt.Errorf(ErrFormatRouterStdRegisterHandlersNameExists, name, route, fullpath)
+			errs.HandleError(err)
+			m.printError(1, err)
+		}
+	}
 	return errs.GetError()
 }
 
@@ -349,9 +408,109 @@ func (m *RouterStd) newHandlerFuncs(path string, hs []interface{}) (HandlerFuncs
 	return handlers, errs.GetError()
 }
 
+// URL method uses the named route to generate a url, the route name is specified by the route param 'name'.
+//
+// URL 方法使用命名路由生成url，路由名称使用路由参数name指定，例如：app.GetFunc("/user/:id|isnum name=user", handler)。
+//
+// args为多个键值对，依次填充路由中的':name'参数和'*name'通配符参数，通配符参数可以为空，其他键值对作为query参数；
+// 值使用fmt.Sprint转换成字符串，并使用路由注册的校验函数检查，[]string值会添加多个query参数，url.Values参数会合并到query参数。
+func (m *RouterStd) URL(name string, args ...interface{}) (string, error) {
+	m.names.RLock()
+	route, ok := m.names.routes[name]
+	m.names.RUnlock()
+	if !ok {
+		return "", fmt.Errorf(ErrFormatRouterStdURLNotFound, name)
+	}
+
+	vals := make(map[string]string)
+	query := make(url.Values)
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i].(type) {
+		case url.Values:
+			for key, val := range arg {
+				query[key] = append(query[key], val...)
+			}
+		case string:
+			if i+1 == len(args) {
+				return "", fmt.Errorf(ErrFormatRouterStdURLArgsInvalid, name, i, arg)
+			}
+			i++
+			switch val := args[i].(type) {
+			case []string:
+				query[arg] = append(query[arg], val...)
+			default:
+				vals[arg] = fmt.Sprint(val)
+			}
+		default:
+			return "", fmt.Errorf(ErrFormatRouterStdURLArgsInvalid, name, i, arg)
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, path := range getSplitPath(route) {
+		if path[0] == '{' {
+			// 多参数路径段依次写入常量和参数
+			for _, item := range newStdPattern(path) {
+				if item.name == "" {
+					buf.WriteString(item.value)
+					continue
+				}
+				val, ok := vals[item.name]
+				if !ok {
+					return "", fmt.Errorf(ErrFormatRouterStdURLParamMissing, name, item.name)
+				}
+				if item.check != nil && !item.check(val) {
+					return "", fmt.Errorf(ErrFormatRouterStdURLParamInvalid, name, item.name, val)
+				}
+				delete(vals, item.name)
+				buf.WriteString(url.PathEscape(val))
+			}
+			continue
+		}
+		if path[0] != ':' && path[0] != '*' {
+			buf.WriteString(path)
+			continue
+		}
+		key, check := loadCheckFunc(path)
+		if key == "" {
+			key = path[1:]
+			if key == "" {
+				key = "*"
+			}
+		}
+		val, ok := vals[key]
+		if !ok && path[0] == ':' {
+			return "", fmt.Errorf(ErrFormatRouterStdURLParamMissing, name, key)
+		}
+		if check != nil && !check(val) {
+			return "", fmt.Errorf(ErrFormatRouterStdURLParamInvalid, name, key, val)
+		}
+		delete(vals, key)
+		if path[0] == ':' {
+			buf.WriteString(url.PathEscape(val))
+			continue
+		}
+		for i, str := range strings.Split(val, "/") {
+			if i > 0 {
+				buf.WriteByte('/')
+			}
+			buf.WriteString(url.PathEscape(str))
+		}
+	}
+
+	for key, val := range vals {
+		query.Add(key, val)
+	}
+	if len(query) > 0 {
+		buf.WriteByte('?')
+		buf.WriteString(query.Encode())
+	}
+	return buf.String(), nil
+}
+
 func checkMethod(method string) bool {
 	switch method {
-	case "ANY", "404", "405", "NotFound", "MethodNotAllowed":
+	case "ANY", "404", "405", "NotFound", "MethodNotAllowed", "ALLOW", "REDIRECT":
 		return true
 	}
 	for _, i := range RouterAllMethod {
@@ -638,6 +797,16 @@ func (r *routerCoreLock) HandleFunc(method, path string, hs HandlerFuncs) {
 	r.RouterCore.HandleFunc(method, path, hs)
 }
 
+// walkRoutes 方法对路由器加读锁遍历已注册的路由。
+func (r *routerCoreLock) walkRoutes(fn func(string, string, HandlerFuncs)) {
+	walker, ok := r.RouterCore.(routerCoreWalker)
+	if ok {
+		r.RLock()
+		defer r.RUnlock()
+		walker.walkRoutes(fn)
+	}
+}
+
 // Match 方法对路由器加读锁进行匹配请求。
 func (r *routerCoreLock) Match(method, path string, params *Params) (hs HandlerFuncs) {
 	r.RLock()
@@ -646,6 +815,142 @@ func (r *routerCoreLock) Match(method, path string, params *Params) (hs HandlerF
 	return
 }
 
+// routerCoreRedirect 在路由未匹配时尝试修正请求路径并重定向。
+type routerCoreRedirect struct {
+	RouterCore
+	params   *Params
+	handlers HandlerFuncs
+}
+
+// routerCoreFolder 定义路由器核心忽略大小写查找已注册路径的方法，routerCoreStd实现该方法。
+type routerCoreFolder interface {
+	lookPathFold(string) (string, bool)
+}
+
+// NewRouterCoreRedirect 函数创建一个自动重定向的路由器核心，默认使用eudore.RouterCoreStd为核心。
+//
+// 请求未匹配时依次尝试清理后的路径(path.Clean、重复斜杠)、切换结尾斜杠的路径和忽略大小写的路径，
+// 修正后的路径可以匹配当前方法时，使用HandlerRouterRedirect处理重定向，
+// 注册中间件后需要注册一次Redirect方法，例如app.AddHandler("Redirect", "", eudore.HandlerRouterRedirect)，否则重定向不会经过中间件处理。
+//
+// 忽略大小写匹配需要核心实现routerCoreFolder，Lock和OpenAPI核心需要包装Redirect核心。
+func NewRouterCoreRedirect(core RouterCore) RouterCore {
+	if core == nil {
+		core = NewRouterCoreStd()
+	}
+	return &routerCoreRedirect{
+		RouterCore: core,
+		params:     &Params{},
+		handlers:   HandlerFuncs{HandlerRouterRedirect},
+	}
+}
+
+// HandleFunc 方法注册路由，方法为Redirect时设置重定向的处理函数，其他方法使用RouterCore注册。
+func (r *routerCoreRedirect) HandleFunc(method string, path string, handler HandlerFuncs) {
+	switch method {
+	case "Redirect", "REDIRECT":
+		r.params = NewParamsRoute(path)
+		r.params.Keys = r.params.Keys[1:]
+		r.params.Vals = r.params.Vals[1:]
+		r.handlers = handler
+	default:
+		r.RouterCore.HandleFunc(method, path, handler)
+	}
+}
+
+// Match 方法匹配请求，未匹配时查找修正后的路径，匹配成功不会有额外开销。
+func (r *routerCoreRedirect) Match(method, reqpath string, params *Params) HandlerFuncs {
+	num := len(params.Keys)
+	hs := r.RouterCore.Match(method, reqpath, params)
+	if !isRouterMatchNotFound(params, num) {
+		return hs
+	}
+
+	for _, fixpath := range r.getFixedPaths(reqpath) {
+		if fixpath != reqpath && r.matchMethod(method, fixpath, params, num) {
+			params.Keys, params.Vals = params.Keys[:num], params.Vals[:num]
+			params.Keys = append(params.Keys, r.params.Keys...)
+			params.Vals = append(params.Vals, r.params.Vals...)
+			params.Add(ParamRoute, fixpath)
+			return r.handlers
+		}
+	}
+	// 检查修正路径时清理了参数，重新匹配原始路径。
+	params.Keys, params.Vals = params.Keys[:num], params.Vals[:num]
+	return r.RouterCore.Match(method, reqpath, params)
+}
+
+// walkRoutes 方法遍历RouterCore已注册的路由。
+func (r *routerCoreRedirect) walkRoutes(fn func(string, string, HandlerFuncs)) {
+	walker, ok := r.RouterCore.(routerCoreWalker)
+	if ok {
+		walker.walkRoutes(fn)
+	}
+}
+
+// getFixedPaths 方法返回需要尝试的修正路径，空路径不修正。
+func (r *routerCoreRedirect) getFixedPaths(reqpath string) []string {
+	if reqpath == "" {
+		return nil
+	}
+	cleanpath := path.Clean(reqpath)
+	if cleanpath == "." {
+		cleanpath = "/"
+	}
+	if cleanpath[0] != '/' {
+		cleanpath = "/" + cleanpath
+	}
+	if reqpath[len(reqpath)-1] == '/' && cleanpath != "/" {
+		cleanpath += "/"
+	}
+
+	paths := []string{cleanpath, cleanpath + "/"}
+	if cleanpath[len(cleanpath)-1] == '/' {
+		paths[1] = cleanpath[:len(cleanpath)-1]
+	}
+	if paths[1] == "" {
+		paths = paths[:1]
+	}
+
+	folder, ok := r.RouterCore.(routerCoreFolder)
+	if ok {
+		for _, p := range paths {
+			fixpath, ok := folder.lookPathFold(p)
+			if ok {
+				paths = append(paths, fixpath)
+			}
+		}
+	}
+	return paths
+}
+
+// getRedirectLocation 函数对修正路径的每一段进行转义，'\'、'?'等字符不会改变重定向的目标。
+func getRedirectLocation(fixpath string) string {
+	strs := strings.Split(fixpath, "/")
+	for i := range strs {
+		strs[i] = url.PathEscape(strs[i])
+	}
+	return strings.Join(strs, "/")
+}
+
+// matchMethod 方法检查路径是否可以匹配当前方法，检查后清理添加的参数。
+func (r *routerCoreRedirect) matchMethod(method, fixpath string, params *Params, num int) bool {
+	r.RouterCore.Match(method, fixpath, params)
+	ok := !isRouterMatchNotFound(params, num) && params.Get(ParamAllow) == ""
+	params.Keys, params.Vals = params.Keys[:num], params.Vals[:num]
+	return ok
+}
+
+// isRouterMatchNotFound 函数检查匹配后添加的参数中route是否为404。
+func isRouterMatchNotFound(params *Params, num int) bool {
+	for i := num; i < len(params.Keys); i++ {
+		if params.Keys[i] == ParamRoute {
+			return params.Vals[i] == "404"
+		}
+	}
+	return false
+}
+
 // routerCoreDebug 定义debug路由器。
 type routerCoreDebug struct {
 	RouterCore   `json:"-" xml:"-"`
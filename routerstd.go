@@ -18,28 +18,40 @@ const (
 
 // routerCoreStd is implemented based on the radix tree to implement all router related features.
 //
-// With path parameters, wildcard parameters, default parameters, parameter verification, wildcard verification, multi-parameter regular capture is not implemented.
+// With path parameters, wildcard parameters, default parameters, parameter verification, wildcard verification, multi-parameter regular capture.
 //
 // RouterStd基于基数树实现，实现全部路由器相关特性。
 //
-// 具有路径参数、通配符参数、默认参数、参数校验、通配符校验，未实现多参数正则捕捉。
+// 具有路径参数、通配符参数、默认参数、参数校验、通配符校验、多参数正则捕捉。
+//
+// 一个路径段内可以混合常量和多个参数，例如'/:year|^\d{4}$-:month|^\d{2}$.html'和'/file/{name}.{ext}'，
+// ':'参数名称直到'/'或'|'校验规则，'/:file.json'的参数名称为'file.json'；
+// 多参数路径段和校验参数具有相同优先级，参数之间的常量使用最左匹配，参数校验失败时回溯。
+//
+// 路由存在GET方法未注册HEAD方法时，HEAD请求使用GET处理并丢弃响应body；
+// 路由未注册OPTIONS方法时，OPTIONS请求自动响应Allow Header，可以注册Allow方法替换自动响应的处理。
 type routerCoreStd struct {
-	params404  *Params
-	params405  *Params
-	handler404 HandlerFuncs
-	handler405 HandlerFuncs
-	root       *stdNode
+	params404      *Params
+	params405      *Params
+	paramsOptions  *Params
+	handler404     HandlerFuncs
+	handler405     HandlerFuncs
+	handlerOptions HandlerFuncs
+	root           *stdNode
 }
 
 type stdNode struct {
-	isany uint16
-	kind  uint16
-	pnum  uint32
-	check func(string) bool
-	path  string
-	name  string
-	allow string
-	route *string
+	isany   uint16
+	kind    uint16
+	pnum    uint32
+	check   func(string) bool
+	pattern stdPattern
+	path    string
+	name    string
+	allow   string
+	route   *string
+	// HEAD请求使用GET处理时的处理函数
+	headhandlers HandlerFuncs
 
 	// 默认标签的名称和值
 	Wchildren  *stdNode
@@ -54,11 +66,13 @@ type stdNode struct {
 // NewRouterCoreStd 函数创建一个Std路由器核心，使用radix匹配。
 func NewRouterCoreStd() RouterCore {
 	return &routerCoreStd{
-		params404:  &Params{Keys: []string{ParamRoute}, Vals: []string{"404"}},
-		params405:  &Params{},
-		handler404: HandlerFuncs{HandlerRouter404},
-		handler405: HandlerFuncs{HandlerRouter405},
-		root:       &stdNode{},
+		params404:      &Params{Keys: []string{ParamRoute}, Vals: []string{"404"}},
+		params405:      &Params{},
+		paramsOptions:  &Params{},
+		handler404:     HandlerFuncs{HandlerRouter404},
+		handler405:     HandlerFuncs{HandlerRouter405},
+		handlerOptions: HandlerFuncs{HandlerRouterOptions},
+		root:           &stdNode{},
 	}
 }
 
@@ -80,6 +94,11 @@ func (r *routerCoreStd) HandleFunc(method string, path string, handler HandlerFu
 		r.params405.Keys = r.params405.Keys[1:]
 		r.params405.Vals = r.params405.Vals[1:]
 		r.handler405 = handler
+	case "Allow", "ALLOW":
+		r.paramsOptions = NewParamsRoute(path)
+		r.paramsOptions.Keys = r.paramsOptions.Keys[1:]
+		r.paramsOptions.Vals = r.paramsOptions.Vals[1:]
+		r.handlerOptions = handler
 	case MethodAny, MethodGet, MethodPost, MethodPut, MethodDelete, MethodHead, MethodPatch, MethodOptions, MethodConnect, MethodTrace:
 		r.insertRoute(method, path, handler)
 	}
@@ -90,6 +109,8 @@ func (r *routerCoreStd) HandleFunc(method string, path string, handler HandlerFu
 // Note: 404 does not support extra parameters, not implemented.
 //
 // 匹配一个请求，如果方法不不允许直接返回node405，未匹配返回node404。
+//
+// 未注册的HEAD方法使用GET方法处理，未注册的OPTIONS方法返回Allow处理。
 func (r *routerCoreStd) Match(method, path string, params *Params) HandlerFuncs {
 	node := r.root.lookNode(path, params)
 	if node == nil {
@@ -106,12 +127,33 @@ func (r *routerCoreStd) Match(method, path string, params *Params) HandlerFuncs
 			break
 		}
 	}
+	switch {
+	case method == MethodHead && node.headhandlers != nil:
+		params.Combine(node.params[0])
+		return node.headhandlers
+	case method == MethodOptions:
+		params.Add(ParamRoute, *node.route)
+		params.Add(ParamAllow, node.allow)
+		params.Combine(r.paramsOptions)
+		return r.handlerOptions
+	}
 	params.Add(ParamRoute, *node.route)
 	params.Add(ParamAllow, node.allow)
 	params.Combine(r.params405)
 	return r.handler405
 }
 
+// lookPathFold 方法忽略常量的大小写匹配路径，返回使用注册路由大小写的路径。
+func (r *routerCoreStd) lookPathFold(path string) (string, bool) {
+	buf, ok := r.root.lookPathFold(path, make([]byte, 0, len(path)+1))
+	return string(buf), ok
+}
+
+// walkRoutes 方法遍历全部已注册的路由，ANY方法注册的路由只返回一次。
+func (r *routerCoreStd) walkRoutes(fn func(string, string, HandlerFuncs)) {
+	r.root.walkRoutes(fn)
+}
+
 // Add a new route Node.
 //
 // If the method does not support it will not be added, request to change the path will respond 405
@@ -138,10 +180,14 @@ func (r *routerCoreStd) insertRoute(method, path string, val HandlerFuncs) {
 
 // 创建一个Radix树Node，会根据当前路由设置不同的节点类型和名称。
 //
-// '*'前缀为通配符节点，':'前缀为参数节点，其他未常量节点,如果通配符和参数结点后带有符号'|'则为校验结点。
+// '*'前缀为通配符节点，':'前缀为参数节点，'{'前缀为多参数校验节点，其他未常量节点,如果通配符和参数结点后带有符号'|'则为校验结点。
 func newStdNode(path string) *stdNode {
 	newNode := &stdNode{path: path}
 	switch path[0] {
+	case '{':
+		newNode.kind = stdNodeKindParamValid
+		newNode.name = path
+		newNode.pattern = newStdPattern(path)
 	case '*':
 		newNode.kind = stdNodeKindWildcard
 		if len(path) == 1 {
@@ -176,6 +222,81 @@ func newStdNode(path string) *stdNode {
 	return newNode
 }
 
+// stdPattern 定义一个路径段内常量和参数的匹配规则，name为空的项是常量。
+type stdPattern []stdPatternItem
+
+type stdPatternItem struct {
+	name  string
+	value string
+	check func(string) bool
+}
+
+// newStdPattern 函数解析'{name|check}const{name}'格式的模式字符串，两个参数之间必须存在常量。
+func newStdPattern(path string) stdPattern {
+	var pattern stdPattern
+	for key := path; key != ""; {
+		if key[0] != '{' {
+			pos := strings.IndexByte(key, '{')
+			if pos == -1 {
+				pos = len(key)
+			}
+			pattern = append(pattern, stdPatternItem{value: key[:pos]})
+			key = key[pos:]
+			continue
+		}
+
+		var block string
+		block, key = getSplitPathBlock(key)
+		item := stdPatternItem{name: block}
+		if name, fn := loadCheckFunc(":" + block); len(name) > 0 {
+			if fn == nil {
+				panic("loadCheckFunc path is invalid, load func failure " + path)
+			}
+			item.name, item.check = name, fn
+		}
+		if len(pattern) > 0 && pattern[len(pattern)-1].name != "" {
+			panic("router std pattern params must be separated by constant " + path)
+		}
+		pattern = append(pattern, item)
+	}
+	return pattern
+}
+
+// match 方法匹配一个路径段并添加参数，参数值不能为空。
+func (p stdPattern) match(key string, params *Params) bool {
+	if len(p) == 0 {
+		return key == ""
+	}
+	item := p[0]
+	if item.name == "" {
+		return strings.HasPrefix(key, item.value) && p[1:].match(key[len(item.value):], params)
+	}
+	if len(p) == 1 {
+		if key == "" || (item.check != nil && !item.check(key)) {
+			return false
+		}
+		params.Add(item.name, key)
+		return true
+	}
+
+	num := len(params.Keys)
+	for pos := 1; pos < len(key); pos++ {
+		next := strings.Index(key[pos:], p[1].value)
+		if next == -1 {
+			return false
+		}
+		pos += next
+		if item.check == nil || item.check(key[:pos]) {
+			params.Add(item.name, key[:pos])
+			if p[1:].match(key[pos:], params) {
+				return true
+			}
+			params.Keys, params.Vals = params.Keys[:num], params.Vals[:num]
+		}
+	}
+	return false
+}
+
 // Load the checksum function by name.
 //
 // 根据名称加载校验函数。
@@ -219,6 +340,7 @@ func (r *stdNode) setHandler(method string, params *Params, handler HandlerFuncs
 	}
 }
 
+// setAllow 方法设置路由允许的方法，包含自动处理的HEAD和OPTIONS方法。
 func (r *stdNode) setAllow() {
 	var allow string
 	for i := uint(0); i < 9; i++ {
@@ -226,12 +348,19 @@ func (r *stdNode) setAllow() {
 			route := r.params[i].Get(ParamRoute)
 			r.route = &route
 			allow = allow + ", " + RouterAllMethod[i]
+		} else if (i == 4 && r.handlers[0] != nil) || (i == 6 && !r.IsEmpty()) {
+			allow = allow + ", " + RouterAllMethod[i]
 		}
 	}
 	if allow != "" {
 		allow = allow[2:]
 	}
 	r.allow = allow
+
+	r.headhandlers = nil
+	if r.handlers[0] != nil && r.handlers[4] == nil {
+		r.headhandlers = NewHandlerFuncsCombine(HandlerFuncs{HandlerRouterHead}, r.handlers[0])
+	}
 }
 
 // insertNode add a child node to the node.
@@ -344,6 +473,12 @@ func (r *stdNode) lookNode(searchKey string, params *Params) *stdNode {
 			// check parameter matching
 			// 校验参数匹配
 			for _, child := range r.PVchildren {
+				if child.pattern != nil {
+					if n := child.lookNodePattern(currentKey, nextSearchKey, params); n != nil {
+						return n
+					}
+					continue
+				}
 				if child.check(currentKey) {
 					if n := child.lookNode(nextSearchKey, params); n != nil {
 						params.Add(child.name, currentKey)
@@ -386,6 +521,86 @@ func (r *stdNode) lookNode(searchKey string, params *Params) *stdNode {
 	return nil
 }
 
+func (r *stdNode) walkRoutes(fn func(string, string, HandlerFuncs)) {
+	if r.handlers[9] != nil {
+		fn(MethodAny, strings.TrimPrefix(r.params[9].String(), "route="), r.handlers[9])
+	}
+	for i := uint(0); i < 9; i++ {
+		if r.handlers[i] != nil && r.isany>>i&0x1 == 0 {
+			fn(RouterAllMethod[i], strings.TrimPrefix(r.params[i].String(), "route="), r.handlers[i])
+		}
+	}
+	for _, nodes := range [][]*stdNode{r.Cchildren, r.Pchildren, r.PVchildren, r.WVchildren} {
+		for _, child := range nodes {
+			child.walkRoutes(fn)
+		}
+	}
+	if r.Wchildren != nil {
+		r.Wchildren.walkRoutes(fn)
+	}
+}
+
+// lookPathFold 方法按照lookNode的顺序匹配路径，常量忽略大小写比较，参数和通配符使用请求路径的值。
+func (r *stdNode) lookPathFold(searchKey string, buf []byte) ([]byte, bool) {
+	if len(searchKey) == 0 && r.allow != "" {
+		return buf, true
+	}
+
+	if len(searchKey) > 0 {
+		for _, child := range r.Cchildren {
+			if len(searchKey) >= len(child.path) && strings.EqualFold(searchKey[:len(child.path)], child.path) {
+				if b, ok := child.lookPathFold(searchKey[len(child.path):], append(buf, child.path...)); ok {
+					return b, true
+				}
+			}
+		}
+
+		if r.pnum != 0 {
+			pos := strings.IndexByte(searchKey, '/')
+			if pos == -1 {
+				pos = len(searchKey)
+			}
+			currentKey, nextSearchKey := searchKey[:pos], searchKey[pos:]
+			for _, child := range r.PVchildren {
+				if child.pattern != nil && !child.pattern.match(currentKey, &Params{}) ||
+					child.pattern == nil && !child.check(currentKey) {
+					continue
+				}
+				if b, ok := child.lookPathFold(nextSearchKey, append(buf, currentKey...)); ok {
+					return b, true
+				}
+			}
+			for _, child := range r.Pchildren {
+				if b, ok := child.lookPathFold(nextSearchKey, append(buf, currentKey...)); ok {
+					return b, true
+				}
+			}
+		}
+	}
+
+	for _, child := range r.WVchildren {
+		if child.check(searchKey) {
+			return append(buf, searchKey...), true
+		}
+	}
+	if r.Wchildren != nil {
+		return append(buf, searchKey...), true
+	}
+	return nil, false
+}
+
+// lookNodePattern 方法匹配多参数路径段，匹配失败时删除已添加的参数。
+func (r *stdNode) lookNodePattern(currentKey, nextSearchKey string, params *Params) *stdNode {
+	num := len(params.Keys)
+	if r.pattern.match(currentKey, params) {
+		if n := r.lookNode(nextSearchKey, params); n != nil {
+			return n
+		}
+	}
+	params.Keys, params.Vals = params.Keys[:num], params.Vals[:num]
+	return nil
+}
+
 func (r *stdNode) deleteRoute(method, path string) {
 	nodes := r.findNode(path)
 	if nodes == nil {
@@ -444,7 +659,7 @@ func (r *stdNode) findNode(path string) []*stdNode {
 				return nil
 			}
 			nodes = append(nodes, child)
-		case ':':
+		case ':', '{':
 			child := last.findNodeParam(i)
 			if child == nil {
 				return nil
@@ -529,15 +744,16 @@ func (r *stdNode) IsMarge() bool {
 	return false
 }
 
+// deleteNode 方法从节点类型对应的子节点列表删除节点，Param节点在Pchildren，ParamValid节点在PVchildren。
 func (r *stdNode) deleteNode(node *stdNode) {
 	switch node.kind {
 	case stdNodeKindConst:
 		r.Cchildren = stdRemoveNode(r.Cchildren, node)
 	case stdNodeKindParam:
-		r.PVchildren = stdRemoveNode(r.PVchildren, node)
+		r.Pchildren = stdRemoveNode(r.Pchildren, node)
 		r.pnum--
 	case stdNodeKindParamValid:
-		r.Pchildren = stdRemoveNode(r.Pchildren, node)
+		r.PVchildren = stdRemoveNode(r.PVchildren, node)
 		r.pnum--
 	case stdNodeKindWildcardValid:
 		r.WVchildren = stdRemoveNode(r.WVchildren, node)
@@ -576,51 +792,180 @@ String path cutting example:
 /api/:get/*		[/api/ :get / *]
 /api/:name/info/*		[/api/ :name /info/ *]
 /api/:name|^\\d+$/info	[/api/ :name|^\d+$ /info]
-/api/*|{^0/api\\S+$}	[/api/ *|^0 /api\S+$]
+/api/*|{^0/api\\S+$}	[/api/ *|^0/api\S+$]
 /api/*|^\\$\\d+$		[/api/ *|^\$\d+$]
+/file/{name}			[/file/ :name]
+/file/{name}.{ext}		[/file/ {name}.{ext}]
+/file/:name.json		[/file/ :name.json]
+/:user-id		[/ :user-id]
+/:year|^\\d{4}$-:month|^\\d{2}$.html	[/ {year|^\d{4}$}-{month|^\d{2}$}.html]
 */
 func getSplitPath(key string) []string {
 	if len(key) < 2 {
 		return []string{"/"}
 	}
 	var strs []string
-	var length = -1
-	var isall = 0
-	var isconst = false
-	for i := range key {
-		// 块模式匹配
-		if isall > 0 {
-			switch key[i] {
-			case '{':
-				isall++
-			case '}':
-				isall--
+	var str string
+	for len(key) > 0 {
+		switch {
+		case key[0] == ':' || isSplitPathParam(key):
+			// 参数模式，一个路径段内存在多个参数或常量后缀时合并成模式字符串
+			if str != "" {
+				strs = append(strs, str)
+				str = ""
 			}
-			if isall > 0 {
-				strs[length] = strs[length] + key[i:i+1]
+			str, key = getSplitPathSegment(key)
+			strs = append(strs, str)
+			str = ""
+		case key[0] == '*':
+			// 通配符模式
+			if str != "" {
+				strs = append(strs, str)
 			}
-			continue
+			str, key = "*", key[1:]
+			for len(key) > 0 && key[0] != '/' && key[0] != ':' && key[0] != '*' {
+				if key[0] == '{' {
+					var block string
+					block, key = getSplitPathBlock(key)
+					str += block
+				} else {
+					str, key = str+key[:1], key[1:]
+				}
+			}
+			strs = append(strs, str)
+			str = ""
+		case key[0] == '{':
+			// 块模式，保留{}内的全部字符
+			var block string
+			block, key = getSplitPathBlock(key)
+			str += block
+		default:
+			pos := strings.IndexAny(key, ":*{")
+			if pos == -1 {
+				pos = len(key)
+			}
+			str, key = str+key[:pos], key[pos:]
+		}
+	}
+	if str != "" {
+		strs = append(strs, str)
+	}
+	return strs
+}
+
+// getSplitPathSegment 函数切割一个路径段内的参数和常量。
+//
+// 路径段只有一个参数时返回':name|check'格式，否则返回'{name|check}const{name}'格式的模式字符串。
+func getSplitPathSegment(key string) (string, string) {
+	var single, pattern string
+	var num int
+	for len(key) > 0 && key[0] != '/' && key[0] != '*' {
+		num++
+		switch {
+		case key[0] == ':':
+			// ':'参数名称直到路径分隔符或校验规则，多参数使用'{name}'或者校验规则结束参数名称
+			pos := strings.IndexAny(key[1:], "/|") + 1
+			if pos == 0 {
+				pos = len(key)
+			}
+			name, check := key[1:pos], ""
+			key = key[pos:]
+			if len(key) > 0 && key[0] == '|' {
+				check, key = getSplitPathCheck(key[1:])
+			}
+			single, pattern = getSplitPathParam(pattern, name, check)
+		case isSplitPathParam(key):
+			var block string
+			block, key = getSplitPathBlock(key)
+			name, check := split2byte(block, '|')
+			single, pattern = getSplitPathParam(pattern, name, check)
+		case key[0] == '{':
+			var block string
+			block, key = getSplitPathBlock(key)
+			pattern += block
+		default:
+			pos := strings.IndexAny(key, "/:*{")
+			if pos == -1 {
+				pos = len(key)
+			}
+			pattern, key = pattern+key[:pos], key[pos:]
 		}
+	}
+	if num == 1 {
+		return single, key
+	}
+	return pattern, key
+}
+
+// getSplitPathParam 函数返回参数的':name|check'格式和追加到模式字符串后的结果。
+func getSplitPathParam(pattern, name, check string) (string, string) {
+	if check == "" {
+		return ":" + name, pattern + "{" + name + "}"
+	}
+	return ":" + name + "|" + check, pattern + "{" + name + "|" + check + "}"
+}
+
+// getSplitPathCheck 函数截取参数的校验规则，'{}'包裹的规则直到匹配的'}'，正则规则直到'$'，其他规则直到'.'或路径分隔符。
+func getSplitPathCheck(key string) (string, string) {
+	if len(key) == 0 {
+		return "", key
+	}
+	switch key[0] {
+	case '{':
+		return getSplitPathBlock(key)
+	case '^':
+		for i := 1; i < len(key); i++ {
+			switch key[i] {
+			case '\\':
+				i++
+			case '/':
+				return key[:i], key[i:]
+			case '$':
+				return key[:i+1], key[i+1:]
+			}
+		}
+		return key, ""
+	}
+	pos := strings.IndexAny(key, "/:*{.")
+	if pos == -1 {
+		return key, ""
+	}
+	return key[:pos], key[pos:]
+}
+
+// getSplitPathBlock 函数截取'{}'块内的字符串，返回块内容和剩余字符串，块可以嵌套。
+func getSplitPathBlock(key string) (string, string) {
+	var num int
+	for i := range key {
 		switch key[i] {
-		case '/':
-			// 常量模式，非常量模式下创建新字符串
-			if !isconst {
-				length++
-				strs = append(strs, "")
-				isconst = true
-			}
-		case ':', '*':
-			// 变量模式
-			isconst = false
-			length++
-			strs = append(strs, "")
 		case '{':
-			isall++
-			continue
+			num++
+		case '}':
+			num--
+			if num == 0 {
+				return key[1:i], key[i+1:]
+			}
 		}
-		strs[length] = strs[length] + key[i:i+1]
 	}
-	return strs
+	return key[1:], ""
+}
+
+// isSplitPathParam 函数检查字符串是否以'{name}'或'{name|check}'格式的参数开头，name只能是字母、数字和下划线。
+func isSplitPathParam(key string) bool {
+	if len(key) < 3 || key[0] != '{' {
+		return false
+	}
+	block, _ := getSplitPathBlock(key)
+	name, _ := split2byte(block, '|')
+	if name == "" || ('0' <= name[0] && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
 }
 
 // Get the largest common prefix of the two strings,
@@ -15,7 +15,9 @@ import (
 	"net"
 	"net/http"
 	"net/http/fcgi"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -82,7 +84,7 @@ type serverFcgi struct {
 
 // ServerListenConfig 定义一个通用的端口监听配置,监听https仅支持单证书。
 type ServerListenConfig struct {
-	NewListen   func(string, string) (net.Listener, error) `alias:"newlisten" json:"-" description:"create listener func, default: net.Listen"`
+	NewListen   func(string, string) (net.Listener, error) `alias:"newlisten" json:"-" description:"create listener func, default: ListenWithFD"`
 	Addr        string                                     `alias:"addr" json:"addr" description:"Listen addr."`
 	HTTPS       bool                                       `alias:"https" json:"https" description:"Is https."`
 	HTTP2       bool                                       `alias:"http2" json:"http2" description:"Is http2."`
@@ -174,7 +176,7 @@ func (srv *serverFcgi) Shutdown(ctx context.Context) error {
 // Listen 方法使ServerListenConfig实现serverListener接口，用于使用对象创建监听。
 func (slc *ServerListenConfig) Listen() (net.Listener, error) {
 	if slc.NewListen == nil {
-		slc.NewListen = net.Listen
+		slc.NewListen = ListenWithFD
 	}
 	// set default port
 	if len(slc.Addr) == 0 {
@@ -222,6 +224,90 @@ func (slc *ServerListenConfig) Listen() (net.Listener, error) {
 	return tls.NewListener(ln, config), nil
 }
 
+// serverListenFiles 记录ListenWithFD创建的监听和继承的fd，监听关闭时会移除记录。
+var serverListenFiles = struct {
+	sync.Mutex
+	inherit   map[string]uintptr
+	listeners []*serverListenFile
+}{}
+
+type serverListenFile struct {
+	Key string
+	net.Listener
+}
+
+// ListenWithFD 函数创建一个监听，如果环境变量EnvEudoreListeners中存在相同network和addr的监听，则使用继承的fd创建监听。
+//
+// EnvEudoreListeners格式为"tcp://:8088,tcp://:8089"，第n个值对应fd为3+n，即exec.Cmd.ExtraFiles的顺序。
+//
+// 创建的监听会被记录，GetListenerFiles函数获取监听的文件用于传递给子进程，监听关闭后移除记录。
+func ListenWithFD(network, addr string) (net.Listener, error) {
+	key := network + "://" + addr
+	serverListenFiles.Lock()
+	defer serverListenFiles.Unlock()
+	if serverListenFiles.inherit == nil {
+		serverListenFiles.inherit = make(map[string]uintptr)
+		for i, name := range strings.Split(os.Getenv(EnvEudoreListeners), ",") {
+			if name != "" {
+				serverListenFiles.inherit[name] = uintptr(3 + i)
+			}
+		}
+	}
+
+	var ln net.Listener
+	var err error
+	fd, ok := serverListenFiles.inherit[key]
+	if ok {
+		delete(serverListenFiles.inherit, key)
+		file := os.NewFile(fd, key)
+		ln, err = net.FileListener(file)
+		file.Close()
+	} else {
+		ln, err = net.Listen(network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := &serverListenFile{key, ln}
+	serverListenFiles.listeners = append(serverListenFiles.listeners, file)
+	return file, nil
+}
+
+// Close 方法关闭监听并移除监听记录。
+func (ln *serverListenFile) Close() error {
+	serverListenFiles.Lock()
+	for i := range serverListenFiles.listeners {
+		if serverListenFiles.listeners[i] == ln {
+			serverListenFiles.listeners = append(serverListenFiles.listeners[:i], serverListenFiles.listeners[i+1:]...)
+			break
+		}
+	}
+	serverListenFiles.Unlock()
+	return ln.Listener.Close()
+}
+
+// GetListenerFiles 函数返回ListenWithFD创建的全部未关闭监听的名称和复制的文件，名称使用','连接后作为子进程环境变量EnvEudoreListeners的值。
+//
+// 返回的文件需要调用者关闭，不支持获取文件的监听会被忽略。
+func GetListenerFiles() ([]string, []*os.File) {
+	serverListenFiles.Lock()
+	defer serverListenFiles.Unlock()
+	var names []string
+	var files []*os.File
+	for _, ln := range serverListenFiles.listeners {
+		filer, ok := ln.Listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			continue
+		}
+		file, err := filer.File()
+		if err == nil {
+			names = append(names, ln.Key)
+			files = append(files, file)
+		}
+	}
+	return names, files
+}
+
 // loadCertificate 实现加载证书，如果证书配置文件为空，则自动创建一个私有证书。
 func loadCertificate(cret, key string) (tls.Certificate, *x509.Certificate, error) {
 	if cret != "" && key != "" {
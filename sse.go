@@ -0,0 +1,155 @@
+package eudore
+
+// sse 实现Server-Sent Events响应，text/event-stream格式定义参考https://html.spec.whatwg.org/multipage/server-sent-events.html。
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerSentEvent 定义一个Server-Sent Events事件。
+//
+// Data为string或[]byte时直接写入，其他类型使用json编码，多行数据会写入多个data字段；
+// Retry大于0时写入retry字段，单位毫秒；Comment非空时写入注释行，可以用于心跳。
+type ServerSentEvent struct {
+	ID      string
+	Event   string
+	Data    interface{}
+	Retry   time.Duration
+	Comment string
+}
+
+// ServerSentEvents 定义Server-Sent Events流的属性。
+//
+// Heartbeat大于0时间隔发送心跳注释，防止代理关闭空闲连接；
+// 如果请求存在Last-Event-ID Header，开始推送事件前调用Resume函数，用于补发客户端断开期间的事件。
+type ServerSentEvents struct {
+	Heartbeat time.Duration
+	Resume    func(Context, string) error
+}
+
+// Stream 方法从ch读取事件写入响应，ch关闭或客户端断开连接时结束并返回nil，写入失败返回错误。
+//
+// Resume函数的参数为请求的Last-Event-ID Header，可以使用WriteServerSentEvent函数补发事件。
+func (sse *ServerSentEvents) Stream(ctx Context, ch <-chan *ServerSentEvent) error {
+	setServerSentEventsHeader(ctx)
+	if sse.Resume != nil {
+		if id := ctx.GetHeader(HeaderLastEventID); id != "" {
+			err := sse.Resume(ctx, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	// 立即发送响应Header，客户端可以确认连接建立。
+	ctx.Response().Flush()
+
+	var heartbeat <-chan time.Time
+	if sse.Heartbeat > 0 {
+		ticker := time.NewTicker(sse.Heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	done := ctx.GetContext().Done()
+	for {
+		select {
+		case <-done:
+			return nil
+		case event, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if event == nil {
+				continue
+			}
+			err := WriteServerSentEvent(ctx, event)
+			if err != nil {
+				return err
+			}
+		case <-heartbeat:
+			err := WriteServerSentEvent(ctx, &ServerSentEvent{Comment: "heartbeat"})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// setServerSentEventsHeader 函数在响应写入前设置text/event-stream响应Header。
+func setServerSentEventsHeader(ctx Context) {
+	header := ctx.Response().Header()
+	if header.Get(HeaderContentType) == "" {
+		header.Set(HeaderContentType, MimeTextEventStream)
+		header.Set(HeaderCacheControl, "no-cache")
+		header.Set(HeaderXAccelBuffering, "no")
+	}
+}
+
+// WriteServerSentEvent 函数写入一个事件并刷新缓冲，响应Header未设置时设置text/event-stream响应Header。
+func WriteServerSentEvent(ctx Context, event *ServerSentEvent) error {
+	setServerSentEventsHeader(ctx)
+	data, err := event.MarshalText()
+	if err != nil {
+		return err
+	}
+	_, err = ctx.Response().Write(data)
+	if err != nil {
+		return err
+	}
+	ctx.Response().Flush()
+	return nil
+}
+
+// MarshalText 方法将事件编码成text/event-stream格式，ID和Event中的换行符会被删除。
+func (event *ServerSentEvent) MarshalText() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if event.Comment != "" {
+		writeServerSentEventLines(buf, ": ", event.Comment)
+	}
+	if event.ID != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(serverSentEventReplacer.Replace(event.ID))
+		buf.WriteByte('\n')
+	}
+	if event.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(serverSentEventReplacer.Replace(event.Event))
+		buf.WriteByte('\n')
+	}
+	if event.Retry > 0 {
+		buf.WriteString("retry: ")
+		buf.WriteString(strconv.FormatInt(int64(event.Retry/time.Millisecond), 10))
+		buf.WriteByte('\n')
+	}
+	switch data := event.Data.(type) {
+	case nil:
+	case string:
+		writeServerSentEventLines(buf, "data: ", data)
+	case []byte:
+		writeServerSentEventLines(buf, "data: ", string(data))
+	default:
+		body, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		writeServerSentEventLines(buf, "data: ", string(body))
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+var serverSentEventReplacer = strings.NewReplacer("\r", "", "\n", "")
+
+// writeServerSentEventLines 函数按行写入字段，兼容\r\n、\r和\n换行符。
+func writeServerSentEventLines(buf *bytes.Buffer, prefix, str string) {
+	str = strings.Replace(str, "\r\n", "\n", -1)
+	str = strings.Replace(str, "\r", "\n", -1)
+	for _, line := range strings.Split(str, "\n") {
+		buf.WriteString(prefix)
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+}
@@ -0,0 +1,264 @@
+package eudore
+
+// tracer 实现W3C Trace Context的解析、生成和传播，以及请求Span的记录和导出。
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceExporter 定义Span导出接口，采样的Span结束时调用Export方法。
+type TraceExporter interface {
+	Export(*TraceSpan)
+}
+
+// TraceSpan 定义一次调用的Span，使用W3C traceparent格式传播TraceID和SpanID。
+//
+// 使用NewTraceSpan创建，结束时调用End方法导出到TraceExporter。
+type TraceSpan struct {
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind,omitempty"`
+	TraceID    string                 `json:"trace-id"`
+	SpanID     string                 `json:"span-id"`
+	ParentID   string                 `json:"parent-id,omitempty"`
+	TraceState string                 `json:"trace-state,omitempty"`
+	Sampled    bool                   `json:"sampled"`
+	StartTime  time.Time              `json:"start-time"`
+	EndTime    time.Time              `json:"end-time"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	exporter   TraceExporter
+	mu         sync.Mutex
+	ended      int32
+}
+
+// NewTraceSpan 函数创建一个Span，parent为空创建新的Trace，否则继承parent的TraceID、TraceState和采样标志。
+//
+// exporter为空使用parent的TraceExporter，都为空时Span不会被导出。
+func NewTraceSpan(parent *TraceSpan, name string, exporter TraceExporter) *TraceSpan {
+	span := &TraceSpan{
+		Name:      name,
+		SpanID:    newTraceID(8),
+		Sampled:   true,
+		StartTime: time.Now(),
+		exporter:  exporter,
+	}
+	if parent == nil {
+		span.TraceID = newTraceID(16)
+		return span
+	}
+	span.TraceID = parent.TraceID
+	span.ParentID = parent.SpanID
+	span.TraceState = parent.TraceState
+	span.Sampled = parent.Sampled
+	if span.exporter == nil {
+		span.exporter = parent.exporter
+	}
+	return span
+}
+
+// ParseTraceparent 函数解析W3C traceparent Header，返回的Span作为远程父Span使用，只有TraceID、SpanID和Sampled属性。
+//
+// 格式: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceparent(traceparent string) (*TraceSpan, error) {
+	s := traceparent
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return nil, ErrTraceparentInvalid
+	}
+	version, traceid, spanid, flags := s[0:2], s[3:35], s[36:52], s[53:55]
+	// 00版本长度固定，未来版本允许使用'-'追加字段
+	if version == "ff" || (version == "00" && len(s) != 55) || (len(s) > 55 && s[55] != '-') {
+		return nil, ErrTraceparentInvalid
+	}
+	if !isTraceHex(version) || !isTraceHex(traceid) || !isTraceHex(spanid) || !isTraceHex(flags) ||
+		isTraceZero(traceid) || isTraceZero(spanid) {
+		return nil, ErrTraceparentInvalid
+	}
+	f, _ := strconv.ParseUint(flags, 16, 8)
+	return &TraceSpan{TraceID: traceid, SpanID: spanid, Sampled: f&0x01 == 0x01}, nil
+}
+
+// isTraceHex 函数检查字符串是否由小写十六进制字符组成。
+func isTraceHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isTraceZero(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '0' {
+			return false
+		}
+	}
+	return true
+}
+
+// newTraceID 函数生成n字节的随机ID，返回十六进制字符串，ID不能全部为0。
+func newTraceID(n int) string {
+	id := make([]byte, n)
+	for {
+		rand.Read(id)
+		for _, b := range id {
+			if b != 0 {
+				return hex.EncodeToString(id)
+			}
+		}
+	}
+}
+
+// Traceparent 方法返回Span的W3C traceparent Header值。
+func (span *TraceSpan) Traceparent() string {
+	flags := "-00"
+	if span.Sampled {
+		flags = "-01"
+	}
+	return "00-" + span.TraceID + "-" + span.SpanID + flags
+}
+
+// Inject 方法将Span写入traceparent和tracestate Header，用于传播到下游请求。
+func (span *TraceSpan) Inject(header http.Header) {
+	header.Set(HeaderTraceparent, span.Traceparent())
+	if span.TraceState != "" {
+		header.Set(HeaderTracestate, span.TraceState)
+	} else {
+		header.Del(HeaderTracestate)
+	}
+}
+
+// SetAttribute 方法设置Span的一个属性。
+func (span *TraceSpan) SetAttribute(key string, val interface{}) {
+	span.mu.Lock()
+	if span.Attributes == nil {
+		span.Attributes = make(map[string]interface{})
+	}
+	span.Attributes[key] = val
+	span.mu.Unlock()
+}
+
+// SetError 方法设置Span的错误信息。
+func (span *TraceSpan) SetError(err error) {
+	if err != nil {
+		span.mu.Lock()
+		span.Error = err.Error()
+		span.mu.Unlock()
+	}
+}
+
+// End 方法结束Span，如果Span被采样则导出到TraceExporter，多次调用只有第一次生效。
+func (span *TraceSpan) End() {
+	if !atomic.CompareAndSwapInt32(&span.ended, 0, 1) {
+		return
+	}
+	span.EndTime = time.Now()
+	if span.Sampled && span.exporter != nil {
+		span.exporter.Export(span)
+	}
+}
+
+// ContextWithTraceSpan 函数返回保存Span的context.Context。
+func ContextWithTraceSpan(ctx context.Context, span *TraceSpan) context.Context {
+	return context.WithValue(ctx, TraceContextKey, span)
+}
+
+// GetTraceSpan 函数获取context.Context中保存的Span，不存在返回nil。
+//
+// 请求的Span使用GetTraceSpan(ctx.GetContext())获取，需要使用middleware.NewTracerFunc创建请求Span。
+func GetTraceSpan(ctx context.Context) *TraceSpan {
+	span, _ := ctx.Value(TraceContextKey).(*TraceSpan)
+	return span
+}
+
+// TraceExporterMemory 定义保存在内存中的Span导出，用于测试。
+type TraceExporterMemory struct {
+	sync.Mutex
+	spans []*TraceSpan
+}
+
+// NewTraceExporterMemory 函数创建一个内存Span导出。
+func NewTraceExporterMemory() *TraceExporterMemory {
+	return &TraceExporterMemory{}
+}
+
+// Export 方法保存一个结束的Span。
+func (exp *TraceExporterMemory) Export(span *TraceSpan) {
+	exp.Lock()
+	exp.spans = append(exp.spans, span)
+	exp.Unlock()
+}
+
+// Spans 方法返回全部已导出的Span。
+func (exp *TraceExporterMemory) Spans() []*TraceSpan {
+	exp.Lock()
+	defer exp.Unlock()
+	return append([]*TraceSpan(nil), exp.spans...)
+}
+
+// Reset 方法清空已导出的Span。
+func (exp *TraceExporterMemory) Reset() {
+	exp.Lock()
+	exp.spans = nil
+	exp.Unlock()
+}
+
+// TraceTransport 定义传播Trace的http.RoundTripper，请求的context.Context中存在Span时创建client Span并写入traceparent Header。
+//
+// client Span在收到响应Header时结束。
+type TraceTransport struct {
+	Transport http.RoundTripper
+}
+
+// NewTraceClient 函数返回使用TraceTransport的http.Client，client为空使用http.DefaultClient。
+//
+// 请求需要使用req.WithContext(ctx.GetContext())传递请求上下文中的Span。
+func NewTraceClient(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	newclient := *client
+	newclient.Transport = &TraceTransport{Transport: client.Transport}
+	return &newclient
+}
+
+// RoundTrip 方法实现http.RoundTripper接口。
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	parent := GetTraceSpan(req.Context())
+	if parent == nil {
+		return transport.RoundTrip(req)
+	}
+
+	span := NewTraceSpan(parent, "HTTP "+req.Method, nil)
+	span.Kind = "client"
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.String())
+	// RoundTripper不能修改原请求，复制请求和Header
+	r2 := new(http.Request)
+	*r2 = *req
+	r2.Header = make(http.Header, len(req.Header)+2)
+	for k, v := range req.Header {
+		r2.Header[k] = v
+	}
+	span.Inject(r2.Header)
+
+	resp, err := transport.RoundTrip(r2)
+	if err != nil {
+		span.SetError(err)
+	} else {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+	}
+	span.End()
+	return resp, err
+}
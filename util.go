@@ -17,6 +17,16 @@ func split2byte(str string, b byte) (string, string) {
 	return str[:pos], str[pos+1:]
 }
 
+// sliceContains 内部函数，检查字符串切片是否包含指定字符串。
+func sliceContains(strs []string, str string) bool {
+	for _, i := range strs {
+		if i == str {
+			return true
+		}
+	}
+	return false
+}
+
 // GetBool 函数转换bool、int、uint、float、string成bool。
 func GetBool(i interface{}) bool {
 	if v, ok := i.(bool); ok {
@@ -0,0 +1,672 @@
+package eudore
+
+// websocket 实现RFC 6455 websocket协议和RFC 7692 permessage-deflate扩展，不依赖第三方库。
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// websocket消息类型。
+const (
+	WebsocketMessageContinuation = 0
+	WebsocketMessageText         = 1
+	WebsocketMessageBinary       = 2
+	WebsocketMessageClose        = 8
+	WebsocketMessagePing         = 9
+	WebsocketMessagePong         = 10
+)
+
+// websocket关闭状态码。
+const (
+	WebsocketCloseNormalClosure    = 1000
+	WebsocketCloseGoingAway        = 1001
+	WebsocketCloseProtocolError    = 1002
+	WebsocketCloseUnsupportedData  = 1003
+	WebsocketCloseNoStatusReceived = 1005
+	WebsocketCloseAbnormalClosure  = 1006
+	WebsocketCloseInvalidPayload   = 1007
+	WebsocketClosePolicyViolation  = 1008
+	WebsocketCloseMessageTooBig    = 1009
+	WebsocketCloseInternalError    = 1011
+)
+
+const (
+	websocketGUID            = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	websocketMaxControlSize  = 125
+	websocketDeflateTail     = "\x00\x00\xff\xff"
+	websocketDeflateExtName  = "permessage-deflate"
+	websocketDeflateResponse = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+)
+
+var (
+	websocketFlateWriters [12]sync.Pool
+	websocketFlateReaders sync.Pool
+)
+
+// WebsocketUpgrader 定义websocket握手升级的属性。
+//
+// CheckOrigin为空时如果请求存在Origin Header，要求Origin的Host和请求Host相同。
+// EnableCompression启用时如果客户端请求permessage-deflate扩展，使用无上下文接管的方式压缩消息。
+type WebsocketUpgrader struct {
+	ReadLimit         int64
+	EnableCompression bool
+	CompressionLevel  int
+	Subprotocols      []string
+	CheckOrigin       func(*http.Request) bool
+}
+
+// WebsocketConn 定义websocket连接，实现消息帧读写、ping/pong和关闭握手。
+//
+// ReadMessage方法只能在一个goroutine中调用，写入方法可以并发调用。
+// ReadMessage会自动回复ping和关闭帧，收到关闭帧时返回*WebsocketCloseError。
+type WebsocketConn struct {
+	// ReadLimit 定义读取一个消息的最大长度，压缩消息为解压后的长度，超过长度发送1009关闭帧并返回ErrWebsocketReadLimit。
+	ReadLimit        int64
+	CompressionLevel int
+	PingHandler      func([]byte) error
+	PongHandler      func([]byte) error
+	conn             net.Conn
+	reader           *bufio.Reader
+	server           bool
+	compress         bool
+	subprotocol      string
+	writeMutex       sync.Mutex
+	closeSent        bool
+	readErr          error
+}
+
+// WebsocketCloseError 定义收到的websocket关闭帧。
+type WebsocketCloseError struct {
+	Code int
+	Text string
+}
+
+// NewWebsocketUpgrader 函数创建websocket升级对象，默认启用压缩。
+func NewWebsocketUpgrader() *WebsocketUpgrader {
+	return &WebsocketUpgrader{
+		ReadLimit:         DefaultWebsocketReadLimit,
+		EnableCompression: true,
+		CompressionLevel:  flate.BestSpeed,
+	}
+}
+
+// Upgrade 方法检查websocket握手请求，劫持连接写入101响应，ctx.Response().Header()中的Header会一起写入。
+//
+// 握手请求无效时写入对应的错误状态码并返回错误。
+//
+// 连接可以在处理函数返回后继续使用，但是不能在其他goroutine中继续使用Context。
+func (u *WebsocketUpgrader) Upgrade(ctx Context) (*WebsocketConn, error) {
+	r := ctx.Request()
+	if r.Method != MethodGet {
+		return nil, websocketHandshakeError(ctx, StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header[HeaderConnection], "upgrade") {
+		return nil, websocketHandshakeError(ctx, StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header[HeaderUpgrade], "websocket") {
+		return nil, websocketHandshakeError(ctx, StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get(HeaderSecWebSocketVersion) != "13" {
+		ctx.SetHeader(HeaderSecWebSocketVersion, "13")
+		return nil, websocketHandshakeError(ctx, StatusUpgradeRequired, "unsupported version")
+	}
+	key := r.Header.Get(HeaderSecWebSocketKey)
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, websocketHandshakeError(ctx, StatusBadRequest, "'Sec-WebSocket-Key' header is invalid")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = websocketCheckSameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, websocketHandshakeError(ctx, StatusForbidden, "request origin not allowed")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+	compress := u.EnableCompression && websocketNegotiateDeflate(r.Header)
+	conn, rw, err := ctx.Response().Hijack()
+	if err != nil {
+		return nil, websocketHandshakeError(ctx, StatusInternalServerError, err.Error())
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	buf.WriteString(GetWebsocketAccept(key))
+	buf.WriteString("\r\n")
+	if subprotocol != "" {
+		buf.WriteString(HeaderSecWebSocketProtocol + ": " + subprotocol + "\r\n")
+	}
+	if compress {
+		buf.WriteString(HeaderSecWebSocketExtensions + ": " + websocketDeflateResponse + "\r\n")
+	}
+	for key, vals := range ctx.Response().Header() {
+		for _, val := range vals {
+			buf.WriteString(key + ": " + val + "\r\n")
+		}
+	}
+	buf.WriteString("\r\n")
+	_, err = conn.Write(buf.Bytes())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ws := NewWebsocketConn(conn, rw.Reader, true, compress)
+	ws.subprotocol = subprotocol
+	if u.ReadLimit != 0 {
+		ws.ReadLimit = u.ReadLimit
+	}
+	ws.CompressionLevel = u.CompressionLevel
+	return ws, nil
+}
+
+func (u *WebsocketUpgrader) selectSubprotocol(r *http.Request) string {
+	for _, protocol := range strings.Split(r.Header.Get(HeaderSecWebSocketProtocol), ",") {
+		protocol = strings.TrimSpace(protocol)
+		for _, p := range u.Subprotocols {
+			if protocol == p {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+func websocketHandshakeError(ctx Context, status int, reason string) error {
+	ctx.SetHeader(HeaderContentType, MimeTextPlainCharsetUtf8)
+	ctx.WriteHeader(status)
+	ctx.Response().Write([]byte(http.StatusText(status)))
+	return fmt.Errorf(ErrFormatWebsocketHandshake, reason)
+}
+
+func websocketCheckSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get(HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// websocketNegotiateDeflate 函数检查客户端是否请求permessage-deflate扩展。
+//
+// 服务端和客户端都不使用上下文接管，客户端要求server_max_window_bits小于15时无法满足，跳过该扩展请求。
+func websocketNegotiateDeflate(header http.Header) bool {
+	for _, ext := range strings.Split(strings.Join(header[HeaderSecWebSocketExtensions], ","), ",") {
+		params := strings.Split(ext, ";")
+		if strings.TrimSpace(params[0]) != websocketDeflateExtName {
+			continue
+		}
+		ok := true
+		for _, param := range params[1:] {
+			key, val := split2byte(strings.TrimSpace(param), '=')
+			switch key {
+			case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+			case "server_max_window_bits":
+				ok = ok && strings.Trim(val, "\"") == "15"
+			default:
+				ok = false
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWebsocketAccept 函数返回Sec-WebSocket-Key对应的Sec-WebSocket-Accept值。
+func GetWebsocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	h.Write([]byte(websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// NewWebsocketKey 函数创建一个随机的Sec-WebSocket-Key值。
+func NewWebsocketKey() string {
+	key := make([]byte, 16)
+	rand.Read(key)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// NewWebsocketConn 函数使用完成握手的连接创建websocket连接，server表示是否为服务端，compress表示是否启用了permessage-deflate扩展。
+//
+// reader为空时直接读取conn，否则从reader读取握手后已缓冲的数据。
+func NewWebsocketConn(conn net.Conn, reader *bufio.Reader, server, compress bool) *WebsocketConn {
+	if reader == nil {
+		reader = bufio.NewReader(conn)
+	}
+	return &WebsocketConn{
+		ReadLimit:        DefaultWebsocketReadLimit,
+		CompressionLevel: flate.BestSpeed,
+		conn:             conn,
+		reader:           reader,
+		server:           server,
+		compress:         compress,
+	}
+}
+
+// Subprotocol 方法返回握手协商的子协议。
+func (ws *WebsocketConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// Compression 方法返回是否启用了permessage-deflate压缩。
+func (ws *WebsocketConn) Compression() bool {
+	return ws.compress
+}
+
+// NetConn 方法返回底层连接。
+func (ws *WebsocketConn) NetConn() net.Conn {
+	return ws.conn
+}
+
+// LocalAddr 方法返回本地地址。
+func (ws *WebsocketConn) LocalAddr() net.Addr {
+	return ws.conn.LocalAddr()
+}
+
+// RemoteAddr 方法返回远程地址。
+func (ws *WebsocketConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// SetReadDeadline 方法设置读取超时时间。
+func (ws *WebsocketConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline 方法设置写入超时时间。
+func (ws *WebsocketConn) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// Close 方法关闭底层连接，如果没有发送关闭帧先发送1000关闭帧。
+//
+// 完整的关闭握手需要先调用WriteClose方法，然后继续调用ReadMessage直到返回*WebsocketCloseError。
+func (ws *WebsocketConn) Close() error {
+	ws.WriteClose(WebsocketCloseNormalClosure, "")
+	return ws.conn.Close()
+}
+
+// WriteText 方法写入一个文本消息。
+func (ws *WebsocketConn) WriteText(data string) error {
+	return ws.WriteMessage(WebsocketMessageText, []byte(data))
+}
+
+// WriteMessage 方法写入一个完整的文本或二进制消息，启用压缩时压缩消息数据，也可以写入ping和pong控制帧。
+func (ws *WebsocketConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case WebsocketMessageText, WebsocketMessageBinary:
+		var rsv1 bool
+		if ws.compress && len(data) > 0 {
+			compressed, err := websocketCompress(data, ws.CompressionLevel)
+			if err != nil {
+				return err
+			}
+			data, rsv1 = compressed, true
+		}
+		return ws.writeFrame(messageType, rsv1, data)
+	case WebsocketMessagePing, WebsocketMessagePong:
+		if len(data) > websocketMaxControlSize {
+			return ErrWebsocketControlTooLarge
+		}
+		return ws.writeFrame(messageType, false, data)
+	case WebsocketMessageClose:
+		code, text := WebsocketCloseNormalClosure, ""
+		if len(data) >= 2 {
+			code, text = int(binary.BigEndian.Uint16(data)), string(data[2:])
+		}
+		return ws.WriteClose(code, text)
+	}
+	return fmt.Errorf(ErrFormatWebsocketMessageType, messageType)
+}
+
+// WritePing 方法写入ping控制帧。
+func (ws *WebsocketConn) WritePing(data []byte) error {
+	return ws.WriteMessage(WebsocketMessagePing, data)
+}
+
+// WriteClose 方法写入关闭帧，之后不能再写入消息，code为WebsocketCloseNoStatusReceived时关闭帧没有数据。
+func (ws *WebsocketConn) WriteClose(code int, text string) error {
+	var data []byte
+	if code != WebsocketCloseNoStatusReceived {
+		if len(text) > websocketMaxControlSize-2 {
+			text = text[:websocketMaxControlSize-2]
+		}
+		data = make([]byte, 2, 2+len(text))
+		binary.BigEndian.PutUint16(data, uint16(code))
+		data = append(data, text...)
+	}
+	ws.writeMutex.Lock()
+	defer ws.writeMutex.Unlock()
+	if ws.closeSent {
+		return ErrWebsocketClosed
+	}
+	ws.closeSent = true
+	return ws.writeFrameLocked(WebsocketMessageClose, false, data)
+}
+
+func (ws *WebsocketConn) writeFrame(opcode int, rsv1 bool, data []byte) error {
+	ws.writeMutex.Lock()
+	defer ws.writeMutex.Unlock()
+	if ws.closeSent {
+		return ErrWebsocketClosed
+	}
+	return ws.writeFrameLocked(opcode, rsv1, data)
+}
+
+// writeFrameLocked 方法写入一个完整的帧，客户端使用随机掩码。
+func (ws *WebsocketConn) writeFrameLocked(opcode int, rsv1 bool, data []byte) error {
+	frame := make([]byte, 2, 14+len(data))
+	frame[0] = 0x80 | byte(opcode)
+	if rsv1 {
+		frame[0] |= 0x40
+	}
+	length := len(data)
+	switch {
+	case length <= 125:
+		frame[1] = byte(length)
+	case length <= 0xffff:
+		frame[1] = 126
+		frame = append(frame, byte(length>>8), byte(length))
+	default:
+		frame[1] = 127
+		frame = appendUint(frame, uint64(length), 8)
+	}
+	if ws.server {
+		frame = append(frame, data...)
+	} else {
+		frame[1] |= 0x80
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		pos := len(frame)
+		frame = append(frame, data...)
+		websocketMask(mask, frame[pos:])
+	}
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+func websocketMask(mask [4]byte, data []byte) {
+	for i := range data {
+		data[i] ^= mask[i&3]
+	}
+}
+
+// websocketFrame 定义读取的帧头。
+type websocketFrame struct {
+	fin    bool
+	rsv1   bool
+	opcode int
+	length int64
+	mask   [4]byte
+	masked bool
+}
+
+// ReadMessage 方法读取一个完整的文本或二进制消息，合并分片并解压数据。
+//
+// 收到ping自动回复pong，收到关闭帧时回复关闭帧并返回*WebsocketCloseError；
+// 协议错误时发送1002关闭帧，文本不是有效的UTF-8时发送1007关闭帧，超过ReadLimit时发送1009关闭帧。
+func (ws *WebsocketConn) ReadMessage() (int, []byte, error) {
+	if ws.readErr != nil {
+		return 0, nil, ws.readErr
+	}
+	messageType, data, err := ws.readMessage()
+	if err != nil {
+		ws.readErr = err
+	}
+	return messageType, data, err
+}
+
+func (ws *WebsocketConn) readMessage() (int, []byte, error) {
+	var messageType int
+	var compressed bool
+	var message []byte
+	for {
+		frame, err := ws.readFrameHeader()
+		if err != nil {
+			return 0, nil, err
+		}
+		if frame.opcode >= WebsocketMessageClose {
+			err = ws.handleControl(frame)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		switch {
+		case frame.opcode == WebsocketMessageContinuation && messageType == 0:
+			return 0, nil, ws.protocolError("continuation frame without start frame")
+		case frame.opcode != WebsocketMessageContinuation && messageType != 0:
+			return 0, nil, ws.protocolError("data frame in fragmented message")
+		case frame.rsv1 && (!ws.compress || frame.opcode == WebsocketMessageContinuation):
+			return 0, nil, ws.protocolError("unexpected rsv1 bit")
+		}
+		if messageType == 0 {
+			messageType, compressed = frame.opcode, frame.rsv1
+		}
+		if ws.ReadLimit > 0 && int64(len(message))+frame.length > ws.ReadLimit {
+			return 0, nil, ws.failConnection(WebsocketCloseMessageTooBig, ErrWebsocketReadLimit)
+		}
+		message, err = ws.readPayload(frame, message)
+		if err != nil {
+			return 0, nil, err
+		}
+		if frame.fin {
+			break
+		}
+	}
+
+	if compressed {
+		var err error
+		message, err = websocketDecompress(message, ws.ReadLimit)
+		if err == ErrWebsocketReadLimit {
+			return 0, nil, ws.failConnection(WebsocketCloseMessageTooBig, err)
+		}
+		if err != nil {
+			return 0, nil, ws.failConnection(WebsocketCloseProtocolError, err)
+		}
+	}
+	if messageType == WebsocketMessageText && !utf8.Valid(message) {
+		return 0, nil, ws.failConnection(WebsocketCloseInvalidPayload, ErrWebsocketInvalidUTF8)
+	}
+	return messageType, message, nil
+}
+
+func (ws *WebsocketConn) readFrameHeader() (websocketFrame, error) {
+	var frame websocketFrame
+	var head [8]byte
+	_, err := io.ReadFull(ws.reader, head[:2])
+	if err != nil {
+		return frame, err
+	}
+	frame.fin = head[0]&0x80 != 0
+	frame.rsv1 = head[0]&0x40 != 0
+	frame.opcode = int(head[0] & 0x0f)
+	frame.masked = head[1]&0x80 != 0
+	frame.length = int64(head[1] & 0x7f)
+	if head[0]&0x30 != 0 {
+		return frame, ws.protocolError("unexpected rsv2 or rsv3 bit")
+	}
+	switch frame.opcode {
+	case WebsocketMessageContinuation, WebsocketMessageText, WebsocketMessageBinary:
+	case WebsocketMessageClose, WebsocketMessagePing, WebsocketMessagePong:
+		if !frame.fin || frame.length > websocketMaxControlSize || frame.rsv1 {
+			return frame, ws.protocolError("invalid control frame")
+		}
+	default:
+		return frame, ws.protocolError("unknown opcode " + strconv.Itoa(frame.opcode))
+	}
+	if frame.masked != ws.server {
+		return frame, ws.protocolError("invalid frame mask")
+	}
+
+	switch frame.length {
+	case 126:
+		_, err = io.ReadFull(ws.reader, head[:2])
+		frame.length = int64(binary.BigEndian.Uint16(head[:2]))
+	case 127:
+		_, err = io.ReadFull(ws.reader, head[:8])
+		frame.length = int64(binary.BigEndian.Uint64(head[:8]))
+		if frame.length < 0 {
+			return frame, ws.protocolError("invalid frame length")
+		}
+	}
+	if err == nil && frame.masked {
+		_, err = io.ReadFull(ws.reader, frame.mask[:])
+	}
+	return frame, err
+}
+
+// readPayload 方法读取帧数据追加到buf，按已读取的数据增长缓冲，避免使用帧头长度直接分配内存。
+func (ws *WebsocketConn) readPayload(frame websocketFrame, buf []byte) ([]byte, error) {
+	pos := len(buf)
+	b := bytes.NewBuffer(buf)
+	n, err := b.ReadFrom(io.LimitReader(ws.reader, frame.length))
+	if err == nil && n != frame.length {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	buf = b.Bytes()
+	if frame.masked {
+		websocketMask(frame.mask, buf[pos:])
+	}
+	return buf, nil
+}
+
+func (ws *WebsocketConn) handleControl(frame websocketFrame) error {
+	data, err := ws.readPayload(frame, nil)
+	if err != nil {
+		return err
+	}
+	switch frame.opcode {
+	case WebsocketMessagePing:
+		if ws.PingHandler != nil {
+			return ws.PingHandler(data)
+		}
+		err = ws.writeFrame(WebsocketMessagePong, false, data)
+		if err == ErrWebsocketClosed {
+			return nil
+		}
+		return err
+	case WebsocketMessagePong:
+		if ws.PongHandler != nil {
+			return ws.PongHandler(data)
+		}
+		return nil
+	}
+
+	// close
+	closeErr := &WebsocketCloseError{Code: WebsocketCloseNoStatusReceived}
+	switch {
+	case len(data) == 1:
+		return ws.protocolError("invalid close frame payload")
+	case len(data) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(data))
+		closeErr.Text = string(data[2:])
+		if !websocketValidCloseCode(closeErr.Code) {
+			return ws.protocolError("invalid close code " + strconv.Itoa(closeErr.Code))
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return ws.failConnection(WebsocketCloseInvalidPayload, ErrWebsocketInvalidUTF8)
+		}
+	}
+	ws.WriteClose(closeErr.Code, "")
+	return closeErr
+}
+
+func websocketValidCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+func (ws *WebsocketConn) protocolError(reason string) error {
+	return ws.failConnection(WebsocketCloseProtocolError, fmt.Errorf(ErrFormatWebsocketProtocol, reason))
+}
+
+// failConnection 方法发送关闭帧并返回错误。
+func (ws *WebsocketConn) failConnection(code int, err error) error {
+	ws.WriteClose(code, "")
+	return err
+}
+
+// Error 方法返回关闭帧描述。
+func (err *WebsocketCloseError) Error() string {
+	return "websocket close " + strconv.Itoa(err.Code) + ": " + err.Text
+}
+
+// websocketCompress 函数使用deflate压缩数据并删除末尾的空块。
+func websocketCompress(data []byte, level int) ([]byte, error) {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		level = flate.BestSpeed
+	}
+	buf := bytes.NewBuffer(nil)
+	pool := &websocketFlateWriters[level-flate.HuffmanOnly]
+	w, ok := pool.Get().(*flate.Writer)
+	if ok {
+		w.Reset(buf)
+	} else {
+		w, _ = flate.NewWriter(buf, level)
+	}
+	defer pool.Put(w)
+	_, err := w.Write(data)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte(websocketDeflateTail)), nil
+}
+
+// websocketDecompress 函数解压数据，limit大于0时限制解压后的长度。
+func websocketDecompress(data []byte, limit int64) ([]byte, error) {
+	// 追加删除的空块和一个结束块
+	reader := io.MultiReader(bytes.NewReader(data), strings.NewReader(websocketDeflateTail+"\x01\x00\x00\xff\xff"))
+	r, ok := websocketFlateReaders.Get().(io.ReadCloser)
+	if ok {
+		r.(flate.Resetter).Reset(reader, nil)
+	} else {
+		r = flate.NewReader(reader)
+	}
+	defer websocketFlateReaders.Put(r)
+	if limit > 0 {
+		data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+		if err == nil && int64(len(data)) > limit {
+			err = ErrWebsocketReadLimit
+		}
+		return data, err
+	}
+	return ioutil.ReadAll(r)
+}